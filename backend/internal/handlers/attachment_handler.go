@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/vibe-code-hinge/backend/internal/services"
+)
+
+// multipartOverhead allows for form boundaries and headers on top of the file itself
+const multipartOverhead = 1 << 20
+
+// multipartMemory is how much of an upload is buffered in memory before spilling to disk
+const multipartMemory = 1 << 20
+
+// AttachmentHandler handles chat attachment uploads and downloads
+type AttachmentHandler struct {
+	attachmentService *services.AttachmentService
+}
+
+// NewAttachmentHandler creates a new attachment handler
+func NewAttachmentHandler(attachmentService *services.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+	}
+}
+
+// UploadAttachment uploads a photo or voice note to a match, sent as the "file" field of
+// a multipart form. The returned id is passed in attachment_ids when sending a message.
+func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Get match ID from path
+	vars := mux.Vars(r)
+	matchID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid match ID")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxImageAttachmentSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, services.ErrAttachmentTooLarge.Error())
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Invalid multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "File is required")
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentService.UploadAttachment(r.Context(), userID, matchID, file, header.Size, header.Header.Get("Content-Type"))
+	if err != nil {
+		respondWithAttachmentError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, attachment)
+}
+
+// DownloadAttachment serves an attachment through a signed URL returned with a message
+func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	// Get attachment ID from path
+	vars := mux.Vars(r)
+	attachmentID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
+
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, services.ErrInvalidAttachmentURL.Error())
+		return
+	}
+
+	body, attachment, err := h.attachmentService.OpenAttachment(r.Context(), attachmentID, query.Get("user_id"), expires, query.Get("sig"))
+	if err != nil {
+		respondWithAttachmentError(w, err)
+		return
+	}
+	defer body.Close()
+
+	// Cache privately for as long as the link is valid
+	maxAge := time.Until(time.Unix(expires, 0)) / time.Second
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", "inline")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("Failed to send attachment %d: %v", attachmentID, err)
+	}
+}
+
+// respondWithAttachmentError maps attachment errors to a status code
+func respondWithAttachmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrAttachmentNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidAttachmentURL), errors.Is(err, services.ErrUserBlocked):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		respondWithError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrUnsupportedAttachment):
+		respondWithError(w, http.StatusUnsupportedMediaType, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
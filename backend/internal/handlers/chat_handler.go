@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vibe-code-hinge/backend/internal/middleware"
+	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/services"
+)
+
+const (
+	// Time allowed to write a frame to the client
+	chatWriteWait = 10 * time.Second
+
+	// Time allowed to read the next pong from the client
+	chatPongWait = 60 * time.Second
+
+	// Send pings at this interval, must be less than chatPongWait
+	chatPingPeriod = (chatPongWait * 9) / 10
+
+	// Maximum frame size accepted from the client
+	chatMaxFrameSize = 8 * 1024
+
+	// Replies queued for a connection before it is considered too slow and dropped
+	chatSendBufferSize = 16
+
+	// Time allowed to handle a single client command
+	chatCommandTimeout = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Connections are authenticated with a bearer token rather than cookies, so
+	// cross-origin upgrades are safe to accept like the rest of the API
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// ChatHandler handles the bidirectional chat WebSocket
+type ChatHandler struct {
+	messageService      *services.MessageService
+	notificationService *services.NotificationService
+}
+
+// NewChatHandler creates a new chat handler
+func NewChatHandler(messageService *services.MessageService, notificationService *services.NotificationService) *ChatHandler {
+	return &ChatHandler{
+		messageService:      messageService,
+		notificationService: notificationService,
+	}
+}
+
+// ServeWS upgrades the request to a WebSocket carrying messages, typing indicators and
+// receipts for all of the authenticated user's matches
+func (h *ChatHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (set by the auth middleware)
+	userID, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied to the client
+		log.Printf("WebSocket upgrade failed for user %s: %v", userID, err)
+		return
+	}
+
+	c := &chatConn{
+		handler: h,
+		ws:      ws,
+		userID:  userID,
+		sub:     h.notificationService.Subscribe(userID, services.MessageStream),
+		send:    make(chan models.ChatFrame, chatSendBufferSize),
+		done:    make(chan struct{}),
+	}
+
+	go c.writePump()
+	c.readPump(r.Context())
+
+	// Stop the writer and release the subscription
+	c.close()
+	h.notificationService.Unsubscribe(c.sub)
+}
+
+// chatConn is a single chat WebSocket connection. Frames are read on the handler's
+// goroutine and written on a separate one, as the WebSocket library requires.
+type chatConn struct {
+	handler   *ChatHandler
+	ws        *websocket.Conn
+	userID    string
+	sub       *services.Subscription
+	send      chan models.ChatFrame
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// close shuts the connection down, it is safe to call from either goroutine
+func (c *chatConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.ws.Close()
+	})
+}
+
+// reply queues a frame for the client, dropping the connection if the client is not
+// keeping up
+func (c *chatConn) reply(frame models.ChatFrame) {
+	select {
+	case c.send <- frame:
+	case <-c.done:
+	default:
+		log.Printf("Chat send queue full for user %s, disconnecting", c.userID)
+		c.close()
+	}
+}
+
+// readPump reads commands from the client until the connection fails or closes
+func (c *chatConn) readPump(ctx context.Context) {
+	c.ws.SetReadLimit(chatMaxFrameSize)
+	c.ws.SetReadDeadline(time.Now().Add(chatPongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(chatPongWait))
+	})
+
+	for {
+		_, payload, err := c.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("Chat connection for user %s closed: %v", c.userID, err)
+			}
+			return
+		}
+
+		var cmd models.ChatCommand
+		if err := json.Unmarshal(payload, &cmd); err != nil {
+			c.reply(models.ChatFrame{Type: models.ChatError, Error: "Invalid frame"})
+			continue
+		}
+
+		cmdCtx, cancel := context.WithTimeout(ctx, chatCommandTimeout)
+		c.handle(cmdCtx, cmd)
+		cancel()
+	}
+}
+
+// handle runs a single client command and replies with an ack or an error
+func (c *chatConn) handle(ctx context.Context, cmd models.ChatCommand) {
+	if cmd.MatchID == 0 {
+		c.reply(models.ChatFrame{Type: models.ChatError, ClientID: cmd.ClientID, Error: "Match ID is required"})
+		return
+	}
+
+	var data interface{}
+	var err error
+	switch cmd.Type {
+	case models.ChatSendMessage:
+		if cmd.Message == "" && len(cmd.AttachmentIDs) == 0 {
+			c.reply(models.ChatFrame{Type: models.ChatError, ClientID: cmd.ClientID, Error: "Message or attachment is required"})
+			return
+		}
+		data, err = c.handler.messageService.SendMessage(ctx, c.userID, models.MessageInput{
+			MatchID:       cmd.MatchID,
+			Message:       cmd.Message,
+			AttachmentIDs: cmd.AttachmentIDs,
+			Confirmed:     cmd.Confirmed,
+			IcebreakerID:  cmd.IcebreakerID,
+		})
+	case models.ChatTypingStart, models.ChatTypingStop:
+		err = c.handler.messageService.SetTyping(ctx, c.userID, cmd.MatchID, cmd.Type == models.ChatTypingStart)
+	case models.ChatRead, models.ChatDelivered:
+		if cmd.MessageID == 0 {
+			c.reply(models.ChatFrame{Type: models.ChatError, ClientID: cmd.ClientID, Error: "Message ID is required"})
+			return
+		}
+		if cmd.Type == models.ChatRead {
+			err = c.handler.messageService.MarkReadUpTo(ctx, c.userID, cmd.MatchID, cmd.MessageID)
+		} else {
+			err = c.handler.messageService.MarkDeliveredUpTo(ctx, c.userID, cmd.MatchID, cmd.MessageID)
+		}
+	default:
+		c.reply(models.ChatFrame{Type: models.ChatError, ClientID: cmd.ClientID, Error: "Unknown frame type"})
+		return
+	}
+
+	if err != nil {
+		_, code := safetyErrorCode(err)
+		c.reply(models.ChatFrame{Type: models.ChatError, ClientID: cmd.ClientID, Error: err.Error(), Code: code})
+		return
+	}
+
+	// Typing indicators are fire-and-forget
+	if cmd.Type == models.ChatTypingStart || cmd.Type == models.ChatTypingStop {
+		return
+	}
+	c.reply(models.ChatFrame{Type: models.ChatAck, ClientID: cmd.ClientID, Data: data})
+}
+
+// writePump writes replies, relayed events and pings to the client
+func (c *chatConn) writePump() {
+	ticker := time.NewTicker(chatPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case frame := <-c.send:
+			if err := c.write(frame); err != nil {
+				return
+			}
+		case event, ok := <-c.sub.Events():
+			if !ok {
+				// Dropped by the hub for falling behind
+				return
+			}
+			if err := c.write(models.ChatFrame{
+				Type:    event.Name,
+				EventID: event.ID,
+				Data:    json.RawMessage(event.Data),
+			}); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.ws.SetWriteDeadline(time.Now().Add(chatWriteWait))
+			if err := c.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// write sends a single frame with a write deadline
+func (c *chatConn) write(frame models.ChatFrame) error {
+	c.ws.SetWriteDeadline(time.Now().Add(chatWriteWait))
+	return c.ws.WriteJSON(frame)
+}
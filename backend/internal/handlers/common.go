@@ -24,3 +24,10 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, models.NewErrorResponse(message))
 }
+
+// respondWithErrorCode writes an error response with a machine-readable error code
+func respondWithErrorCode(w http.ResponseWriter, code int, errorCode string, message string) {
+	response := models.NewErrorResponse(message)
+	response.Code = errorCode
+	respondWithJSON(w, code, response)
+}
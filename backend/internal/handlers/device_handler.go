@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/services"
+)
+
+// DeviceHandler handles push notification device registration
+type DeviceHandler struct {
+	pushService *services.PushService
+}
+
+// NewDeviceHandler creates a new device handler
+func NewDeviceHandler(pushService *services.PushService) *DeviceHandler {
+	return &DeviceHandler{
+		pushService: pushService,
+	}
+}
+
+// RegisterDevice registers a device token so the user gets push notifications on it
+func (h *DeviceHandler) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	var input models.DeviceInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	device, err := h.pushService.RegisterDevice(r.Context(), userID, input)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDeviceToken) || errors.Is(err, services.ErrInvalidDevicePlatform) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, device)
+}
+
+// UnregisterDevice stops push notifications to a device, for example on logout
+func (h *DeviceHandler) UnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	token := mux.Vars(r)["token"]
+	if err := h.pushService.UnregisterDevice(r.Context(), userID, token); err != nil {
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.NewSuccessResponse("Device unregistered", nil))
+}
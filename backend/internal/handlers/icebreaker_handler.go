@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/vibe-code-hinge/backend/internal/services"
+)
+
+// IcebreakerHandler handles icebreaker suggestions
+type IcebreakerHandler struct {
+	icebreakerService *services.IcebreakerService
+}
+
+// NewIcebreakerHandler creates a new icebreaker handler
+func NewIcebreakerHandler(icebreakerService *services.IcebreakerService) *IcebreakerHandler {
+	return &IcebreakerHandler{
+		icebreakerService: icebreakerService,
+	}
+}
+
+// GetIcebreakers suggests first messages for a match. Send one with its id as
+// icebreaker_id so replies to it are tracked.
+func (h *IcebreakerHandler) GetIcebreakers(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Get match ID from path
+	vars := mux.Vars(r)
+	matchID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid match ID")
+		return
+	}
+
+	icebreakers, err := h.icebreakerService.GetIcebreakers(r.Context(), userID, matchID, getIntQueryParam(r, "limit", 5))
+	if err != nil {
+		if errors.Is(err, services.ErrMatchNotFound) {
+			respondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrUserBlocked) {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, icebreakers)
+}
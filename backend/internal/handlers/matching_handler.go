@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -76,7 +77,7 @@ func (h *MatchingHandler) CreateSwipe(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service to create swipe
-	match, err := h.matchingService.CreateSwipe(r.Context(), userID, input.ProfileID, input.IsLike)
+	match, err := h.matchingService.CreateSwipe(r.Context(), userID, input)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -100,14 +101,24 @@ func (h *MatchingHandler) GetMatches(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	params := models.MatchPageParams{
+		Cursor:   r.URL.Query().Get("cursor"),
+		Limit:    getIntQueryParam(r, "limit", 50),
+		Archived: r.URL.Query().Get("archived") == "true",
+	}
+
 	// Call service to get matches
-	matches, err := h.matchingService.GetMatches(r.Context(), userID)
+	page, err := h.matchingService.GetMatches(r.Context(), userID, params)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidMatchCursor) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, matches)
+	respondWithJSON(w, http.StatusOK, page)
 }
 
 // LikeProfile handles the action of liking a profile
@@ -129,7 +140,7 @@ func (h *MatchingHandler) LikeProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service to create a like
-	match, err := h.matchingService.CreateSwipe(r.Context(), userID, profileID, true)
+	match, err := h.matchingService.CreateSwipe(r.Context(), userID, models.SwipeInput{ProfileID: profileID, IsLike: true})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -162,7 +173,7 @@ func (h *MatchingHandler) SkipProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service to create a skip
-	_, err := h.matchingService.CreateSwipe(r.Context(), userID, profileID, false)
+	_, err := h.matchingService.CreateSwipe(r.Context(), userID, models.SwipeInput{ProfileID: profileID, IsLike: false})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -189,9 +200,8 @@ func (h *MatchingHandler) SendRose(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: Implement sending a rose (premium feature)
-	// For now, just record it as a special type of like
-	match, err := h.matchingService.CreateSwipe(r.Context(), userID, profileID, true)
+	// A rose is a like that stands out in the likes inbox
+	match, err := h.matchingService.CreateSwipe(r.Context(), userID, models.SwipeInput{ProfileID: profileID, IsLike: true, IsRose: true})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -215,24 +225,107 @@ func (h *MatchingHandler) GetLikes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: Implement getting likes from the service
-	// For now, return placeholder data
-	likes := []map[string]interface{}{
-		{
-			"id":        1,
-			"name":      "Sample User 1",
-			"age":       28,
-			"blurred":   true, // Requires premium to see clearly
-			"timestamp": "2023-08-15T12:34:56Z",
-		},
-		{
-			"id":        2,
-			"name":      "Sample User 2",
-			"age":       31,
-			"blurred":   true,
-			"timestamp": "2023-08-14T10:12:23Z",
-		},
+	likes, err := h.matchingService.GetLikes(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
 	respondWithJSON(w, http.StatusOK, likes)
 }
+
+// SetMatchFlag returns a handler that turns one of the user's flags on a match (archived,
+// muted or pinned) on or off
+func (h *MatchingHandler) SetMatchFlag(flag string, value bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get user ID from context (would come from JWT middleware)
+		userID := r.URL.Query().Get("user_id")
+		if userID == "" {
+			respondWithError(w, http.StatusBadRequest, "User ID is required")
+			return
+		}
+
+		// Get match ID from path
+		vars := mux.Vars(r)
+		matchID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid match ID")
+			return
+		}
+
+		state, err := h.matchingService.SetMatchFlag(r.Context(), userID, matchID, flag, value)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrMatchNotFound):
+				respondWithError(w, http.StatusNotFound, err.Error())
+			case errors.Is(err, services.ErrInvalidMatchFlag):
+				respondWithError(w, http.StatusBadRequest, err.Error())
+			default:
+				respondWithError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, state)
+	}
+}
+
+// BlockUser handles blocking another user
+func (h *MatchingHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	block, err := h.matchingService.BlockUser(r.Context(), userID, mux.Vars(r)["id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrCannotBlockSelf):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, block)
+}
+
+// UnblockUser handles removing a block on another user
+func (h *MatchingHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	if err := h.matchingService.UnblockUser(r.Context(), userID, mux.Vars(r)["id"]); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.NewSuccessResponse("User unblocked", nil))
+}
+
+// GetBlocks handles listing the users the user has blocked
+func (h *MatchingHandler) GetBlocks(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	blocks, err := h.matchingService.GetBlocks(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, blocks)
+}
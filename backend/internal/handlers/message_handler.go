@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -41,17 +44,34 @@ func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get pagination parameters
-	limit := getIntQueryParam(r, "limit", 50)
-	offset := getIntQueryParam(r, "offset", 0)
+	params := models.MessagePageParams{
+		Limit: getIntQueryParam(r, "limit", 50),
+	}
+	if params.BeforeID, err = getInt64QueryParam(r, "before_id"); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid before_id")
+		return
+	}
+	if params.AfterID, err = getInt64QueryParam(r, "after_id"); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid after_id")
+		return
+	}
+	if params.BeforeID > 0 && params.AfterID > 0 {
+		respondWithError(w, http.StatusBadRequest, "Only one of before_id and after_id can be set")
+		return
+	}
 
 	// Get messages
-	messages, err := h.messageService.GetMessages(r.Context(), userID, matchID, limit, offset)
+	page, err := h.messageService.GetMessages(r.Context(), userID, matchID, params)
 	if err != nil {
+		if errors.Is(err, services.ErrUserBlocked) {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, messages)
+	respondWithJSON(w, http.StatusOK, page)
 }
 
 // CreateMessage sends a message in a match
@@ -74,7 +94,10 @@ func (h *MessageHandler) CreateMessage(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var input struct {
-		Message string `json:"message"`
+		Message       string  `json:"message"`
+		AttachmentIDs []int64 `json:"attachment_ids"`
+		Confirmed     bool    `json:"confirmed"`
+		IcebreakerID  int64   `json:"icebreaker_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
@@ -83,27 +106,278 @@ func (h *MessageHandler) CreateMessage(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	// Validate input
-	if input.Message == "" {
-		respondWithError(w, http.StatusBadRequest, "Message is required")
+	if input.Message == "" && len(input.AttachmentIDs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Message or attachment is required")
 		return
 	}
 
 	// Create message input
 	messageInput := models.MessageInput{
-		MatchID: matchID,
-		Message: input.Message,
+		MatchID:       matchID,
+		Message:       input.Message,
+		AttachmentIDs: input.AttachmentIDs,
+		Confirmed:     input.Confirmed,
+		IcebreakerID:  input.IcebreakerID,
 	}
 
 	// Send message
 	message, err := h.messageService.SendMessage(r.Context(), userID, messageInput)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidAttachments) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrMatchExpired) {
+			respondWithError(w, http.StatusGone, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrUserBlocked) {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if status, code := safetyErrorCode(err); code != "" {
+			respondWithErrorCode(w, status, code, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, message)
+}
+
+// MarkRead marks the partner's messages in a match as read up to a message
+func (h *MessageHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Get match ID from path
+	vars := mux.Vars(r)
+	matchID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid match ID")
+		return
+	}
+
+	var input models.MarkReadInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	// Validate input
+	if input.MessageID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Message ID is required")
+		return
+	}
+
+	if err := h.messageService.MarkReadUpTo(r.Context(), userID, matchID, input.MessageID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.NewSuccessResponse("Messages marked as read", nil))
+}
+
+// SearchMessages searches the text of messages across the user's conversations
+func (h *MessageHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Get pagination parameters
+	limit := getIntQueryParam(r, "limit", 20)
+	offset := getIntQueryParam(r, "offset", 0)
+
+	results, err := h.messageService.SearchMessages(r.Context(), userID, r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSearchQuery) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// EditMessage edits the text of one of the user's messages
+func (h *MessageHandler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Get message ID from path
+	vars := mux.Vars(r)
+	messageID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+
+	var input models.EditMessageInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	// Validate input
+	if input.Message == "" {
+		respondWithError(w, http.StatusBadRequest, "Message is required")
+		return
+	}
+
+	message, err := h.messageService.EditMessage(r.Context(), userID, messageID, input)
+	if err != nil {
+		if status, code := safetyErrorCode(err); code != "" {
+			respondWithErrorCode(w, status, code, err.Error())
+			return
+		}
+		respondWithMessageError(w, err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, message)
 }
 
+// UnsendMessage unsends one of the user's messages, leaving a tombstone in the conversation
+func (h *MessageHandler) UnsendMessage(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Get message ID from path
+	vars := mux.Vars(r)
+	messageID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+
+	if err := h.messageService.UnsendMessage(r.Context(), userID, messageID); err != nil {
+		respondWithMessageError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.NewSuccessResponse("Message unsent", nil))
+}
+
+// GetMessageEdits retrieves the edit history of a message
+func (h *MessageHandler) GetMessageEdits(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Get message ID from path
+	vars := mux.Vars(r)
+	messageID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+
+	edits, err := h.messageService.GetMessageEdits(r.Context(), userID, messageID)
+	if err != nil {
+		respondWithMessageError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, edits)
+}
+
+// SetReaction sets the user's emoji reaction to a message
+func (h *MessageHandler) SetReaction(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Get message ID from path
+	vars := mux.Vars(r)
+	messageID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+
+	var input models.ReactionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	reaction, err := h.messageService.SetReaction(r.Context(), userID, messageID, input.Emoji)
+	if err != nil {
+		respondWithMessageError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, reaction)
+}
+
+// RemoveReaction removes the user's reaction from a message
+func (h *MessageHandler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Get message ID from path
+	vars := mux.Vars(r)
+	messageID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+
+	if err := h.messageService.RemoveReaction(r.Context(), userID, messageID); err != nil {
+		respondWithMessageError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.NewSuccessResponse("Reaction removed", nil))
+}
+
+// respondWithMessageError maps errors from changing a message to a status code
+func respondWithMessageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrMessageNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrNotMessageSender), errors.Is(err, services.ErrEditWindowExpired):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrMessageUnsent):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidEmoji):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
 // Helper function to get a query parameter as an integer
 func getIntQueryParam(r *http.Request, param string, defaultValue int) int {
 	valueStr := r.URL.Query().Get(param)
@@ -118,3 +392,91 @@ func getIntQueryParam(r *http.Request, param string, defaultValue int) int {
 	
 	return value
 }
+
+// Helper function to get an optional id query parameter, zero when absent
+func getInt64QueryParam(r *http.Request, param string) (int64, error) {
+	valueStr := r.URL.Query().Get(param)
+	if valueStr == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseInt(valueStr, 10, 64)
+	if err != nil || value < 0 {
+		return 0, errors.New("invalid " + param)
+	}
+
+	return value, nil
+}
+
+// ExportConversation downloads a copy of a conversation as JSON or an HTML page
+func (h *MessageHandler) ExportConversation(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// Get match ID from path
+	vars := mux.Vars(r)
+	matchID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid match ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.ExportFormatJSON
+	}
+
+	export, err := h.messageService.ExportConversation(r.Context(), userID, matchID, format)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMatchNotFound):
+			respondWithError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrInvalidExportFormat):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrUserBlocked):
+			respondWithError(w, http.StatusForbidden, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	var body bytes.Buffer
+	contentType := "application/json"
+	if format == models.ExportFormatHTML {
+		contentType = "text/html; charset=utf-8"
+		err = services.RenderConversationHTML(&body, export)
+	} else {
+		encoder := json.NewEncoder(&body)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(export)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error encoding export")
+		return
+	}
+
+	filename := fmt.Sprintf("conversation-%d-%s.%s", matchID, export.ExportedAt.UTC().Format("20060102"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+// safetyErrorCode returns the status and error code for messages stopped by the safety
+// pipeline, or an empty code for any other error
+func safetyErrorCode(err error) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrMessageNeedsConfirmation):
+		return http.StatusConflict, models.ErrorCodeConfirmationRequired
+	case errors.Is(err, services.ErrMessageBlocked):
+		return http.StatusUnprocessableEntity, models.ErrorCodeMessageBlocked
+	default:
+		return 0, ""
+	}
+}
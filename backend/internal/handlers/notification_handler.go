@@ -1,10 +1,14 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
-	"time"
+	"strconv"
 
+	"github.com/gorilla/mux"
+	"github.com/vibe-code-hinge/backend/internal/middleware"
+	"github.com/vibe-code-hinge/backend/internal/models"
 	"github.com/vibe-code-hinge/backend/internal/services"
 )
 
@@ -22,127 +26,120 @@ func NewNotificationHandler(notificationService *services.NotificationService) *
 
 // MessageEvents handles SSE for message events
 func (h *NotificationHandler) MessageEvents(w http.ResponseWriter, r *http.Request) {
+	h.streamEvents(w, r, services.MessageStream)
+}
+
+// NotificationEvents handles SSE for general notifications
+func (h *NotificationHandler) NotificationEvents(w http.ResponseWriter, r *http.Request) {
+	h.streamEvents(w, r, services.NotificationStream)
+}
+
+// streamEvents streams the authenticated user's events on the given stream
+func (h *NotificationHandler) streamEvents(w http.ResponseWriter, r *http.Request, stream services.EventStream) {
+	// Get user ID from context (set by the auth middleware)
+	userID, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	err := h.notificationService.StreamEvents(w, r, userID, stream)
+	if errors.Is(err, services.ErrStreamingUnsupported) {
+		respondWithError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+	if err != nil {
+		log.Printf("Event stream for user %s ended with error: %v", userID, err)
+	}
+}
+
+// GetNotifications returns a page of the user's notifications, newest first
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (would come from JWT middleware)
-	// For demonstration, we'll use a query parameter for now
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
 		respondWithError(w, http.StatusBadRequest, "User ID is required")
 		return
 	}
 
-	// Set headers for SSE
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	// Create notification channel
-	messageChan := make(chan []byte)
-	
-	// TODO: Register this channel with the notification service
-	// This is just a placeholder implementation
-	go func() {
-		// Simulate sending message events every 5 seconds
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
-		
-		for {
-			select {
-			case <-ticker.C:
-				event := fmt.Sprintf("data: {\"type\":\"message\",\"message_id\":%d,\"sender_id\":\"user123\",\"content\":\"Hello there!\"}\n\n", time.Now().Unix())
-				messageChan <- []byte(event)
-			case <-r.Context().Done():
-				close(messageChan)
-				return
-			}
-		}
-	}()
-
-	// Stream events to client
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		respondWithError(w, http.StatusInternalServerError, "Streaming not supported")
-		return
+	params := models.NotificationPageParams{
+		Cursor: r.URL.Query().Get("cursor"),
+		Limit:  getIntQueryParam(r, "limit", 20),
 	}
 
-	for {
-		select {
-		case <-r.Context().Done():
+	page, err := h.notificationService.GetNotifications(r.Context(), userID, params)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidNotificationCursor) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
 			return
-		case msg, ok := <-messageChan:
-			if !ok {
-				return
-			}
-			_, err := w.Write(msg)
-			if err != nil {
-				return
-			}
-			flusher.Flush()
 		}
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
+
+	respondWithJSON(w, http.StatusOK, page)
 }
 
-// NotificationEvents handles SSE for general notifications
-func (h *NotificationHandler) NotificationEvents(w http.ResponseWriter, r *http.Request) {
+// GetUnreadCount returns how many of the user's notifications are unread
+func (h *NotificationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (would come from JWT middleware)
-	// For demonstration, we'll use a query parameter for now
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
 		respondWithError(w, http.StatusBadRequest, "User ID is required")
 		return
 	}
 
-	// Set headers for SSE
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	// Create notification channel
-	notificationChan := make(chan []byte)
-	
-	// TODO: Register this channel with the notification service
-	// This is just a placeholder implementation
-	go func() {
-		// Simulate sending notification events
-		ticker := time.NewTicker(10 * time.Second)
-		defer ticker.Stop()
-		
-		for {
-			select {
-			case <-ticker.C:
-				notificationTypes := []string{"new_match", "new_like", "new_message"}
-				notificationType := notificationTypes[time.Now().Unix()%3]
-				event := fmt.Sprintf("data: {\"type\":\"%s\",\"user_id\":\"user456\",\"timestamp\":%d}\n\n", 
-					notificationType, time.Now().Unix())
-				notificationChan <- []byte(event)
-			case <-r.Context().Done():
-				close(notificationChan)
-				return
-			}
-		}
-	}()
+	count, err := h.notificationService.GetUnreadNotificationCount(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-	// Stream events to client
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		respondWithError(w, http.StatusInternalServerError, "Streaming not supported")
+	respondWithJSON(w, http.StatusOK, models.UnreadCount{UnreadCount: count})
+}
+
+// MarkRead marks one of the user's notifications as read
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
 		return
 	}
 
-	for {
-		select {
-		case <-r.Context().Done():
+	// Get notification ID from path
+	vars := mux.Vars(r)
+	notificationID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	if err := h.notificationService.MarkNotificationAsRead(r.Context(), userID, notificationID); err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			respondWithError(w, http.StatusNotFound, err.Error())
 			return
-		case notification, ok := <-notificationChan:
-			if !ok {
-				return
-			}
-			_, err := w.Write(notification)
-			if err != nil {
-				return
-			}
-			flusher.Flush()
 		}
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
-} 
\ No newline at end of file
+
+	respondWithJSON(w, http.StatusOK, models.NewSuccessResponse("Notification marked as read", nil))
+}
+
+// MarkAllRead marks all of the user's notifications as read
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	if err := h.notificationService.MarkAllNotificationsAsRead(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.NewSuccessResponse("All notifications marked as read", nil))
+}
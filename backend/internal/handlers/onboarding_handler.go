@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/vibe-code-hinge/backend/internal/services"
+)
+
+// OnboardingHandler handles onboarding routes
+type OnboardingHandler struct {
+	profileService *services.ProfileService
+}
+
+// NewOnboardingHandler creates a new onboarding handler
+func NewOnboardingHandler(profileService *services.ProfileService) *OnboardingHandler {
+	return &OnboardingHandler{
+		profileService: profileService,
+	}
+}
+
+// GetOnboarding returns the user's current onboarding step and what is still missing
+func (h *OnboardingHandler) GetOnboarding(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	status, err := h.profileService.GetOnboarding(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, status)
+}
+
+// CompleteOnboarding finishes onboarding, showing the user's profile in feeds
+func (h *OnboardingHandler) CompleteOnboarding(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	status, err := h.profileService.CompleteOnboarding(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrOnboardingIncomplete) {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, status)
+}
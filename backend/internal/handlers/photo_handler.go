@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/services"
+)
+
+// PhotoHandler handles profile photo uploads and management
+type PhotoHandler struct {
+	photoService *services.PhotoService
+}
+
+// NewPhotoHandler creates a new photo handler
+func NewPhotoHandler(photoService *services.PhotoService) *PhotoHandler {
+	return &PhotoHandler{
+		photoService: photoService,
+	}
+}
+
+// GetPhotos lists the photos on the user's profile, the primary photo first
+func (h *PhotoHandler) GetPhotos(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	photos, err := h.photoService.GetPhotos(r.Context(), userID)
+	if err != nil {
+		respondWithPhotoError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, photos)
+}
+
+// UploadPhoto adds a photo to the user's profile, sent as the "file" field of a
+// multipart form
+func (h *PhotoHandler) UploadPhoto(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxPhotoSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, services.ErrPhotoTooLarge.Error())
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Invalid multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "File is required")
+		return
+	}
+	defer file.Close()
+
+	photo, err := h.photoService.UploadPhoto(r.Context(), userID, file, header.Size)
+	if err != nil {
+		respondWithPhotoError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, photo)
+}
+
+// DeletePhoto removes a photo from the user's profile
+func (h *PhotoHandler) DeletePhoto(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	photoID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid photo ID")
+		return
+	}
+
+	if err := h.photoService.DeletePhoto(r.Context(), userID, photoID); err != nil {
+		respondWithPhotoError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.NewSuccessResponse("Photo deleted", nil))
+}
+
+// ReorderPhotos puts the user's photos in a new order
+func (h *PhotoHandler) ReorderPhotos(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	var input models.PhotoOrderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	photos, err := h.photoService.ReorderPhotos(r.Context(), userID, input.PhotoIDs)
+	if err != nil {
+		respondWithPhotoError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, photos)
+}
+
+// SetPrimaryPhoto makes a photo the primary photo on the user's profile
+func (h *PhotoHandler) SetPrimaryPhoto(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	photoID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid photo ID")
+		return
+	}
+
+	photos, err := h.photoService.SetPrimaryPhoto(r.Context(), userID, photoID)
+	if err != nil {
+		respondWithPhotoError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, photos)
+}
+
+// ServePhoto serves a stored photo variant from the URL returned with the photo
+func (h *PhotoHandler) ServePhoto(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	body, err := h.photoService.OpenPhoto(r.Context(), vars["profile_id"], vars["key"], vars["variant"])
+	if err != nil {
+		respondWithPhotoError(w, err)
+		return
+	}
+	defer body.Close()
+
+	// A variant never changes once stored, a new upload gets a new key
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("Failed to send photo %s: %v", vars["key"], err)
+	}
+}
+
+// respondWithPhotoError maps photo errors to a status code
+func respondWithPhotoError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrPhotoNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrPhotoTooLarge):
+		respondWithError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrUnsupportedPhoto):
+		respondWithError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, services.ErrPhotoBanned):
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, services.ErrInvalidPhotoSize),
+		errors.Is(err, services.ErrInvalidPhotoOrder):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrTooManyPhotos),
+		errors.Is(err, services.ErrPhotoProfileRequired):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/vibe-code-hinge/backend/internal/models"
 	"github.com/vibe-code-hinge/backend/internal/services"
 )
 
@@ -49,7 +51,7 @@ func (h *PreferenceHandler) UpdatePreferences(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	var preferences map[string]interface{}
+	var preferences models.PreferenceInput
 	if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
@@ -57,8 +59,12 @@ func (h *PreferenceHandler) UpdatePreferences(w http.ResponseWriter, r *http.Req
 	defer r.Body.Close()
 
 	// Call service to update preferences
-	err := h.preferenceService.UpdatePreferences(r.Context(), userID, preferences)
+	err := h.preferenceService.CreateOrUpdatePreference(r.Context(), userID, &preferences)
 	if err != nil {
+		if errors.Is(err, services.ErrUnknownAttribute) || errors.Is(err, services.ErrInvalidAttribute) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
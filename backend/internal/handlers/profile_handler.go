@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -82,14 +83,14 @@ func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 // GetProfilePrompts handles retrieval of a user's prompts
 func (h *ProfileHandler) GetProfilePrompts(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	id, err := strconv.ParseInt(vars["id"], 10, 64)
-	if err != nil {
+	id := vars["id"]
+	if id == "" {
 		respondWithError(w, http.StatusBadRequest, "Invalid profile ID")
 		return
 	}
 
 	// Call service to get prompts
-	prompts, err := h.promptService.GetUserPrompts(r.Context(), strconv.FormatInt(id, 10))
+	prompts, err := h.promptService.GetUserPrompts(r.Context(), id)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -101,15 +102,15 @@ func (h *ProfileHandler) GetProfilePrompts(w http.ResponseWriter, r *http.Reques
 // UpdateProfilePrompts handles updating a user's prompts
 func (h *ProfileHandler) UpdateProfilePrompts(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	id, err := strconv.ParseInt(vars["id"], 10, 64)
-	if err != nil {
+	id := vars["id"]
+	if id == "" {
 		respondWithError(w, http.StatusBadRequest, "Invalid profile ID")
 		return
 	}
 
 	// Get user ID from context (would come from JWT middleware)
 	// For now, we'll just use the profile ID as the user ID for simplicity
-	userID := strconv.FormatInt(id, 10)
+	userID := id
 
 	var input struct {
 		PromptID  string `json:"prompt_id"`
@@ -129,11 +130,103 @@ func (h *ProfileHandler) UpdateProfilePrompts(w http.ResponseWriter, r *http.Req
 	}
 
 	// Call service to update prompt
-	err = h.promptService.UpdateUserPrompt(r.Context(), userID, input.PromptID, input.Response)
+	err := h.promptService.UpdateUserPrompt(r.Context(), userID, input.PromptID, input.Response)
 	if err != nil {
+		if errors.Is(err, services.ErrTooManyPrompts) {
+			respondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, map[string]string{"status": "success"})
 }
+
+// DeleteProfilePrompt handles removing a user's answer to a prompt
+func (h *ProfileHandler) DeleteProfilePrompt(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid profile ID")
+		return
+	}
+
+	promptID, err := strconv.ParseInt(vars["prompt_id"], 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid prompt ID")
+		return
+	}
+
+	// Get user ID from context (would come from JWT middleware)
+	// For now, we'll just use the profile ID as the user ID for simplicity
+	userID := id
+
+	if err := h.promptService.DeleteUserPrompt(r.Context(), userID, promptID); err != nil {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.NewSuccessResponse("Prompt removed", nil))
+}
+
+// GetAttributeCatalog lists the structured profile attributes and their allowed values
+func (h *ProfileHandler) GetAttributeCatalog(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, models.ProfileAttributes)
+}
+
+// GetAttributes returns the user's attributes, hidden ones included
+func (h *ProfileHandler) GetAttributes(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	attributes, err := h.profileService.GetAttributes(r.Context(), userID)
+	if err != nil {
+		respondWithAttributeError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, attributes)
+}
+
+// UpdateAttributes sets, clears, shows or hides the user's attributes
+func (h *ProfileHandler) UpdateAttributes(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	var input map[string]models.ProfileAttributeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	attributes, err := h.profileService.UpdateAttributes(r.Context(), userID, input)
+	if err != nil {
+		respondWithAttributeError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, attributes)
+}
+
+// respondWithAttributeError maps profile attribute errors to a status code
+func respondWithAttributeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownAttribute),
+		errors.Is(err, services.ErrInvalidAttribute):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrAttributeProfileRequired):
+		respondWithError(w, http.StatusConflict, err.Error())
+	default:
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
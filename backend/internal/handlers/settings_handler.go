@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/services"
+)
+
+// SettingsHandler handles user settings routes
+type SettingsHandler struct {
+	settingsService *services.SettingsService
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(settingsService *services.SettingsService) *SettingsHandler {
+	return &SettingsHandler{
+		settingsService: settingsService,
+	}
+}
+
+// GetSettings handles the retrieval of user settings
+func (h *SettingsHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	// For demonstration, we'll use a query parameter for now
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	settings, err := h.settingsService.GetSettings(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, settings)
+}
+
+// UpdateSettings handles the update of user settings
+func (h *SettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	// For demonstration, we'll use a query parameter for now
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	var input models.UserSettingsInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	settings, err := h.settingsService.UpdateSettings(r.Context(), userID, input)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, settings)
+}
+
+// GetNotificationPreferences handles the retrieval of notification preferences
+func (h *SettingsHandler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	preferences, err := h.settingsService.GetNotificationPreferences(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, preferences)
+}
+
+// UpdateNotificationPreferences handles the update of notification preferences
+func (h *SettingsHandler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context (would come from JWT middleware)
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	var input models.NotificationPreferencesInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	preferences, err := h.settingsService.UpdateNotificationPreferences(r.Context(), userID, input)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownNotificationType) || errors.Is(err, services.ErrInvalidQuietHours) || errors.Is(err, services.ErrInvalidTimezone) {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, preferences)
+}
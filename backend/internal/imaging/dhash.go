@@ -0,0 +1,37 @@
+package imaging
+
+import (
+	"image"
+	"math/bits"
+)
+
+// DHash computes the 64-bit difference hash of an image: it is shrunk to 9x8 grayscale
+// pixels and each bit records whether a pixel is brighter than its right neighbour.
+// Resizing, recompressing and small edits barely change the hash, so near-duplicate
+// images have hashes a few bits apart.
+func DHash(img *image.RGBA) uint64 {
+	small := resize(img, 9, 8)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if luma(small, x, y) > luma(small, x+1, y) {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HashDistance is the number of bits two hashes differ in, 0 for identical images and
+// up to 64
+func HashDistance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// luma returns the perceived brightness of a pixel
+func luma(img *image.RGBA, x int, y int) int {
+	p := img.Pix[y*img.Stride+x*4:]
+	return 299*int(p[0]) + 587*int(p[1]) + 114*int(p[2])
+}
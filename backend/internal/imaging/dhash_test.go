@@ -0,0 +1,101 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// duplicateThreshold is the default distance at which uploads are reported as duplicates
+const duplicateThreshold = 6
+
+// patternImage draws a smooth pattern with some structure for the hash to pick up,
+// varying with the frequencies given
+func patternImage(width int, height int, fx float64, fy float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			u, v := float64(x)/float64(width), float64(y)/float64(height)
+			l := 128 + 60*math.Sin(fx*u*2*math.Pi)*math.Cos(fy*v*2*math.Pi) + 60*(u-v)
+			img.SetRGBA(x, y, color.RGBA{uint8(l), uint8(l * 0.8), uint8(255 - l), 255})
+		}
+	}
+	return img
+}
+
+// withSticker returns a copy of an image with a white square drawn over a corner, like
+// a small edit
+func withSticker(img *image.RGBA, side int) *image.RGBA {
+	edited := image.NewRGBA(img.Bounds())
+	copy(edited.Pix, img.Pix)
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			edited.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	return edited
+}
+
+// recompress round-trips an image through a low quality JPEG
+func recompress(t *testing.T, img *image.RGBA) *image.RGBA {
+	t.Helper()
+	data, err := EncodeJPEG(img, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestHashDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0xFFFF, 0xFFFF, 0},
+		{0, 1, 1},
+		{0, 1 << 63, 1},
+		{0x0F0F, 0xF0F0, 16},
+		{0, math.MaxUint64, 64},
+	}
+	for _, tt := range tests {
+		if got := HashDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("HashDistance(%016x, %016x) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDHash(t *testing.T) {
+	original := patternImage(800, 600, 3, 2)
+	hash := DHash(original)
+
+	tests := []struct {
+		name      string
+		img       *image.RGBA
+		duplicate bool
+	}{
+		{"same image", original, true},
+		{"recompressed", recompress(t, original), true},
+		{"resized", Fit(original, 200), true},
+		{"resized and recompressed", recompress(t, Fit(original, 320)), true},
+		{"small edit", withSticker(original, 80), true},
+		{"unrelated", patternImage(800, 600, 5, 7), false},
+		{"mirrored pattern", patternImage(800, 600, -3, 2), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := HashDistance(hash, DHash(tt.img))
+			if tt.duplicate && distance > duplicateThreshold {
+				t.Errorf("distance = %d, want at most %d", distance, duplicateThreshold)
+			}
+			if !tt.duplicate && distance <= duplicateThreshold {
+				t.Errorf("distance = %d, want more than %d", distance, duplicateThreshold)
+			}
+		})
+	}
+}
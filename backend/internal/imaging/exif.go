@@ -0,0 +1,128 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// exifOrientationTag is the EXIF tag holding how the camera was held
+const exifOrientationTag = 0x0112
+
+// jpegOrientation reads the EXIF orientation (1 to 8) from a JPEG's APP1 segment, or
+// returns 1, upright, when it has none or it can't be read
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Walk the segments before the image data looking for APP1
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			// Fill byte
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// Start of scan or end of image, no EXIF before the pixels
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of EXIF's TIFF structure
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < entries; e++ {
+		entry := ifd + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		// A SHORT, stored in the first two bytes of the value field
+		orientation := int(order.Uint16(tiff[entry+8:]))
+		if orientation < 1 || orientation > 8 {
+			return 1
+		}
+		return orientation
+	}
+	return 1
+}
+
+// orient turns an image upright given its EXIF orientation. Orientations 5 to 8 swap
+// width and height.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// Where source pixel (x, y) ends up
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored
+				dx, dy = width-1-x, y
+			case 3: // Upside down
+				dx, dy = width-1-x, height-1-y
+			case 4: // Upside down and mirrored
+				dx, dy = x, height-1-y
+			case 5: // Mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // Turned left, rotate clockwise
+				dx, dy = height-1-y, x
+			case 7: // Mirrored along the top-right diagonal
+				dx, dy = height-1-y, width-1-x
+			case 8: // Turned right, rotate anticlockwise
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:y*src.Stride+x*4+4])
+		}
+	}
+
+	return dst
+}
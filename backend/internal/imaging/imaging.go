@@ -0,0 +1,128 @@
+// Package imaging decodes uploaded photos, applies their EXIF orientation, resizes them
+// and re-encodes them as JPEG or PNG. Re-encoding keeps only the pixels, so EXIF metadata
+// such as GPS coordinates never leaves the server.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Registered with image.Decode
+	"image/jpeg"
+	"image/png"
+)
+
+// ErrUnsupportedFormat is returned for anything that isn't a JPEG, PNG or GIF image
+var ErrUnsupportedFormat = errors.New("image must be a JPEG, PNG or GIF")
+
+// Formats are the image formats that can be decoded, as named by image.DecodeConfig
+var Formats = map[string]bool{
+	"jpeg": true,
+	"png":  true,
+	"gif":  true,
+}
+
+// Inspect reads an image's format and dimensions from its header without decoding it,
+// so oversized images can be turned away before they are decoded. The dimensions are
+// as stored, before EXIF orientation is applied.
+func Inspect(data []byte) (format string, width int, height int, err error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !Formats[format] {
+		return "", 0, 0, ErrUnsupportedFormat
+	}
+	return format, config.Width, config.Height, nil
+}
+
+// Decode decodes an image onto an opaque white background, the first frame for GIFs,
+// and turns it upright according to its EXIF orientation
+func Decode(data []byte) (*image.RGBA, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil || !Formats[format] {
+		return nil, ErrUnsupportedFormat
+	}
+
+	bounds := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, img.Bounds(), src, bounds.Min, draw.Over)
+
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, nil
+}
+
+// Fit scales an image down to fit within maxSide on both sides, keeping its aspect
+// ratio. Images that already fit are returned unchanged.
+func Fit(img *image.RGBA, maxSide int) *image.RGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width <= maxSide && height <= maxSide {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxSide/width)
+		width = maxSide
+	} else {
+		width = max(1, width*maxSide/height)
+		height = maxSide
+	}
+	return resize(img, width, height)
+}
+
+// EncodeJPEG encodes an image as a baseline JPEG with no metadata
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodePNG encodes an image as a PNG with no metadata
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resize scales an image down by averaging the source pixels each destination pixel
+// covers, which keeps detail without the aliasing of nearest-neighbour sampling
+func resize(src *image.RGBA, width int, height int) *image.RGBA {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max(y0+1, (y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max(x0+1, (x+1)*srcWidth/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+
+			d := dst.Pix[y*dst.Stride+x*4 : y*dst.Stride+x*4+4]
+			d[0] = uint8(r / n)
+			d[1] = uint8(g / n)
+			d[2] = uint8(b / n)
+			d[3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,262 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// Quadrant colors of the test photo, chosen to stay far apart after JPEG compression
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	green = color.RGBA{0, 255, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	white = color.RGBA{255, 255, 255, 255}
+)
+
+// quadrantImage is a 64x32 image with red, green, blue and white quadrants from the
+// top left, each aligned to JPEG's 16 pixel blocks
+func quadrantImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			c := red
+			switch {
+			case x >= 32 && y < 16:
+				c = green
+			case x < 32 && y >= 16:
+				c = blue
+			case x >= 32 && y >= 16:
+				c = white
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// encodeTestJPEG encodes an image as a JPEG with no metadata
+func encodeTestJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withExif inserts an EXIF APP1 segment holding the orientation and a GPS IFD pointer
+// after the JPEG's start of image marker, the way cameras write it
+func withExif(data []byte, order binary.ByteOrder, orientation int) []byte {
+	tiff := make([]byte, 8+2+2*12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 2)
+
+	// GPSInfo, a LONG pointing past the IFD, then Orientation, a SHORT
+	entry := tiff[10:]
+	order.PutUint16(entry, 0x8825)
+	order.PutUint16(entry[2:], 4)
+	order.PutUint32(entry[4:], 1)
+	order.PutUint32(entry[8:], uint32(len(tiff)))
+	entry = tiff[22:]
+	order.PutUint16(entry, exifOrientationTag)
+	order.PutUint16(entry[2:], 3)
+	order.PutUint32(entry[4:], 1)
+	order.PutUint16(entry[8:], uint16(orientation))
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+// jpegMarkers lists the markers of the segments before a JPEG's image data
+func jpegMarkers(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var markers []byte
+	for i := 2; i+4 <= len(data); {
+		marker := data[i+1]
+		markers = append(markers, marker)
+		if marker == 0xDA {
+			return markers
+		}
+		i += 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+	}
+	t.Fatal("no start of scan marker")
+	return nil
+}
+
+// nearest returns which of the quadrant colors a pixel is closest to
+func nearest(img *image.RGBA, x int, y int) color.RGBA {
+	p := img.RGBAAt(x, y)
+	var best color.RGBA
+	bestDistance := -1
+	for _, c := range []color.RGBA{red, green, blue, white} {
+		dr, dg, db := int(p.R)-int(c.R), int(p.G)-int(c.G), int(p.B)-int(c.B)
+		if d := dr*dr + dg*dg + db*db; bestDistance < 0 || d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	data := encodeTestJPEG(t, quadrantImage())
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", data, 1},
+		{"little endian", withExif(data, binary.LittleEndian, 6), 6},
+		{"big endian", withExif(data, binary.BigEndian, 8), 8},
+		{"out of range", withExif(data, binary.LittleEndian, 9), 1},
+		{"truncated", withExif(data, binary.LittleEndian, 6)[:30], 1},
+		{"not a jpeg", []byte("not a jpeg at all"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeOrientsAndStripsExif(t *testing.T) {
+	data := encodeTestJPEG(t, quadrantImage())
+
+	// The colors that end up in each corner: top left, top right, bottom left, bottom right
+	tests := []struct {
+		orientation int
+		wide        bool
+		corners     [4]color.RGBA
+	}{
+		{1, true, [4]color.RGBA{red, green, blue, white}},
+		{2, true, [4]color.RGBA{green, red, white, blue}},
+		{3, true, [4]color.RGBA{white, blue, green, red}},
+		{4, true, [4]color.RGBA{blue, white, red, green}},
+		{5, false, [4]color.RGBA{red, blue, green, white}},
+		{6, false, [4]color.RGBA{blue, red, white, green}},
+		{7, false, [4]color.RGBA{white, green, blue, red}},
+		{8, false, [4]color.RGBA{green, white, red, blue}},
+	}
+	for _, tt := range tests {
+		exif := withExif(data, binary.LittleEndian, tt.orientation)
+		img, err := Decode(exif)
+		if err != nil {
+			t.Fatalf("orientation %d: Decode() error = %v", tt.orientation, err)
+		}
+
+		width, height := img.Bounds().Dx(), img.Bounds().Dy()
+		if tt.wide && (width != 64 || height != 32) || !tt.wide && (width != 32 || height != 64) {
+			t.Errorf("orientation %d: decoded %dx%d", tt.orientation, width, height)
+			continue
+		}
+		corners := [4]color.RGBA{
+			nearest(img, width/4, height/4),
+			nearest(img, width*3/4, height/4),
+			nearest(img, width/4, height*3/4),
+			nearest(img, width*3/4, height*3/4),
+		}
+		if corners != tt.corners {
+			t.Errorf("orientation %d: corners = %v, want %v", tt.orientation, corners, tt.corners)
+		}
+
+		// Re-encoding keeps only the pixels, so the EXIF segment and its GPS pointer are gone
+		encoded, err := EncodeJPEG(img, 85)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.IndexByte(jpegMarkers(t, exif), 0xE1) < 0 {
+			t.Fatal("test image has no APP1 segment")
+		}
+		if bytes.IndexByte(jpegMarkers(t, encoded), 0xE1) >= 0 {
+			t.Errorf("orientation %d: re-encoded image still has an APP1 segment", tt.orientation)
+		}
+		if jpegOrientation(encoded) != 1 {
+			t.Errorf("orientation %d: re-encoded image is not upright", tt.orientation)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	var pngData, gifData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 10, 20))); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(&gifData, image.NewPaletted(image.Rect(0, 0, 7, 5), []color.Color{color.Black}), nil); err != nil {
+		t.Fatal(err)
+	}
+	jpegData := encodeTestJPEG(t, quadrantImage())
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantFormat string
+		wantWidth  int
+		wantHeight int
+		wantErr    error
+	}{
+		{"jpeg", jpegData, "jpeg", 64, 32, nil},
+		{"jpeg sideways", withExif(jpegData, binary.LittleEndian, 6), "jpeg", 64, 32, nil}, // As stored
+		{"png", pngData.Bytes(), "png", 10, 20, nil},
+		{"gif", gifData.Bytes(), "gif", 7, 5, nil},
+		{"bmp", []byte("BM\x00\x00\x00\x00\x00\x00\x00\x00\x36\x00\x00\x00"), "", 0, 0, ErrUnsupportedFormat},
+		{"truncated jpeg", jpegData[:10], "", 0, 0, ErrUnsupportedFormat},
+		{"empty", nil, "", 0, 0, ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, width, height, err := Inspect(tt.data)
+			if err != tt.wantErr || format != tt.wantFormat || width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("Inspect() = %q %dx%d, %v, want %q %dx%d, %v", format, width, height, err, tt.wantFormat, tt.wantWidth, tt.wantHeight, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		maxSide       int
+		wantWidth     int
+		wantHeight    int
+	}{
+		{"fits", 40, 30, 50, 40, 30},
+		{"exact", 50, 50, 50, 50, 50},
+		{"landscape", 100, 50, 50, 50, 25},
+		{"portrait", 50, 100, 50, 25, 50},
+		{"square", 300, 300, 100, 100, 100},
+		{"sliver", 1000, 1, 100, 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			got := Fit(img, tt.maxSide)
+			if got.Bounds().Dx() != tt.wantWidth || got.Bounds().Dy() != tt.wantHeight {
+				t.Errorf("Fit(%dx%d, %d) = %dx%d, want %dx%d", tt.width, tt.height, tt.maxSide, got.Bounds().Dx(), got.Bounds().Dy(), tt.wantWidth, tt.wantHeight)
+			}
+			if tt.width <= tt.maxSide && tt.height <= tt.maxSide && got != img {
+				t.Error("Fit() copied an image that already fits")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package imaging
+
+import "encoding/binary"
+
+// Flags in the VP8X chunk of an extended WebP saying which metadata chunks follow
+const (
+	webpExifFlag = 0x08
+	webpXMPFlag  = 0x04
+)
+
+// StripWebPMetadata removes the EXIF and XMP chunks from a WebP image and clears their
+// flags, leaving the image data untouched. WebPs can't be decoded here, so unlike other
+// formats they are cleaned without re-encoding. Returns ErrUnsupportedFormat for
+// anything that isn't a well-formed WebP.
+func StripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrUnsupportedFormat
+	}
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, ErrUnsupportedFormat
+		}
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		if size > len(data)-i-8 {
+			return nil, ErrUnsupportedFormat
+		}
+		// Chunks are padded to an even length, though some writers leave off the last pad
+		end := min(i+8+size+size%2, len(data))
+		chunk := data[i:end]
+
+		switch string(chunk[:4]) {
+		case "EXIF", "XMP ":
+			// Dropped
+		case "VP8X":
+			if size < 1 {
+				return nil, ErrUnsupportedFormat
+			}
+			out = append(out, chunk...)
+			out[len(out)-len(chunk)+8] &^= webpExifFlag | webpXMPFlag
+		default:
+			out = append(out, chunk...)
+		}
+		i = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// webpChunk encodes a RIFF chunk, padded to an even length
+func webpChunk(fourCC string, data []byte) []byte {
+	chunk := append([]byte(fourCC), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// webpFile wraps chunks in a WebP RIFF header
+func webpFile(chunks ...[]byte) []byte {
+	body := bytes.Join(chunks, nil)
+	file := append([]byte("RIFF\x00\x00\x00\x00WEBP"), body...)
+	binary.LittleEndian.PutUint32(file[4:], uint32(len(file)-8))
+	return file
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	// VP8X with the ICC, EXIF and XMP flags set and a 1x1 canvas
+	vp8x := webpChunk("VP8X", []byte{0x20 | webpExifFlag | webpXMPFlag, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	cleanVP8X := webpChunk("VP8X", []byte{0x20, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	iccp := webpChunk("ICCP", []byte("profile"))
+	image := webpChunk("VP8L", []byte("\x2f\x00\x00\x00\x00"))
+	exif := webpChunk("EXIF", []byte("II*\x00GPS 51.5N 0.12W"))
+	xmp := webpChunk("XMP ", []byte("<x:xmpmeta/>"))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []byte
+		wantErr bool
+	}{
+		{"simple", webpFile(image), webpFile(image), false},
+		{"extended without metadata", webpFile(cleanVP8X, iccp, image), webpFile(cleanVP8X, iccp, image), false},
+		{"exif and xmp", webpFile(vp8x, iccp, image, exif, xmp), webpFile(cleanVP8X, iccp, image), false},
+		{"missing final pad", webpFile(vp8x, image, exif)[:len(webpFile(vp8x, image, exif))-1], webpFile(cleanVP8X, image), false},
+		{"truncated chunk", webpFile(vp8x, image)[:33], nil, true},
+		{"not a webp", []byte("RIFF\x04\x00\x00\x00WAVE"), nil, true},
+		{"empty", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StripWebPMetadata(tt.data)
+			if tt.wantErr {
+				if err != ErrUnsupportedFormat {
+					t.Fatalf("error = %v, want ErrUnsupportedFormat", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("StripWebPMetadata() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package mail
+
+import (
+	"context"
+	"log"
+)
+
+// LogMailer is a Mailer that writes emails to the log instead of sending them. It is
+// meant for development.
+type LogMailer struct{}
+
+// NewLogMailer creates a new LogMailer
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the recipient, subject and plain text body
+func (m *LogMailer) Send(ctx context.Context, email Email) error {
+	if email.To == "" {
+		return errNoRecipient
+	}
+	log.Printf("Email to %s: %s\n%s", email.To, email.Subject, email.Text)
+	return nil
+}
@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vibe-code-hinge/backend/internal/utils"
+)
+
+// Email is a message to send to one recipient. Mailers send both bodies as alternatives
+// when both are set.
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// NewMailerFromConfig creates the Mailer selected by MAILER, either "log" (the default),
+// which writes emails to the log for development, "smtp" or "none". It returns a nil
+// Mailer for "none".
+func NewMailerFromConfig(config *utils.Config) (Mailer, error) {
+	switch backend := config.GetEnv("MAILER", "log"); backend {
+	case "log":
+		return NewLogMailer(), nil
+	case "smtp":
+		return NewSMTPMailer(SMTPConfig{
+			Host:     config.GetEnv("SMTP_HOST", ""),
+			Port:     config.GetEnvInt("SMTP_PORT", 587),
+			Username: config.GetEnv("SMTP_USERNAME", ""),
+			Password: config.GetEnv("SMTP_PASSWORD", ""),
+			From:     config.GetEnv("MAIL_FROM", ""),
+		})
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", backend)
+	}
+}
+
+// errNoRecipient is returned when an email has no recipient
+var errNoRecipient = errors.New("email has no recipient")
@@ -0,0 +1,119 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig holds the settings for an SMTP relay
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // Leave empty for relays that don't need authentication
+	Password string
+	From     string
+}
+
+// SMTPMailer is a Mailer that sends through an SMTP relay, upgrading to TLS with
+// STARTTLS when the server offers it
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+// NewSMTPMailer creates a new SMTPMailer
+func NewSMTPMailer(config SMTPConfig) (*SMTPMailer, error) {
+	if config.Host == "" || config.From == "" {
+		return nil, errors.New("SMTP host and from address are required")
+	}
+	return &SMTPMailer{config: config}, nil
+}
+
+// Send sends the email. net/smtp can't be cancelled, so ctx only stops a send that
+// hasn't started.
+func (m *SMTPMailer) Send(ctx context.Context, email Email) error {
+	if email.To == "" {
+		return errNoRecipient
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	body, err := m.buildMessage(email)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	return smtp.SendMail(addr, auth, m.config.From, []string{email.To}, body)
+}
+
+// buildMessage writes the email as MIME, with the text and HTML bodies as alternatives
+func (m *SMTPMailer) buildMessage(email Email) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", email.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if email.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&b, email.Text); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	boundaryBytes := make([]byte, 12)
+	if _, err := rand.Read(boundaryBytes); err != nil {
+		return nil, err
+	}
+	boundary := "vibe-" + hex.EncodeToString(boundaryBytes)
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	// Clients show the last alternative they understand, so plain text goes first
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	}
+	for _, part := range parts {
+		if part.body == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s\r\n", part.contentType)
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&b, part.body); err != nil {
+			return nil, err
+		}
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+
+	return b.Bytes(), nil
+}
+
+// writeQuotedPrintable writes body quoted-printable encoded, keeping lines short enough
+// for SMTP
+func writeQuotedPrintable(b *bytes.Buffer, body string) error {
+	w := quotedprintable.NewWriter(b)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Attribute types
+const (
+	AttributeTypeChoice      = "choice"       // One of Options, a JSON string
+	AttributeTypeMultiChoice = "multi_choice" // Some of Options, a JSON array of strings
+	AttributeTypeText        = "text"         // Free text up to Max characters, a JSON string
+	AttributeTypeNumber      = "number"       // A whole number from Min to Max, a JSON number
+)
+
+// AttributeDefinition describes a structured profile attribute
+type AttributeDefinition struct {
+	Key        string   `json:"key"`
+	Label      string   `json:"label"`
+	Type       string   `json:"type"`
+	Options    []string `json:"options,omitempty"` // Allowed values of choice attributes
+	Min        int      `json:"min,omitempty"`     // Smallest number
+	Max        int      `json:"max,omitempty"`     // Largest number, or longest text
+	Unit       string   `json:"unit,omitempty"`
+	Filterable bool     `json:"filterable"` // Can be used in feed attribute filters
+}
+
+// Answers shared by the drinking, smoking and drugs attributes
+var habitOptions = []string{"yes", "sometimes", "no", "prefer_not_to_say"}
+
+// ProfileAttributes is the catalog of structured profile attributes. Validation, feed
+// filters and GET /profile-attributes all read it, so a new attribute or option only
+// needs adding here.
+var ProfileAttributes = []AttributeDefinition{
+	{Key: "height", Label: "Height", Type: AttributeTypeNumber, Min: 90, Max: 250, Unit: "cm", Filterable: true},
+	{Key: "ethnicity", Label: "Ethnicity", Type: AttributeTypeMultiChoice, Filterable: true, Options: []string{
+		"black", "east_asian", "hispanic_latino", "middle_eastern", "native_american",
+		"pacific_islander", "south_asian", "southeast_asian", "white", "other",
+	}},
+	{Key: "religion", Label: "Religion", Type: AttributeTypeChoice, Filterable: true, Options: []string{
+		"agnostic", "atheist", "buddhist", "catholic", "christian", "hindu", "jewish",
+		"muslim", "sikh", "spiritual", "other",
+	}},
+	{Key: "politics", Label: "Politics", Type: AttributeTypeChoice, Filterable: true, Options: []string{
+		"liberal", "moderate", "conservative", "not_political", "other",
+	}},
+	{Key: "education", Label: "Education", Type: AttributeTypeChoice, Filterable: true, Options: []string{
+		"high_school", "undergrad", "postgrad",
+	}},
+	{Key: "school", Label: "School", Type: AttributeTypeText, Max: 100},
+	{Key: "job_title", Label: "Job title", Type: AttributeTypeText, Max: 100},
+	{Key: "company", Label: "Company", Type: AttributeTypeText, Max: 100},
+	{Key: "hometown", Label: "Hometown", Type: AttributeTypeText, Max: 100},
+	{Key: "children", Label: "Children", Type: AttributeTypeChoice, Filterable: true, Options: []string{
+		"dont_have_children", "have_children",
+	}},
+	{Key: "family_plans", Label: "Family plans", Type: AttributeTypeChoice, Filterable: true, Options: []string{
+		"want_children", "dont_want_children", "open_to_children", "not_sure",
+	}},
+	{Key: "drinking", Label: "Drinking", Type: AttributeTypeChoice, Filterable: true, Options: habitOptions},
+	{Key: "smoking", Label: "Smoking", Type: AttributeTypeChoice, Filterable: true, Options: habitOptions},
+	{Key: "drugs", Label: "Drugs", Type: AttributeTypeChoice, Filterable: true, Options: habitOptions},
+	{Key: "pronouns", Label: "Pronouns", Type: AttributeTypeMultiChoice, Options: []string{
+		"she/her", "he/him", "they/them", "ze/zir", "xe/xem", "other",
+	}},
+}
+
+// LookupAttribute returns the catalog definition of an attribute
+func LookupAttribute(key string) (AttributeDefinition, bool) {
+	for _, definition := range ProfileAttributes {
+		if definition.Key == key {
+			return definition, true
+		}
+	}
+	return AttributeDefinition{}, false
+}
+
+// ProfileAttribute is a user's answer for one attribute. Only visible attributes are
+// shown on their profile.
+type ProfileAttribute struct {
+	Value     json.RawMessage `json:"value"`
+	Visible   bool            `json:"visible"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// ProfileAttributeInput sets or clears an attribute. A null value clears it, an omitted
+// value only changes its visibility. New attributes are visible unless Visible is false.
+type ProfileAttributeInput struct {
+	Value   json.RawMessage `json:"value,omitempty"`
+	Visible *bool           `json:"visible,omitempty"`
+}
+
+// AttributeFilter narrows the feed to profiles whose visible answer for an attribute
+// is one of Values, or for numbers is from Min to Max. Profiles that don't show the
+// attribute are kept.
+type AttributeFilter struct {
+	Values []string `json:"values,omitempty"`
+	Min    *int     `json:"min,omitempty"`
+	Max    *int     `json:"max,omitempty"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Device represents a device registered to receive push notifications
+type Device struct {
+	ID         int64     `json:"id"`
+	Platform   string    `json:"platform"` // ios, android or web
+	Token      string    `json:"token"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// DeviceInput represents the request body for registering a device
+type DeviceInput struct {
+	Token    string `json:"token"`
+	Platform string `json:"platform"`
+}
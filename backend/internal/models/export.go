@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Conversation export formats
+const (
+	ExportFormatJSON = "json"
+	ExportFormatHTML = "html"
+)
+
+// ConversationExport represents a user's copy of a conversation, oldest message first
+type ConversationExport struct {
+	MatchID      int64               `json:"match_id"`
+	MatchedAt    time.Time           `json:"matched_at"`
+	ExportedBy   string              `json:"exported_by"`
+	ExportedAt   time.Time           `json:"exported_at"`
+	Format       string              `json:"format"`
+	Participants []ExportParticipant `json:"participants"`
+	Messages     []Message           `json:"messages"` // Attachment URLs are signed for the exporting user
+}
+
+// ExportParticipant represents one of the users in an exported conversation
+type ExportParticipant struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+}
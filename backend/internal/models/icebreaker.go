@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Icebreaker sources, what a suggested opener was generated from
+const (
+	IcebreakerSourcePrompt      = "prompt"       // One of the partner's prompt answers
+	IcebreakerSourceInterest    = "interest"     // Something both profiles share
+	IcebreakerSourceLikeComment = "like_comment" // The comment left on the like that led to the match
+	IcebreakerSourceGeneric     = "generic"      // Works for any match
+)
+
+// Icebreaker represents a suggested first message for a match
+type Icebreaker struct {
+	ID          int64     `json:"id"` // Pass as icebreaker_id when sending it
+	MatchID     int64     `json:"match_id"`
+	Text        string    `json:"text"`
+	Source      string    `json:"source"`
+	TemplateKey string    `json:"template_key"`
+	PromptID    *int64    `json:"prompt_id,omitempty"` // Set for prompt icebreakers
+	CreatedAt   time.Time `json:"created_at"`
+}
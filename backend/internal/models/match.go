@@ -14,6 +14,11 @@ type Match struct {
 	OtherUser     *Profile  `json:"other_user,omitempty"` // Populated when retrieving matches
 	LastMessage   *Message  `json:"last_message,omitempty"` // Last message in the conversation
 	UnreadCount   int       `json:"unread_count,omitempty"` // Number of unread messages
+	YourTurn      bool       `json:"your_turn"`            // The partner sent the last message
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // When the match expires without another message
+	Archived      bool       `json:"archived"`             // Hidden from the matches list unless archived=true
+	Muted         bool       `json:"muted"`                // No notifications for this match
+	Pinned        bool       `json:"pinned"`               // Sorted to the top of the matches list
 }
 
 // Swipe represents a user's swipe (like or skip) on another profile
@@ -25,7 +30,8 @@ type Swipe struct {
 	Message   string     `json:"message,omitempty"` // Message attached to a like
 	IsRose    bool       `json:"is_rose"`          // Whether this is a rose
 	CreatedAt time.Time  `json:"created_at"`
-	Profile   *Profile   `json:"profile,omitempty"` // Populated when retrieving likes
+	Profile   *Profile   `json:"profile,omitempty"` // Populated when retrieving likes, unless redacted
+	Redacted  bool       `json:"redacted,omitempty"` // The liker is hidden until the user goes premium
 }
 
 // SwipeInput represents the input for creating a swipe
@@ -53,6 +59,41 @@ type MatchWithProfile struct {
 	Profile Profile `json:"profile"`
 }
 
+// MatchPageParams represents cursor pagination parameters for the matches list
+type MatchPageParams struct {
+	Cursor   string // next_cursor from the previous page, empty for the first page
+	Limit    int
+	Archived bool // List archived matches instead of the rest
+}
+
+// MatchPage represents a page of matches, most recent conversation first
+type MatchPage struct {
+	Matches    []MatchWithProfile `json:"matches"`
+	NextCursor string             `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// Per-user flags on a match
+const (
+	MatchFlagArchived = "archived"
+	MatchFlagMuted    = "muted"
+	MatchFlagPinned   = "pinned"
+)
+
+// MatchState represents a user's flags on one of their matches
+type MatchState struct {
+	MatchID   int64     `json:"match_id"`
+	Archived  bool      `json:"archived"`
+	Muted     bool      `json:"muted"`
+	Pinned    bool      `json:"pinned"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Block represents a user the caller has blocked
+type Block struct {
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // MarkAsReadInput represents the input for marking messages as read
 type MarkAsReadInput struct {
 	MatchID int64 `json:"match_id"`
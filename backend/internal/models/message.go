@@ -2,21 +2,128 @@ package models
 
 import "time"
 
+// Message delivery states
+const (
+	MessageStatusSent      = "sent"
+	MessageStatusDelivered = "delivered"
+	MessageStatusRead      = "read"
+)
+
 // Message represents a message in a conversation
 type Message struct {
-	ID        int64     `json:"id"`
-	MatchID   int64     `json:"match_id"`
-	SenderID  string    `json:"sender_id"`
-	Message   string    `json:"message"`
-	IsRead    bool      `json:"is_read"`
+	ID            int64        `json:"id"`
+	MatchID       int64        `json:"match_id"`
+	SenderID      string       `json:"sender_id"`
+	Message       string       `json:"message"`
+	IsRead        bool         `json:"is_read"`
+	Status        string       `json:"status"`                 // sent, delivered or read
+	DeliveredAt   *time.Time   `json:"delivered_at,omitempty"` // When the recipient's device received it
+	ReadAt        *time.Time   `json:"read_at,omitempty"`      // Only set if the recipient shares read receipts
+	Edited        bool         `json:"edited"`
+	EditedAt      *time.Time   `json:"edited_at,omitempty"`
+	Deleted       bool         `json:"deleted"`                  // Unsent by the sender, Message is empty
+	DeletedAt     *time.Time   `json:"deleted_at,omitempty"`     // When the message was unsent
+	SafetyWarning bool         `json:"safety_warning,omitempty"` // Flagged by the safety pipeline, show the recipient a warning
+	CreatedAt     time.Time    `json:"created_at"`
+	Attachments   []Attachment `json:"attachments,omitempty"`
+	Reactions     []Reaction   `json:"reactions,omitempty"` // Populated when retrieving messages
+	Sender        *Profile     `json:"sender,omitempty"`    // Populated when retrieving messages
+}
+
+// Attachment kinds
+const (
+	AttachmentImage = "image"
+	AttachmentAudio = "audio"
+)
+
+// Attachment represents a photo or voice note uploaded to a match. URL is signed for the
+// user it was returned to and stops working at URLExpiresAt.
+type Attachment struct {
+	ID           int64     `json:"id"`
+	MatchID      int64     `json:"match_id"`
+	MessageID    *int64    `json:"message_id,omitempty"` // Nil until the attachment is sent
+	UploaderID   string    `json:"uploader_id"`
+	Kind         string    `json:"kind"` // image or audio
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	URL          string    `json:"url"`
+	URLExpiresAt time.Time `json:"url_expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MessageEdit represents a previous version of an edited message
+type MessageEdit struct {
+	ID              int64     `json:"id"`
+	MessageID       int64     `json:"message_id"`
+	PreviousMessage string    `json:"previous_message"`
+	EditedAt        time.Time `json:"edited_at"`
+}
+
+// Reaction represents a user's emoji reaction to a message
+type Reaction struct {
+	MessageID int64     `json:"message_id"`
+	UserID    string    `json:"user_id"`
+	Emoji     string    `json:"emoji"`
 	CreatedAt time.Time `json:"created_at"`
-	Sender    *Profile  `json:"sender,omitempty"` // Populated when retrieving messages
 }
 
 // MessageInput represents the input for creating a message
 type MessageInput struct {
-	MatchID  int64  `json:"match_id"`
-	Message  string `json:"message"`
+	MatchID       int64   `json:"match_id"`
+	Message       string  `json:"message"`
+	AttachmentIDs []int64 `json:"attachment_ids,omitempty"` // Uploaded with POST /matches/{id}/attachments
+	Confirmed     bool    `json:"confirmed,omitempty"`      // Send even though the safety pipeline asked for confirmation
+	IcebreakerID  int64   `json:"icebreaker_id,omitempty"`  // The suggestion from GET /matches/{id}/icebreakers being sent
+}
+
+// EditMessageInput represents the input for editing a message
+type EditMessageInput struct {
+	Message   string `json:"message"`
+	Confirmed bool   `json:"confirmed,omitempty"` // Save an edit the safety pipeline held for confirmation
+}
+
+// ReactionInput represents the input for reacting to a message
+type ReactionInput struct {
+	Emoji string `json:"emoji"`
+}
+
+// MessagePageParams represents keyset pagination parameters for message history
+type MessagePageParams struct {
+	BeforeID int64 // Only messages older than this message
+	AfterID  int64 // Only messages newer than this message
+	Limit    int
+}
+
+// MessagePage represents a page of messages, newest first
+type MessagePage struct {
+	Messages      []Message `json:"messages"`
+	HasMoreBefore bool      `json:"has_more_before"` // Older messages exist, page with before_id set to the last message
+	HasMoreAfter  bool      `json:"has_more_after"`  // Newer messages exist, page with after_id set to the first message
+}
+
+// MessageSearchResult represents a message matching a search, with the conversation it
+// belongs to
+type MessageSearchResult struct {
+	MessageID int64              `json:"message_id"`
+	MatchID   int64              `json:"match_id"`
+	SenderID  string             `json:"sender_id"`
+	Snippet   string             `json:"snippet"` // HTML-escaped, matched terms are wrapped in <mark>
+	Rank      float64            `json:"rank"`
+	CreatedAt time.Time          `json:"created_at"`
+	Match     MessageSearchMatch `json:"match"`
+}
+
+// MessageSearchMatch represents the conversation a search result was found in
+type MessageSearchMatch struct {
+	ID              int64  `json:"id"`
+	PartnerID       string `json:"partner_id"`
+	PartnerName     string `json:"partner_name"`
+	PartnerPhotoURL string `json:"partner_photo_url,omitempty"`
+}
+
+// MarkReadInput represents the input for marking a conversation as read
+type MarkReadInput struct {
+	MessageID int64 `json:"message_id"` // Read up to and including this message
 }
 
 // ConversationResponse represents a conversation with messages
@@ -27,19 +134,98 @@ type ConversationResponse struct {
 
 // MessageEvent represents a message event for SSE
 type MessageEvent struct {
-	Type      string    `json:"type"`
-	MessageID int64     `json:"message_id"`
-	MatchID   int64     `json:"match_id"`
-	SenderID  string    `json:"sender_id"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"`
+	Type          string       `json:"type"`
+	MessageID     int64        `json:"message_id"`
+	MatchID       int64        `json:"match_id"`
+	SenderID      string       `json:"sender_id"`
+	Message       string       `json:"message"`
+	Attachments   []Attachment `json:"attachments,omitempty"` // URLs are signed for the recipient
+	SafetyWarning bool         `json:"safety_warning,omitempty"`
+	Muted         bool         `json:"muted,omitempty"` // The recipient muted the match, don't alert them
+	CreatedAt     time.Time    `json:"created_at"`
 }
 
 // NotificationEvent represents a notification event for SSE
 type NotificationEvent struct {
-	Type        string    `json:"type"`
-	UserID      string    `json:"user_id"`
-	TargetID    int64     `json:"target_id,omitempty"`
-	Message     string    `json:"message,omitempty"`
-	Timestamp   time.Time `json:"timestamp"`
+	Type           string            `json:"type"`
+	NotificationID int64             `json:"notification_id,omitempty"` // Inbox entry, for marking it read
+	UserID         string            `json:"user_id"`
+	TargetID       int64             `json:"target_id,omitempty"`
+	Message        string            `json:"message,omitempty"`
+	Data           *NotificationData `json:"data,omitempty"`
+	Timestamp      time.Time         `json:"timestamp"`
+}
+
+// Chat WebSocket frame types sent by the client
+const (
+	ChatSendMessage = "send_message"
+	ChatTypingStart = "typing_start"
+	ChatTypingStop  = "typing_stop"
+	ChatRead        = "read"
+	ChatDelivered   = "delivered"
+)
+
+// Chat WebSocket frame types sent by the server, in addition to relayed events
+const (
+	ChatAck   = "ack"
+	ChatError = "error"
+)
+
+// ChatCommand represents a frame sent by the client over the chat WebSocket
+type ChatCommand struct {
+	Type          string  `json:"type"`
+	ClientID      string  `json:"client_id,omitempty"` // Echoed back in the ack so clients can match replies
+	MatchID       int64   `json:"match_id"`
+	MessageID     int64   `json:"message_id,omitempty"` // Read or delivered up to and including this message
+	Message       string  `json:"message,omitempty"`
+	AttachmentIDs []int64 `json:"attachment_ids,omitempty"`
+	Confirmed     bool    `json:"confirmed,omitempty"` // Send past a safety confirmation prompt
+	IcebreakerID  int64   `json:"icebreaker_id,omitempty"`
+}
+
+// ChatFrame represents a frame sent by the server over the chat WebSocket
+type ChatFrame struct {
+	Type     string      `json:"type"`
+	ClientID string      `json:"client_id,omitempty"`
+	EventID  int64       `json:"event_id,omitempty"` // Event log id for relayed events
+	Data     interface{} `json:"data,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Code     string      `json:"code,omitempty"` // Machine-readable error code, see ErrorResponse
+}
+
+// Chat event types for changes to existing messages
+const (
+	MessageEdited   = "message_edited"
+	MessageUnsent   = "message_unsent"
+	ReactionAdded   = "reaction_added"
+	ReactionRemoved = "reaction_removed"
+)
+
+// MessageUpdateEvent represents an edit, unsend or reaction relayed to the match partner
+type MessageUpdateEvent struct {
+	Type          string    `json:"type"`
+	MatchID       int64     `json:"match_id"`
+	MessageID     int64     `json:"message_id"`
+	UserID        string    `json:"user_id"`                  // The user who made the change
+	Message       string    `json:"message,omitempty"`        // The new text, for edits
+	Emoji         string    `json:"emoji,omitempty"`          // For reactions
+	SafetyWarning bool      `json:"safety_warning,omitempty"` // The edited text was flagged by the safety pipeline
+	At            time.Time `json:"at"`
+}
+
+// TypingEvent represents a typing indicator relayed to the match partner
+type TypingEvent struct {
+	Type    string    `json:"type"`
+	MatchID int64     `json:"match_id"`
+	UserID  string    `json:"user_id"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// ReceiptEvent represents a read or delivered receipt relayed to the message sender
+type ReceiptEvent struct {
+	Type      string    `json:"type"`
+	MatchID   int64     `json:"match_id"`
+	UserID    string    `json:"user_id"`    // The user who read or received the messages
+	MessageID int64     `json:"message_id"` // Up to and including this message
+	At        time.Time `json:"at"`
 }
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Notification represents an entry in a user's notification inbox
+type Notification struct {
+	ID        int64            `json:"id"`
+	Type      string           `json:"type"`
+	TargetID  int64            `json:"target_id,omitempty"`
+	Message   string           `json:"message,omitempty"`
+	Data      NotificationData `json:"data"`
+	IsRead    bool             `json:"is_read"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// NotificationData holds the deep-link targets of a notification. Only the fields that
+// apply to its type are set.
+type NotificationData struct {
+	MatchID   int64        `json:"match_id,omitempty"`
+	ProfileID string       `json:"profile_id,omitempty"`
+	MessageID int64        `json:"message_id,omitempty"`
+	SenderID  string       `json:"sender_id,omitempty"`
+	Preview   *LikePreview `json:"preview,omitempty"` // new_like and new_rose only
+}
+
+// LikePreview shows who sent a like or rose. Only premium members see the liker, everyone
+// else gets a redacted preview.
+type LikePreview struct {
+	SwipeID  int64  `json:"swipe_id"`
+	Redacted bool   `json:"redacted"`
+	Name     string `json:"name,omitempty"`
+	PhotoURL string `json:"photo_url,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// NotificationPageParams represents cursor pagination parameters for the inbox
+type NotificationPageParams struct {
+	Cursor string // next_cursor from the previous page, empty for the first page
+	Limit  int
+}
+
+// NotificationPage represents a page of notifications, newest first
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	NextCursor    string         `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// UnreadCount represents the number of unread notifications
+type UnreadCount struct {
+	UnreadCount int `json:"unread_count"`
+}
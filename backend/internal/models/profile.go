@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -21,15 +22,27 @@ type Profile struct {
 	CreatedAt  time.Time         `json:"created_at"`
 	UpdatedAt  time.Time         `json:"updated_at"`
 	OnboardingCompleted bool     `json:"onboarding_completed"`
+	Completeness int             `json:"completeness"` // 0 to 100, how filled in the profile is
+	Attributes map[string]json.RawMessage `json:"attributes,omitempty"` // Visible attribute values, keyed as in ProfileAttributes
 }
 
 // Photo represents a profile photo
 type Photo struct {
-	ID        int64     `json:"id"`
-	ProfileID string    `json:"profile_id"`
-	URL       string    `json:"url"`
-	IsPrimary bool      `json:"is_primary"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int64             `json:"id"`
+	ProfileID string            `json:"profile_id"`
+	URL       string            `json:"url"` // The large variant for uploaded photos
+	IsPrimary bool              `json:"is_primary"`
+	Position  int               `json:"position"`
+	Width     int               `json:"width,omitempty"`
+	Height    int               `json:"height,omitempty"`
+	Variants  map[string]string `json:"variants,omitempty"` // URL of each resized variant, keyed by name
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// PhotoOrderInput represents the new order of a profile's photos. It must list every
+// photo on the profile exactly once.
+type PhotoOrderInput struct {
+	PhotoIDs []int64 `json:"photo_ids"`
 }
 
 // ProfileInput represents input for creating or updating a profile
@@ -40,7 +53,7 @@ type ProfileInput struct {
 	Gender     string            `json:"gender"`
 	Location   string            `json:"location,omitempty"`
 	Occupation string            `json:"occupation,omitempty"`
-	Photos     []string          `json:"photos,omitempty"`
+	Photos     []string          `json:"photos,omitempty"` // Ignored, photos are uploaded through POST /profiles/me/photos
 	Vices      map[string]bool   `json:"vices,omitempty"`
 	Preferences map[string]interface{} `json:"preferences,omitempty"`
 }
@@ -75,6 +88,31 @@ type OnboardingInput struct {
 	Prompts     []ProfilePromptInput   `json:"prompts"`
 }
 
+// Onboarding steps, in the order they are completed
+const (
+	OnboardingStepBasics      = "basics"      // Name, date of birth and gender
+	OnboardingStepPhotos      = "photos"      // The minimum number of photos
+	OnboardingStepPrompts     = "prompts"     // Exactly three prompt answers
+	OnboardingStepPreferences = "preferences" // Who the user wants to see
+	OnboardingStepDone        = "done"
+)
+
+// OnboardingStatus represents where a user is in onboarding. Step is the first step
+// with something missing, or done once every step is complete.
+type OnboardingStatus struct {
+	Step         string                  `json:"step"`
+	Completed    bool                    `json:"completed"` // Onboarding was finished, the profile is shown in feeds
+	Completeness int                     `json:"completeness"`
+	Missing      []OnboardingRequirement `json:"missing"`
+}
+
+// OnboardingRequirement is something still missing from an onboarding step
+type OnboardingRequirement struct {
+	Step    string `json:"step"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // Age calculates the age of a profile based on date of birth
 func (p *Profile) Age() int {
 	today := time.Now()
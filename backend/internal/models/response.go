@@ -11,6 +11,7 @@ type SuccessResponse struct {
 type ErrorResponse struct {
 	Status string `json:"status"`
 	Error  string `json:"error"`
+	Code   string `json:"code,omitempty"` // Machine-readable code for errors clients act on
 }
 
 // NewSuccessResponse creates a new success response
@@ -30,6 +31,12 @@ func NewErrorResponse(error string) ErrorResponse {
 	}
 }
 
+// Error codes clients act on
+const (
+	ErrorCodeConfirmationRequired = "confirmation_required" // Resend with "confirmed": true after asking the user
+	ErrorCodeMessageBlocked       = "message_blocked"
+)
+
 // Pagination represents pagination metadata
 type Pagination struct {
 	Total       int `json:"total"`
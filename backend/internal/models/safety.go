@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Safety actions taken on an outgoing message, in increasing order of severity
+const (
+	SafetyAllow = "allow" // Sent as is
+	SafetyWarn  = "warn"  // Sent with a warning shown to the recipient
+	SafetyHold  = "hold"  // Only sent once the sender confirms
+	SafetyBlock = "block" // Not sent and reported to moderation
+)
+
+// Safety labels set by content classifiers
+const (
+	SafetyLabelHarassment          = "harassment"
+	SafetyLabelContactSolicitation = "contact_solicitation"
+	SafetyLabelScam                = "scam"
+	SafetyLabelOffPlatform         = "off_platform"
+	SafetyLabelMoneyRequest        = "money_request"
+	SafetyLabelCopyPaste           = "copy_paste"
+)
+
+// Classification represents a content classifier's verdict on a message
+type Classification struct {
+	Score  float64  `json:"score"` // 0 is harmless, 1 is certainly harmful
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Moderation report subjects
+const (
+	ModerationSubjectMessage = "message"
+	ModerationSubjectPhoto   = "photo"
+)
+
+// ModerationReport represents content flagged for review by the moderation team
+type ModerationReport struct {
+	ID            int64     `json:"id"`
+	SubjectType   string    `json:"subject_type"` // message or photo
+	SubjectUserID string    `json:"subject_user_id"`
+	MatchID       *int64    `json:"match_id,omitempty"`
+	PhotoID       *int64    `json:"photo_id,omitempty"` // For photo reports
+	Content       string    `json:"content"`
+	Source        string    `json:"source"` // What flagged it, e.g. the message classifier
+	Action        string    `json:"action"` // What was done automatically
+	Score         float64   `json:"score"`
+	Labels        []string  `json:"labels"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
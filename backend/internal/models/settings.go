@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// UserSettings represents a user's app settings
+type UserSettings struct {
+	UserID              string    `json:"user_id"`
+	ReadReceiptsEnabled bool      `json:"read_receipts_enabled"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// UserSettingsInput represents input for updating settings, omitted fields are left unchanged
+type UserSettingsInput struct {
+	ReadReceiptsEnabled *bool `json:"read_receipts_enabled,omitempty"`
+}
+
+// Notification channels a user can turn on or off per notification type
+const (
+	NotificationChannelInApp = "in_app"
+	NotificationChannelPush  = "push"
+	NotificationChannelEmail = "email"
+)
+
+// NotificationPreferenceTypes are the notification types users can set preferences for
+var NotificationPreferenceTypes = []string{"match", "message", "new_like", "new_rose", "standouts", "your_turn", "match_expired"}
+
+// ChannelPreferences represents which channels a notification type is delivered on
+type ChannelPreferences struct {
+	InApp bool `json:"in_app"` // Inbox entries and live notification events
+	Push  bool `json:"push"`
+	Email bool `json:"email"`
+}
+
+// QuietHours represents the daily period during which pushes are held back. Start and
+// end are "HH:MM" in the user's timezone, and the period may run past midnight.
+type QuietHours struct {
+	Enabled  bool   `json:"enabled"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone"` // IANA name, e.g. "Europe/London"
+}
+
+// NotificationPreferences represents a user's notification settings
+type NotificationPreferences struct {
+	UserID     string                        `json:"user_id"`
+	Types      map[string]ChannelPreferences `json:"types"`
+	QuietHours QuietHours                    `json:"quiet_hours"`
+}
+
+// ChannelPreferencesInput represents input for updating one notification type,
+// omitted channels are left unchanged
+type ChannelPreferencesInput struct {
+	InApp *bool `json:"in_app,omitempty"`
+	Push  *bool `json:"push,omitempty"`
+	Email *bool `json:"email,omitempty"`
+}
+
+// QuietHoursInput represents input for updating quiet hours, omitted fields are left unchanged
+type QuietHoursInput struct {
+	Enabled  *bool   `json:"enabled,omitempty"`
+	Start    *string `json:"start,omitempty"`
+	End      *string `json:"end,omitempty"`
+	Timezone *string `json:"timezone,omitempty"`
+}
+
+// NotificationPreferencesInput represents input for updating notification settings,
+// omitted types and fields are left unchanged
+type NotificationPreferencesInput struct {
+	Types      map[string]ChannelPreferencesInput `json:"types,omitempty"`
+	QuietHours *QuietHoursInput                   `json:"quiet_hours,omitempty"`
+}
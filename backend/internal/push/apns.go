@@ -0,0 +1,165 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// APNs endpoints, requests go over HTTP/2 which net/http negotiates on its own
+const (
+	apnsProductionEndpoint = "https://api.push.apple.com"
+	apnsSandboxEndpoint    = "https://api.sandbox.push.apple.com"
+)
+
+// apnsTokenLifetime is how long a provider token is reused. Apple rejects tokens older
+// than an hour and refreshing more often than every 20 minutes.
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNsConfig holds the settings for token-based APNs authentication
+type APNsConfig struct {
+	KeyID   string // Id of the .p8 signing key
+	TeamID  string
+	Topic   string // The app's bundle id
+	Sandbox bool   // Send to the development environment
+}
+
+// APNsProvider is a PushProvider for the Apple Push Notification service, used for iOS.
+// Requests are authenticated with an ES256 provider token signed with a .p8 key.
+type APNsProvider struct {
+	config   APNsConfig
+	key      crypto.Signer
+	endpoint string
+	client   *http.Client
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsProviderFromFile creates an APNsProvider from a .p8 signing key file
+func NewAPNsProviderFromFile(keyFile string, config APNsConfig) (*APNsProvider, error) {
+	if config.KeyID == "" || config.TeamID == "" || config.Topic == "" {
+		return nil, errors.New("APNs key id, team id and topic are required")
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	if !isP256(key) {
+		return nil, errors.New("APNs signing key must be an ECDSA P-256 key")
+	}
+
+	endpoint := apnsProductionEndpoint
+	if config.Sandbox {
+		endpoint = apnsSandboxEndpoint
+	}
+
+	return &APNsProvider{
+		config:   config,
+		key:      key,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// Send delivers the message as an alert. Data keys are added to the payload next to aps.
+func (p *APNsProvider) Send(ctx context.Context, token string, msg Message) error {
+	providerToken, err := p.getProviderToken()
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{}
+	for key, value := range msg.Data {
+		payload[key] = value
+	}
+	payload["aps"] = map[string]interface{}{
+		"alert": map[string]string{
+			"title": msg.Title,
+			"body":  msg.Body,
+		},
+		"sound": "default",
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", p.config.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return requestError("apns", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var failure struct {
+		Reason string `json:"reason"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	_ = json.Unmarshal(raw, &failure)
+
+	switch {
+	case resp.StatusCode == http.StatusGone, failure.Reason == "BadDeviceToken", failure.Reason == "Unregistered", failure.Reason == "DeviceTokenNotForTopic":
+		return ErrInvalidToken
+	case failure.Reason == "ExpiredProviderToken":
+		// Sign a new provider token on retry
+		p.mu.Lock()
+		p.token = ""
+		p.mu.Unlock()
+		return &ProviderError{Provider: "apns", StatusCode: resp.StatusCode, Reason: failure.Reason, Temporary: true}
+	}
+
+	return statusError("apns", resp, failure.Reason)
+}
+
+// getProviderToken returns the cached provider token, signing a new one when it is
+// due to be refreshed
+func (p *APNsProvider) getProviderToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.token != "" && now.Sub(p.issuedAt) < apnsTokenLifetime {
+		return p.token, nil
+	}
+
+	token, err := signJWT(
+		map[string]string{"alg": "ES256", "kid": p.config.KeyID},
+		map[string]interface{}{"iss": p.config.TeamID, "iat": now.Unix()},
+		p.key,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	p.token = token
+	p.issuedAt = now
+	return token, nil
+}
@@ -0,0 +1,224 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fcmScope is the OAuth scope needed to send through the FCM HTTP v1 API
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// fcmEndpoint is the FCM HTTP v1 API
+const fcmEndpoint = "https://fcm.googleapis.com"
+
+// fcmServiceAccount is the part of a Google service account key file FCM needs
+type fcmServiceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMProvider is a PushProvider for Firebase Cloud Messaging, used for Android and web.
+// It authenticates with a service account, exchanging a signed JWT for an access token
+// that is cached until shortly before it expires.
+type FCMProvider struct {
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	key         crypto.Signer
+	endpoint    string
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMProviderFromFile creates an FCMProvider from a service account key file. The
+// project id defaults to the one in the file.
+func NewFCMProviderFromFile(credentialsFile string, projectID string) (*FCMProvider, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var account fcmServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("invalid service account file: %w", err)
+	}
+	if projectID == "" {
+		projectID = account.ProjectID
+	}
+	if projectID == "" || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, errors.New("service account file needs project_id, client_email and private_key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	key, err := parsePrivateKey([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return &FCMProvider{
+		projectID:   projectID,
+		clientEmail: account.ClientEmail,
+		tokenURI:    account.TokenURI,
+		key:         key,
+		endpoint:    fcmEndpoint,
+		client:      &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// Send delivers the message through the FCM HTTP v1 API
+func (p *FCMProvider) Send(ctx context.Context, token string, msg Message) error {
+	accessToken, err := p.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": token,
+			"notification": map[string]string{
+				"title": msg.Title,
+				"body":  msg.Body,
+			},
+			"data": msg.Data,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := p.endpoint + "/v1/projects/" + url.PathEscape(p.projectID) + "/messages:send"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return requestError("fcm", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var failure struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	_ = json.Unmarshal(raw, &failure)
+
+	reason := failure.Error.Status
+	for _, detail := range failure.Error.Details {
+		if detail.ErrorCode != "" {
+			reason = detail.ErrorCode
+		}
+	}
+
+	// UNREGISTERED means the app was uninstalled or the token rotated
+	if resp.StatusCode == http.StatusNotFound || reason == "UNREGISTERED" {
+		return ErrInvalidToken
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		// The access token may have been revoked early, fetch a new one on retry
+		p.mu.Lock()
+		p.accessToken = ""
+		p.mu.Unlock()
+		return &ProviderError{Provider: "fcm", StatusCode: resp.StatusCode, Reason: reason, Temporary: true}
+	}
+
+	providerErr := statusError("fcm", resp, reason)
+	if failure.Error.Message != "" {
+		providerErr.Reason = strings.TrimSpace(reason + " " + failure.Error.Message)
+	}
+	return providerErr
+}
+
+// getAccessToken returns a cached access token, fetching a new one when it is about to
+// expire
+func (p *FCMProvider) getAccessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != "" && time.Now().Before(p.expiresAt) {
+		return p.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(
+		map[string]string{"alg": "RS256", "typ": "JWT"},
+		map[string]interface{}{
+			"iss":   p.clientEmail,
+			"scope": fcmScope,
+			"aud":   p.tokenURI,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+		p.key,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", requestError("fcm", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", statusError("fcm", resp, "token exchange failed: "+string(raw))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("fcm: token exchange returned no access token")
+	}
+
+	// Refresh a minute early so a token never expires mid-request
+	p.accessToken = token.AccessToken
+	p.expiresAt = now.Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return p.accessToken, nil
+}
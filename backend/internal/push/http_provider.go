@@ -0,0 +1,69 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPProvider is a PushProvider that posts each push as JSON to a URL instead of a real
+// push service. Point it at a local server to see what would be delivered and to test
+// failures: 404 and 410 responses mean an invalid token, 429 and 5xx a temporary error.
+//
+// The request body looks like
+//
+//	{"platform": "ios", "token": "...", "title": "...", "body": "...", "data": {...}}
+type HTTPProvider struct {
+	url      string
+	platform string
+	client   *http.Client
+}
+
+// NewHTTPProvider creates an HTTPProvider posting pushes for a platform to url
+func NewHTTPProvider(url string, platform string) *HTTPProvider {
+	return &HTTPProvider{
+		url:      url,
+		platform: platform,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send posts the message to the configured URL
+func (p *HTTPProvider) Send(ctx context.Context, token string, msg Message) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"platform": p.platform,
+		"token":    token,
+		"title":    msg.Title,
+		"body":     msg.Body,
+		"data":     msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return requestError("http", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return ErrInvalidToken
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return statusError("http", resp, strings.TrimSpace(string(raw)))
+}
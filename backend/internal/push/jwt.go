@@ -0,0 +1,84 @@
+package push
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+)
+
+// signJWT builds a compact JWT from header and claims, signed with key. RSA keys sign
+// with RS256 and ECDSA P-256 keys with ES256, set alg in the header to match.
+func signJWT(header map[string]string, claims map[string]interface{}, key crypto.Signer) (string, error) {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			return "", err
+		}
+	case *ecdsa.PrivateKey:
+		// JWS wants the raw r and s values, not the ASN.1 encoding ecdsa produces
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			return "", err
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	default:
+		return "", errors.New("unsupported signing key type")
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey reads a PEM encoded PKCS#8 or PKCS#1 private key, as found in FCM
+// service account files and APNs .p8 keys
+func parsePrivateKey(pemData []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("invalid private key")
+}
+
+// isP256 reports whether key is an ECDSA key on the P-256 curve, as ES256 needs
+func isP256(key crypto.Signer) bool {
+	k, ok := key.(*ecdsa.PrivateKey)
+	return ok && k.Curve == elliptic.P256()
+}
@@ -0,0 +1,157 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/utils"
+)
+
+// Device platforms a token can be registered for
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWeb     = "web"
+)
+
+// ErrInvalidToken is returned when the provider says a device token is no longer valid,
+// for example because the app was uninstalled. The token should be forgotten.
+var ErrInvalidToken = errors.New("device token is no longer valid")
+
+// Message is a push notification as shown on the device
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string // Delivered to the app alongside the alert, for deep links
+}
+
+// PushProvider delivers push notifications to devices on one push service
+type PushProvider interface {
+	// Send delivers msg to the device with the given token. It returns ErrInvalidToken
+	// when the token should be pruned and a temporary *ProviderError when the send is
+	// worth retrying.
+	Send(ctx context.Context, token string, msg Message) error
+}
+
+// ProviderError is a send the push service rejected or that didn't reach it
+type ProviderError struct {
+	Provider   string
+	StatusCode int           // 0 when the request failed before a response
+	Reason     string        // The service's error code or message
+	Temporary  bool          // The same send may succeed if retried
+	RetryAfter time.Duration // How long the service asked us to wait, if it did
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+	}
+	return fmt.Sprintf("%s: status %d: %s", e.Provider, e.StatusCode, e.Reason)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// IsTemporary reports whether a failed send is worth retrying
+func IsTemporary(err error) bool {
+	var providerErr *ProviderError
+	return errors.As(err, &providerErr) && providerErr.Temporary
+}
+
+// RetryAfter returns how long the push service asked us to wait before retrying, or 0
+func RetryAfter(err error) time.Duration {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.RetryAfter
+	}
+	return 0
+}
+
+// statusError builds the error for an unsuccessful response. Rate limiting and server
+// errors are temporary, anything else is not.
+func statusError(provider string, resp *http.Response, reason string) *ProviderError {
+	temporary := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return &ProviderError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Reason:     reason,
+		Temporary:  temporary,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// requestError builds the error for a request that got no response. These are always
+// temporary, the push service may not even have seen the request.
+func requestError(provider string, err error) *ProviderError {
+	return &ProviderError{Provider: provider, Temporary: true, Err: err}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// NewProvidersFromConfig creates the push providers selected by PUSH_PROVIDER, keyed by
+// the device platform they deliver to:
+//
+//   - "native" (the default) uses APNs for iOS and FCM for Android and web, each only
+//     when its credentials are configured
+//   - "http" posts every push as JSON to PUSH_HTTP_URL, a stand-in for local testing
+//   - "none" disables push
+func NewProvidersFromConfig(config *utils.Config) (map[string]PushProvider, error) {
+	providers := map[string]PushProvider{}
+
+	switch backend := config.GetEnv("PUSH_PROVIDER", "native"); backend {
+	case "native":
+		if keyFile := config.GetEnv("APNS_KEY_FILE", ""); keyFile != "" {
+			apns, err := NewAPNsProviderFromFile(keyFile, APNsConfig{
+				KeyID:   config.GetEnv("APNS_KEY_ID", ""),
+				TeamID:  config.GetEnv("APNS_TEAM_ID", ""),
+				Topic:   config.GetEnv("APNS_TOPIC", ""),
+				Sandbox: config.GetEnvBool("APNS_SANDBOX", false),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("APNs: %w", err)
+			}
+			providers[PlatformIOS] = apns
+		}
+		if credentialsFile := config.GetEnv("FCM_CREDENTIALS_FILE", ""); credentialsFile != "" {
+			fcm, err := NewFCMProviderFromFile(credentialsFile, config.GetEnv("FCM_PROJECT_ID", ""))
+			if err != nil {
+				return nil, fmt.Errorf("FCM: %w", err)
+			}
+			providers[PlatformAndroid] = fcm
+			providers[PlatformWeb] = fcm
+		}
+	case "http":
+		url := config.GetEnv("PUSH_HTTP_URL", "")
+		if url == "" {
+			return nil, errors.New("PUSH_HTTP_URL is required for the http push provider")
+		}
+		for _, platform := range []string{PlatformIOS, PlatformAndroid, PlatformWeb} {
+			providers[platform] = NewHTTPProvider(url, platform)
+		}
+	case "none":
+	default:
+		return nil, fmt.Errorf("unknown push provider %q", backend)
+	}
+
+	return providers, nil
+}
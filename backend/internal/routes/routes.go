@@ -1,12 +1,21 @@
 package routes
 
 import (
+	"context"
 	"database/sql"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/vibe-code-hinge/backend/internal/handlers"
+	"github.com/vibe-code-hinge/backend/internal/mail"
+	"github.com/vibe-code-hinge/backend/internal/middleware"
+	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/push"
 	"github.com/vibe-code-hinge/backend/internal/services"
+	"github.com/vibe-code-hinge/backend/internal/storage"
+	"github.com/vibe-code-hinge/backend/internal/utils"
 )
 
 // SetupRoutes configures all the routes for the API
@@ -20,6 +29,75 @@ func SetupRoutes(router *mux.Router, db *sql.DB) {
 	promptService := services.NewPromptService(db)
 	feedService := services.NewFeedService(db)
 	notificationService := services.NewNotificationService(db)
+	settingsService := services.NewSettingsService(db)
+	icebreakerService := services.NewIcebreakerService(db)
+
+	// Wire up real-time delivery
+	matchingService.SetNotificationService(notificationService)
+	messageService.Initialize(matchingService, notificationService)
+
+	// Fan real-time events out across API instances through Postgres LISTEN/NOTIFY
+	config := utils.NewConfig()
+	if config.GetEnv("EVENT_BROKER", "postgres") == "postgres" {
+		broker, err := services.NewPostgresBroker(db, config.GetDatabaseURL())
+		if err != nil {
+			log.Printf("Failed to start Postgres event broker, delivering events in-process only: %v", err)
+		} else {
+			notificationService.SetBroker(broker)
+		}
+	}
+
+	// Chat attachments and profile photos
+	var attachmentService *services.AttachmentService
+	var photoService *services.PhotoService
+	blobStore, err := storage.NewBlobStoreFromConfig(config)
+	if err != nil {
+		log.Printf("Failed to create blob store, chat attachments and photo uploads are disabled: %v", err)
+	} else {
+		attachmentService = services.NewAttachmentService(db, blobStore)
+		messageService.SetAttachmentService(attachmentService)
+		photoService = services.NewPhotoService(db, blobStore)
+	}
+
+	// Push notifications for users who aren't connected
+	pushProviders, err := push.NewProvidersFromConfig(config)
+	if err != nil {
+		log.Printf("Failed to create push providers, push notifications are disabled: %v", err)
+	}
+	pushService := services.NewPushService(db, pushProviders)
+	notificationService.SetPushService(pushService)
+
+	// Daily email digests
+	mailer, err := mail.NewMailerFromConfig(config)
+	if err != nil {
+		log.Printf("Failed to create mailer, email digests are disabled: %v", err)
+	}
+	notificationService.SetMailer(mailer)
+
+	// Match, message and photo side effects are delivered through the outbox once committed
+	outbox := services.NewOutboxDispatcher(db)
+	matchingService.SetOutbox(outbox)
+	messageService.SetOutbox(outbox)
+	services.NewModerationService(db).SetOutbox(outbox)
+	if photoService != nil {
+		photoService.SetOutbox(outbox)
+	}
+
+	// Background jobs
+	go outbox.Run(context.Background(), time.Second)
+	go notificationService.RunEventLogCleanup(context.Background(), time.Hour)
+	go matchingService.RunMatchJobs(context.Background(), 15*time.Minute)
+	go notificationService.RunNotificationJobs(context.Background(), time.Minute)
+	if attachmentService != nil {
+		go attachmentService.RunAttachmentCleanup(context.Background(), time.Hour)
+	}
+
+	// Authentication for routes that take the caller from the token
+	jwtSecret := config.GetEnv("JWT_SECRET", "")
+	if jwtSecret == "" {
+		log.Println("JWT_SECRET is not set, authenticated routes will reject all requests")
+	}
+	requireAuth := middleware.Auth(jwtSecret)
 
 	// Create handlers
 	authHandler := handlers.NewAuthHandler(userService)
@@ -30,6 +108,13 @@ func SetupRoutes(router *mux.Router, db *sql.DB) {
 	promptHandler := handlers.NewPromptHandler(promptService)
 	feedHandler := handlers.NewFeedHandler(feedService)
 	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	chatHandler := handlers.NewChatHandler(messageService, notificationService)
+	settingsHandler := handlers.NewSettingsHandler(settingsService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService)
+	icebreakerHandler := handlers.NewIcebreakerHandler(icebreakerService)
+	deviceHandler := handlers.NewDeviceHandler(pushService)
+	photoHandler := handlers.NewPhotoHandler(photoService)
+	onboardingHandler := handlers.NewOnboardingHandler(profileService)
 
 	// Health check endpoint
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -43,15 +128,39 @@ func SetupRoutes(router *mux.Router, db *sql.DB) {
 	router.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
 
 	// Profile routes
+	router.HandleFunc("/profile-attributes", profileHandler.GetAttributeCatalog).Methods("GET")
+	router.HandleFunc("/profiles/me/attributes", profileHandler.GetAttributes).Methods("GET")
+	router.HandleFunc("/profiles/me/attributes", profileHandler.UpdateAttributes).Methods("PUT")
 	router.HandleFunc("/profiles/{id}", profileHandler.GetProfile).Methods("GET")
 	router.HandleFunc("/profiles/{id}", profileHandler.UpdateProfile).Methods("PUT")
 	router.HandleFunc("/profiles/{id}/prompts", profileHandler.GetProfilePrompts).Methods("GET")
 	router.HandleFunc("/profiles/{id}/prompts", profileHandler.UpdateProfilePrompts).Methods("PUT")
+	router.HandleFunc("/profiles/{id}/prompts/{prompt_id}", profileHandler.DeleteProfilePrompt).Methods("DELETE")
+
+	// Onboarding routes, profiles are only shown in feeds once onboarding is complete
+	router.HandleFunc("/onboarding", onboardingHandler.GetOnboarding).Methods("GET")
+	router.HandleFunc("/onboarding/complete", onboardingHandler.CompleteOnboarding).Methods("POST")
+
+	// Profile photo routes, photos are served from their unguessable URLs
+	if photoService != nil {
+		router.HandleFunc("/profiles/me/photos", photoHandler.GetPhotos).Methods("GET")
+		router.HandleFunc("/profiles/me/photos", photoHandler.UploadPhoto).Methods("POST")
+		router.HandleFunc("/profiles/me/photos/order", photoHandler.ReorderPhotos).Methods("PUT")
+		router.HandleFunc("/profiles/me/photos/{id}", photoHandler.DeletePhoto).Methods("DELETE")
+		router.HandleFunc("/profiles/me/photos/{id}/primary", photoHandler.SetPrimaryPhoto).Methods("PUT")
+		router.HandleFunc("/photos/{profile_id}/{key}/{variant}", photoHandler.ServePhoto).Methods("GET")
+	}
 
 	// User Preferences routes
 	router.HandleFunc("/preferences", preferenceHandler.GetPreferences).Methods("GET")
 	router.HandleFunc("/preferences", preferenceHandler.UpdatePreferences).Methods("PUT")
 
+	// User Settings routes
+	router.HandleFunc("/settings", settingsHandler.GetSettings).Methods("GET")
+	router.HandleFunc("/settings", settingsHandler.UpdateSettings).Methods("PUT")
+	router.HandleFunc("/settings/notifications", settingsHandler.GetNotificationPreferences).Methods("GET")
+	router.HandleFunc("/settings/notifications", settingsHandler.UpdateNotificationPreferences).Methods("PUT")
+
 	// Prompts routes
 	router.HandleFunc("/prompts", promptHandler.GetDefaultPrompts).Methods("GET")
 
@@ -69,12 +178,53 @@ func SetupRoutes(router *mux.Router, db *sql.DB) {
 	// Likes and Matches routes
 	router.HandleFunc("/likes", matchingHandler.GetLikes).Methods("GET")
 	router.HandleFunc("/matches", matchingHandler.GetMatches).Methods("GET")
+	router.HandleFunc("/matches/{id}/archive", matchingHandler.SetMatchFlag(models.MatchFlagArchived, true)).Methods("PUT")
+	router.HandleFunc("/matches/{id}/archive", matchingHandler.SetMatchFlag(models.MatchFlagArchived, false)).Methods("DELETE")
+	router.HandleFunc("/matches/{id}/mute", matchingHandler.SetMatchFlag(models.MatchFlagMuted, true)).Methods("PUT")
+	router.HandleFunc("/matches/{id}/mute", matchingHandler.SetMatchFlag(models.MatchFlagMuted, false)).Methods("DELETE")
+	router.HandleFunc("/matches/{id}/pin", matchingHandler.SetMatchFlag(models.MatchFlagPinned, true)).Methods("PUT")
+	router.HandleFunc("/matches/{id}/pin", matchingHandler.SetMatchFlag(models.MatchFlagPinned, false)).Methods("DELETE")
+
+	// Block routes, a block hides both users from each other and ends their conversation
+	router.HandleFunc("/blocks", matchingHandler.GetBlocks).Methods("GET")
+	router.HandleFunc("/users/{id}/block", matchingHandler.BlockUser).Methods("PUT")
+	router.HandleFunc("/users/{id}/block", matchingHandler.UnblockUser).Methods("DELETE")
 
 	// Messaging routes
 	router.HandleFunc("/matches/{id}/messages", messageHandler.GetMessages).Methods("GET")
 	router.HandleFunc("/matches/{id}/messages", messageHandler.CreateMessage).Methods("POST")
+	router.HandleFunc("/matches/{id}/read", messageHandler.MarkRead).Methods("POST")
+	router.HandleFunc("/matches/{id}/export", messageHandler.ExportConversation).Methods("GET")
+	router.HandleFunc("/matches/{id}/icebreakers", icebreakerHandler.GetIcebreakers).Methods("GET")
+	router.HandleFunc("/messages/search", messageHandler.SearchMessages).Methods("GET")
+	router.HandleFunc("/messages/{id}", messageHandler.EditMessage).Methods("PUT")
+	router.HandleFunc("/messages/{id}", messageHandler.UnsendMessage).Methods("DELETE")
+	router.HandleFunc("/messages/{id}/edits", messageHandler.GetMessageEdits).Methods("GET")
+	router.HandleFunc("/messages/{id}/reaction", messageHandler.SetReaction).Methods("PUT")
+	router.HandleFunc("/messages/{id}/reaction", messageHandler.RemoveReaction).Methods("DELETE")
+
+	// Attachment routes, downloads are authorized by the signed URL
+	if attachmentService != nil {
+		router.HandleFunc("/matches/{id}/attachments", attachmentHandler.UploadAttachment).Methods("POST")
+		router.HandleFunc("/attachments/{id}", attachmentHandler.DownloadAttachment).Methods("GET")
+	}
+
+	// Notification inbox routes
+	router.HandleFunc("/notifications", notificationHandler.GetNotifications).Methods("GET")
+	router.HandleFunc("/notifications/unread-count", notificationHandler.GetUnreadCount).Methods("GET")
+	router.HandleFunc("/notifications/read-all", notificationHandler.MarkAllRead).Methods("POST")
+	router.HandleFunc("/notifications/{id}/read", notificationHandler.MarkRead).Methods("POST")
+
+	// Push notification device routes
+	router.HandleFunc("/devices", deviceHandler.RegisterDevice).Methods("POST")
+	router.HandleFunc("/devices/{token}", deviceHandler.UnregisterDevice).Methods("DELETE")
 
 	// Server-Sent Events (SSE) routes
-	router.HandleFunc("/events/messages", notificationHandler.MessageEvents).Methods("GET")
-	router.HandleFunc("/events/notifications", notificationHandler.NotificationEvents).Methods("GET")
+	eventsRouter := router.PathPrefix("/events").Subrouter()
+	eventsRouter.Use(requireAuth)
+	eventsRouter.HandleFunc("/messages", notificationHandler.MessageEvents).Methods("GET")
+	eventsRouter.HandleFunc("/notifications", notificationHandler.NotificationEvents).Methods("GET")
+
+	// WebSocket chat route
+	router.Handle("/ws", requireAuth(http.HandlerFunc(chatHandler.ServeWS))).Methods("GET")
 }
@@ -0,0 +1,548 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vibe-code-hinge/backend/internal/imaging"
+	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/storage"
+	"github.com/vibe-code-hinge/backend/internal/utils"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Attachment size limits by kind
+const (
+	MaxImageAttachmentSize = 10 << 20
+	MaxAudioAttachmentSize = 5 << 20
+)
+
+// Photos are re-encoded to drop their metadata, within the same pixel limit as profile
+// photos since decoding needs 4 bytes a pixel
+const (
+	maxAttachmentPixels   = maxPhotoPixels
+	attachmentJPEGQuality = 85
+)
+
+// maxAttachmentsPerMessage caps how many attachments a single message can carry
+const maxAttachmentsPerMessage = 10
+
+// defaultAttachmentURLTTL is how long a signed download URL stays valid
+const defaultAttachmentURLTTL = time.Hour
+
+// unsentAttachmentMaxAge is how long an uploaded attachment can wait to be sent before
+// it is deleted
+const unsentAttachmentMaxAge = 24 * time.Hour
+
+// sniffLength is how many bytes http.DetectContentType looks at
+const sniffLength = 512
+
+// Errors returned by the attachment service
+var (
+	ErrAttachmentNotFound    = errors.New("attachment not found")
+	ErrUnsupportedAttachment = errors.New("attachment must be a JPEG, PNG, GIF or WebP image or an MP3, M4A, Ogg, WebM or WAV voice note")
+	ErrAttachmentTooLarge    = errors.New("attachment is too large")
+	ErrInvalidAttachments    = errors.New("attachments not found, already sent or from another match")
+	ErrInvalidAttachmentURL  = errors.New("attachment link is invalid or has expired")
+)
+
+// imageTypes are the sniffed content types accepted as photos
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// audioTypes maps sniffed content types to the type voice notes are stored with.
+// Containers that can hold video are only accepted when the client declared audio.
+var audioTypes = map[string]struct {
+	contentType   string
+	needsDeclared bool
+}{
+	"audio/mpeg":      {"audio/mpeg", false},
+	"audio/wave":      {"audio/wav", false},
+	"application/ogg": {"audio/ogg", false},
+	"video/mp4":       {"audio/mp4", true},
+	"video/webm":      {"audio/webm", true},
+}
+
+// AttachmentService handles photo and voice note attachments in chat
+type AttachmentService struct {
+	BaseService
+	store     storage.BlobStore
+	urlBase   string
+	urlSecret string
+	urlTTL    time.Duration
+}
+
+// NewAttachmentService creates a new AttachmentService storing attachments in store
+func NewAttachmentService(db *sql.DB, store storage.BlobStore) *AttachmentService {
+	config := utils.NewConfig()
+
+	secret := config.GetEnv("ATTACHMENT_URL_SECRET", "")
+	if secret == "" {
+		if jwtSecret := config.GetEnv("JWT_SECRET", ""); jwtSecret != "" {
+			// Never sign links with the JWT key itself, a leaked link signature must not
+			// help forge tokens or the other way round
+			secret = deriveAttachmentURLSecret(jwtSecret)
+		} else {
+			// Links only work on this instance until it restarts, which is fine for development
+			log.Println("ATTACHMENT_URL_SECRET is not set, signing attachment links with a random key")
+			secret, _ = utils.GenerateRandomString(32)
+		}
+	}
+
+	return &AttachmentService{
+		BaseService: NewBaseService(db),
+		store:       store,
+		urlBase:     strings.TrimSuffix(config.GetEnv("ATTACHMENT_URL_BASE", "/api/v1"), "/"),
+		urlSecret:   secret,
+		urlTTL:      config.GetEnvDuration("ATTACHMENT_URL_TTL", defaultAttachmentURLTTL),
+	}
+}
+
+// deriveAttachmentURLSecret derives a key for signing attachment links from the JWT
+// secret with HKDF-SHA256, so the two are independent
+func deriveAttachmentURLSecret(jwtSecret string) string {
+	key := make([]byte, 32)
+	reader := hkdf.New(sha256.New, []byte(jwtSecret), nil, []byte("vibe attachment url signing"))
+	if _, err := io.ReadFull(reader, key); err != nil {
+		panic(err) // Only fails when asking for more than 255 blocks
+	}
+	return hex.EncodeToString(key)
+}
+
+// UploadAttachment stores a photo or voice note for a match. The type is sniffed from
+// the content rather than trusted from the client, and photos are stripped of their
+// metadata. The attachment is returned with a
+// download URL for the uploader and is sent by passing its id to SendMessage.
+func (s *AttachmentService) UploadAttachment(ctx context.Context, userID string, matchID int64, body io.Reader, size int64, declaredType string) (*models.Attachment, error) {
+	db := s.GetDB()
+
+	// Verify match and user participation
+	var blocked bool
+	err := db.QueryRowContext(ctx, `
+		SELECT `+blockedSQL("m.user1_id", "m.user2_id")+`
+		FROM matches m
+		WHERE m.id = $1 AND (m.user1_id = $2 OR m.user2_id = $2)
+	`, matchID, userID).Scan(&blocked)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("match not found or user not part of match")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrUserBlocked
+	}
+
+	if size <= 0 {
+		return nil, ErrUnsupportedAttachment
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(body, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	kind, contentType, err := sniffAttachment(head, declaredType)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxAttachmentSize(kind) {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	content := io.MultiReader(bytes.NewReader(head), body)
+	if kind == models.AttachmentImage {
+		// Photos are read whole to strip their metadata, GPS coordinates included
+		data, err := io.ReadAll(io.LimitReader(content, MaxImageAttachmentSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > MaxImageAttachmentSize {
+			return nil, ErrAttachmentTooLarge
+		}
+		if data, err = scrubImage(data, contentType); err != nil {
+			return nil, err
+		}
+		content = bytes.NewReader(data)
+		size = int64(len(data))
+	}
+
+	random, err := utils.GenerateRandomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("attachments/%d/%s", matchID, hex.EncodeToString(random))
+
+	if err := s.store.Put(ctx, key, content, size, contentType); err != nil {
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		MatchID:     matchID,
+		UploaderID:  userID,
+		Kind:        kind,
+		ContentType: contentType,
+		Size:        size,
+		CreatedAt:   time.Now(),
+	}
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO message_attachments (match_id, uploader_id, kind, content_type, size_bytes, storage_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, matchID, userID, kind, contentType, size, key, attachment.CreatedAt).Scan(&attachment.ID)
+	if err != nil {
+		s.deleteBlobs(ctx, []string{key})
+		return nil, err
+	}
+
+	s.signAttachment(attachment, userID)
+	return attachment, nil
+}
+
+// OpenAttachment verifies a signed download URL and opens the attachment for the user
+// it was signed for. The user must still be part of the match, neither of the pair may
+// have blocked the other, and attachments that have not been sent yet can only be
+// downloaded by their uploader.
+func (s *AttachmentService) OpenAttachment(ctx context.Context, attachmentID int64, userID string, expires int64, signature string) (io.ReadCloser, *models.Attachment, error) {
+	if time.Now().Unix() > expires ||
+		!utils.VerifySignature(attachmentSigningString(attachmentID, userID, expires), signature, s.urlSecret) {
+		return nil, nil, ErrInvalidAttachmentURL
+	}
+
+	var attachment models.Attachment
+	var key string
+	err := s.GetDB().QueryRowContext(ctx, `
+		SELECT a.id, a.match_id, a.message_id, a.uploader_id, a.kind, a.content_type, a.size_bytes, a.storage_key, a.created_at
+		FROM message_attachments a
+		JOIN matches m ON m.id = a.match_id
+		WHERE a.id = $1
+		  AND (m.user1_id = $2 OR m.user2_id = $2)
+		  AND (a.message_id IS NOT NULL OR a.uploader_id = $2)
+		  AND NOT `+blockedSQL("m.user1_id", "m.user2_id")+`
+	`, attachmentID, userID).Scan(
+		&attachment.ID,
+		&attachment.MatchID,
+		&attachment.MessageID,
+		&attachment.UploaderID,
+		&attachment.Kind,
+		&attachment.ContentType,
+		&attachment.Size,
+		&key,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, err
+	}
+
+	body, err := s.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return body, &attachment, nil
+}
+
+// PruneUnsentAttachments deletes attachments that were uploaded but never sent
+func (s *AttachmentService) PruneUnsentAttachments(ctx context.Context) (int, error) {
+	rows, err := s.GetDB().QueryContext(ctx, `
+		DELETE FROM message_attachments
+		WHERE message_id IS NULL AND created_at < $1
+		RETURNING storage_key
+	`, time.Now().Add(-unsentAttachmentMaxAge))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return 0, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	s.deleteBlobs(ctx, keys)
+	return len(keys), nil
+}
+
+// RunAttachmentCleanup prunes unsent attachments every interval until ctx is cancelled
+func (s *AttachmentService) RunAttachmentCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.PruneUnsentAttachments(ctx)
+			if err != nil {
+				log.Printf("Failed to prune unsent attachments: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Pruned %d unsent attachments", deleted)
+			}
+		}
+	}
+}
+
+// linkAttachments attaches the user's unsent uploads to a message in the same match and
+// returns them without URLs
+func (s *AttachmentService) linkAttachments(ctx context.Context, tx *sql.Tx, userID string, matchID int64, messageID int64, attachmentIDs []int64) ([]models.Attachment, error) {
+	ids := make([]int64, 0, len(attachmentIDs))
+	seen := make(map[int64]bool, len(attachmentIDs))
+	for _, id := range attachmentIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > maxAttachmentsPerMessage {
+		return nil, fmt.Errorf("a message can carry at most %d attachments", maxAttachmentsPerMessage)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE message_attachments
+		SET message_id = $1
+		WHERE id = ANY($2) AND uploader_id = $3 AND match_id = $4 AND message_id IS NULL
+		RETURNING id, match_id, message_id, uploader_id, kind, content_type, size_bytes, created_at
+	`, messageID, pq.Array(ids), userID, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments, err := scanAttachments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) != len(ids) {
+		return nil, ErrInvalidAttachments
+	}
+
+	return attachments, nil
+}
+
+// unlinkMessageAttachments deletes a message's attachments and returns their storage
+// keys, so the blobs can be deleted once the transaction commits
+func (s *AttachmentService) unlinkMessageAttachments(ctx context.Context, tx *sql.Tx, messageID int64) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		DELETE FROM message_attachments
+		WHERE message_id = $1
+		RETURNING storage_key
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// attachToMessages loads the attachments for a page of messages in a single query, with
+// URLs signed for userID
+func (s *AttachmentService) attachToMessages(ctx context.Context, userID string, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(messages))
+	index := make(map[int64]int, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+		index[msg.ID] = i
+	}
+
+	rows, err := s.GetDB().QueryContext(ctx, `
+		SELECT id, match_id, message_id, uploader_id, kind, content_type, size_bytes, created_at
+		FROM message_attachments
+		WHERE message_id = ANY($1)
+		ORDER BY id ASC
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	attachments, err := scanAttachments(rows)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		s.signAttachment(&attachment, userID)
+		i := index[*attachment.MessageID]
+		messages[i].Attachments = append(messages[i].Attachments, attachment)
+	}
+
+	return nil
+}
+
+// signedFor returns a copy of attachments with URLs signed for userID
+func (s *AttachmentService) signedFor(attachments []models.Attachment, userID string) []models.Attachment {
+	signed := make([]models.Attachment, len(attachments))
+	copy(signed, attachments)
+	for i := range signed {
+		s.signAttachment(&signed[i], userID)
+	}
+	return signed
+}
+
+// signAttachment sets a download URL on the attachment that only userID can use and
+// that expires after the configured TTL
+func (s *AttachmentService) signAttachment(attachment *models.Attachment, userID string) {
+	expiresAt := time.Now().Add(s.urlTTL).Truncate(time.Second)
+	expires := expiresAt.Unix()
+
+	query := url.Values{}
+	query.Set("user_id", userID)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("sig", utils.SignString(attachmentSigningString(attachment.ID, userID, expires), s.urlSecret))
+
+	attachment.URL = fmt.Sprintf("%s/attachments/%d?%s", s.urlBase, attachment.ID, query.Encode())
+	attachment.URLExpiresAt = expiresAt
+}
+
+// deleteBlobs removes stored attachments, logging failures since the rows are already gone
+func (s *AttachmentService) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete attachment blob %s: %v", key, err)
+		}
+	}
+}
+
+// attachmentSigningString is the string signed in attachment download URLs
+func attachmentSigningString(attachmentID int64, userID string, expires int64) string {
+	return fmt.Sprintf("attachment:%d:%s:%d", attachmentID, userID, expires)
+}
+
+// scanAttachments scans attachment rows without storage keys
+func scanAttachments(rows *sql.Rows) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	for rows.Next() {
+		var attachment models.Attachment
+		if err := rows.Scan(
+			&attachment.ID,
+			&attachment.MatchID,
+			&attachment.MessageID,
+			&attachment.UploaderID,
+			&attachment.Kind,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+// sniffAttachment works out the kind and stored content type of an upload from its
+// first bytes
+func sniffAttachment(head []byte, declaredType string) (string, string, error) {
+	sniffed := http.DetectContentType(head)
+	if i := strings.IndexByte(sniffed, ';'); i >= 0 {
+		sniffed = sniffed[:i]
+	}
+	// DetectContentType only knows mp4 brands, voice notes recorded on iOS are M4A
+	if sniffed == "application/octet-stream" && len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		sniffed = "video/mp4"
+	}
+
+	if imageTypes[sniffed] {
+		return models.AttachmentImage, sniffed, nil
+	}
+
+	if audio, ok := audioTypes[sniffed]; ok {
+		if audio.needsDeclared && !strings.HasPrefix(declaredType, "audio/") {
+			return "", "", ErrUnsupportedAttachment
+		}
+		return models.AttachmentAudio, audio.contentType, nil
+	}
+
+	return "", "", ErrUnsupportedAttachment
+}
+
+// scrubImage removes metadata such as EXIF GPS coordinates from a photo before it is
+// stored. JPEGs and PNGs are decoded, turned upright and re-encoded with only their
+// pixels, transparency flattened onto white. WebPs have their metadata chunks dropped,
+// and GIFs, which have no EXIF, are kept as they are so animations still play.
+func scrubImage(data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg", "image/png":
+		_, width, height, err := imaging.Inspect(data)
+		if err != nil {
+			return nil, ErrUnsupportedAttachment
+		}
+		if width*height > maxAttachmentPixels {
+			return nil, ErrAttachmentTooLarge
+		}
+		img, err := imaging.Decode(data)
+		if err != nil {
+			return nil, ErrUnsupportedAttachment
+		}
+		if contentType == "image/png" {
+			return imaging.EncodePNG(img)
+		}
+		return imaging.EncodeJPEG(img, attachmentJPEGQuality)
+	case "image/webp":
+		stripped, err := imaging.StripWebPMetadata(data)
+		if err != nil {
+			return nil, ErrUnsupportedAttachment
+		}
+		return stripped, nil
+	default:
+		return data, nil
+	}
+}
+
+// maxAttachmentSize returns the size limit for an attachment kind
+func maxAttachmentSize(kind string) int64 {
+	if kind == models.AttachmentAudio {
+		return MaxAudioAttachmentSize
+	}
+	return MaxImageAttachmentSize
+}
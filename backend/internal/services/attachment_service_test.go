@@ -0,0 +1,75 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withAPP1 inserts an EXIF APP1 segment carrying GPS text after a JPEG's start of image
+// marker
+func withAPP1(data []byte) []byte {
+	payload := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08GPS 51.5074N 0.1278W")
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestScrubImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+
+	var jpegData, pngData, gifData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(&gifData, image.NewPaletted(image.Rect(0, 0, 4, 4), []color.Color{color.Black}), nil); err != nil {
+		t.Fatal(err)
+	}
+	webp := []byte("RIFF\x20\x00\x00\x00WEBPEXIF\x0a\x00\x00\x00GPS 51.5N0VP8L\x01\x00\x00\x00\x2f\x00")
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		wantErr     error
+	}{
+		{"jpeg with exif", withAPP1(jpegData.Bytes()), "image/jpeg", nil},
+		{"png", pngData.Bytes(), "image/png", nil},
+		{"gif", gifData.Bytes(), "image/gif", nil},
+		{"webp with exif", webp, "image/webp", nil},
+		{"broken jpeg", jpegData.Bytes()[:20], "image/jpeg", ErrUnsupportedAttachment},
+		{"broken webp", webp[:20], "image/webp", ErrUnsupportedAttachment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scrubImage(tt.data, tt.contentType)
+			if err != tt.wantErr {
+				t.Fatalf("scrubImage() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if bytes.Contains(got, []byte("GPS")) || bytes.Contains(got, []byte("Exif")) {
+				t.Error("scrubbed image still has its metadata")
+			}
+			if config, format, err := image.DecodeConfig(bytes.NewReader(got)); tt.contentType != "image/webp" && (err != nil || "image/"+format != tt.contentType || config.Width == 0) {
+				t.Errorf("scrubbed image doesn't decode as %s: %v", tt.contentType, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// BrokerHandler receives every event published through a broker, on every instance. An
+// empty userID addresses every connected user, like the reset a broker sends when it
+// may have missed events.
+type BrokerHandler func(userID string, event Event)
+
+// Broker carries real-time events between API instances. Publishing on one instance
+// delivers the event to the handlers subscribed on all instances, which then hand it
+// to their local connections.
+type Broker interface {
+	Publish(ctx context.Context, userID string, event Event) error
+	Subscribe(handler BrokerHandler)
+	Close() error
+}
+
+// MemoryBroker is a Broker for a single instance that delivers events in-process
+type MemoryBroker struct {
+	mu       sync.RWMutex
+	handlers []BrokerHandler
+}
+
+// NewMemoryBroker creates a new MemoryBroker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+// Publish delivers the event to every subscribed handler
+func (b *MemoryBroker) Publish(ctx context.Context, userID string, event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, handler := range b.handlers {
+		handler(userID, event)
+	}
+	return nil
+}
+
+// Subscribe registers a handler for published events
+func (b *MemoryBroker) Subscribe(handler BrokerHandler) {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+}
+
+// Close releases the broker's handlers
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	b.handlers = nil
+	b.mu.Unlock()
+	return nil
+}
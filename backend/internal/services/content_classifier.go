@@ -0,0 +1,270 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	_ "embed"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/utils"
+)
+
+// Score thresholds for the action taken on a message
+const (
+	safetyWarnScore  = 0.3
+	safetyHoldScore  = 0.6
+	safetyBlockScore = 0.85
+)
+
+// ClassificationInput is an outgoing message with the context classifiers need
+type ClassificationInput struct {
+	SenderID       string
+	MatchID        int64
+	Message        string
+	ContentHash    string // Hash of the normalized text, see contentHash
+	IsFirstMessage bool   // The sender hasn't written in this conversation before
+}
+
+// ContentClassifier scores outgoing messages for harassment, scams and spam
+type ContentClassifier interface {
+	Classify(ctx context.Context, input ClassificationInput) (*models.Classification, error)
+}
+
+// safetyAction maps a classifier score to the action taken on the message
+func safetyAction(score float64) string {
+	switch {
+	case score >= safetyBlockScore:
+		return models.SafetyBlock
+	case score >= safetyHoldScore:
+		return models.SafetyHold
+	case score >= safetyWarnScore:
+		return models.SafetyWarn
+	default:
+		return models.SafetyAllow
+	}
+}
+
+// Copy-paste detection, the same text sent to this many other matches within the window
+const (
+	copyPasteWindow      = 24 * time.Hour
+	copyPasteMinLength   = 20
+	copyPasteWarnMatches = 5
+	copyPasteHoldMatches = 10
+)
+
+// Scores contributed by each rule
+const (
+	blocklistTermScore      = 0.9
+	harassmentScore         = 0.9
+	phoneScore              = 0.4
+	linkScore               = 0.35
+	socialHandleScore       = 0.3
+	offPlatformScore        = 0.45
+	offPlatformMentionScore = 0.25
+	cryptoScore             = 0.45
+	moneyRequestScore       = 0.6
+	copyPasteWarnScore      = 0.4
+	copyPasteHoldScore      = 0.7
+)
+
+// safetyRule is a pattern and the score and label it contributes when it matches
+type safetyRule struct {
+	pattern *regexp.Regexp
+	score   float64
+	label   string
+}
+
+var (
+	// Rules matched against the leetspeak-normalized text
+	harassmentRules = []safetyRule{
+		{regexp.MustCompile(`\b(?:kys|kill (?:yo)?ur ?self|go die|i (?:will|ll|am going to|m gonna) (?:kill|hurt|find) you)\b`), harassmentScore, models.SafetyLabelHarassment},
+		{regexp.MustCompile(`\b(?:ugly|worthless|stupid|fat|dumb) (?:bitch|whore|slut|cunt)\b`), harassmentScore, models.SafetyLabelHarassment},
+	}
+	scamRules = []safetyRule{
+		{regexp.MustCompile(`\b(?:move|talk|chat|text|message|contact|add|reach|hit|continue)\b.{0,30}\b(?:whats ?app|telegram|signal|kik|wechat|viber|hangouts)\b`), offPlatformScore, models.SafetyLabelOffPlatform},
+		{regexp.MustCompile(`\b(?:crypto(?:currency)?|bitcoin|btc|ethereum|usdt|binance|forex|nft|mining pool|trading platform|investment (?:plan|platform|opportunity)|wallet address|passive income)\b`), cryptoScore, models.SafetyLabelScam},
+		{regexp.MustCompile(`\b(?:gift ?cards?|cash ?app|venmo|zelle|western union|moneygram|wire (?:me|transfer)|send (?:me )?(?:some )?money|bank details|paypal me)\b`), moneyRequestScore, models.SafetyLabelMoneyRequest},
+	}
+	offPlatformMention = regexp.MustCompile(`\b(?:whats ?app|telegram|kik|wechat|viber)\b`)
+
+	// Rules matched against the lowercased original text, which keeps digits and symbols
+	contactRules = []safetyRule{
+		{regexp.MustCompile(`(?:\+?\d[\s\-.()]*){7,}`), phoneScore, models.SafetyLabelContactSolicitation},
+		{regexp.MustCompile(`(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|io|me|co|app|ly|gg|link|xyz|info)\b|[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`), linkScore, models.SafetyLabelContactSolicitation},
+		{regexp.MustCompile(`(?:^|\s)@[a-z0-9._]{3,}|\b(?:add me on|my|hit me up on|find me on) (?:snap|snapchat|insta|instagram|ig|tiktok)\b`), socialHandleScore, models.SafetyLabelContactSolicitation},
+	}
+
+	// leetspeak undoes common character swaps used to dodge filters
+	leetspeak = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+)
+
+// defaultBlocklist is the built-in list of terms that always block a message
+//
+//go:embed safety_blocklist.txt
+var defaultBlocklist string
+
+// RulesClassifier is the default ContentClassifier. It combines pattern rules for
+// harassment, contact details in first messages and scam language with a check for the
+// same text being sent to many matches.
+type RulesClassifier struct {
+	BaseService
+	blocklist []safetyRule
+}
+
+// NewRulesClassifier creates a new RulesClassifier. Slurs in the built-in blocklist always
+// block a message, and more terms can be added one per line in SAFETY_BLOCKLIST_FILE.
+func NewRulesClassifier(db *sql.DB) *RulesClassifier {
+	c := &RulesClassifier{BaseService: NewBaseService(db)}
+
+	blocklist, err := parseBlocklist(strings.NewReader(defaultBlocklist))
+	if err != nil {
+		log.Printf("Failed to load default safety blocklist: %v", err)
+	}
+	c.blocklist = blocklist
+
+	if path := utils.NewConfig().GetEnv("SAFETY_BLOCKLIST_FILE", ""); path != "" {
+		extra, err := loadBlocklist(path)
+		if err != nil {
+			log.Printf("Failed to load safety blocklist %s: %v", path, err)
+		}
+		c.blocklist = append(c.blocklist, extra...)
+	}
+
+	return c
+}
+
+// Classify scores a message. Scores from independent rules are combined so that several
+// weak signals add up without any single one reaching 1.
+func (c *RulesClassifier) Classify(ctx context.Context, input ClassificationInput) (*models.Classification, error) {
+	lowered := strings.ToLower(input.Message)
+	normalized := normalizeForSafety(input.Message)
+
+	var scores []float64
+	labels := map[string]bool{}
+	match := func(rules []safetyRule, text string) {
+		for _, rule := range rules {
+			if rule.pattern.MatchString(text) {
+				scores = append(scores, rule.score)
+				labels[rule.label] = true
+			}
+		}
+	}
+
+	match(c.blocklist, normalized)
+	match(harassmentRules, normalized)
+	match(scamRules, normalized)
+	if !labels[models.SafetyLabelOffPlatform] && offPlatformMention.MatchString(normalized) {
+		scores = append(scores, offPlatformMentionScore)
+		labels[models.SafetyLabelOffPlatform] = true
+	}
+
+	// Swapping numbers is normal once people are talking, not as an opener
+	if input.IsFirstMessage {
+		match(contactRules, lowered)
+	}
+
+	// The same long message pasted into many conversations
+	if utf8.RuneCountInString(strings.TrimSpace(input.Message)) >= copyPasteMinLength && input.ContentHash != "" {
+		var matches int
+		err := c.GetDB().QueryRowContext(ctx, `
+			SELECT COUNT(DISTINCT match_id)
+			FROM messages
+			WHERE sender_id = $1 AND content_hash = $2 AND match_id <> $3 AND created_at > $4
+		`, input.SenderID, input.ContentHash, input.MatchID, time.Now().Add(-copyPasteWindow)).Scan(&matches)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case matches >= copyPasteHoldMatches:
+			scores = append(scores, copyPasteHoldScore)
+			labels[models.SafetyLabelCopyPaste] = true
+		case matches >= copyPasteWarnMatches:
+			scores = append(scores, copyPasteWarnScore)
+			labels[models.SafetyLabelCopyPaste] = true
+		}
+	}
+
+	classification := &models.Classification{Score: combineScores(scores)}
+	for label := range labels {
+		classification.Labels = append(classification.Labels, label)
+	}
+
+	return classification, nil
+}
+
+// combineScores treats each score as an independent probability of harm and returns
+// the probability that at least one is right
+func combineScores(scores []float64) float64 {
+	harmless := 1.0
+	for _, score := range scores {
+		harmless *= 1 - score
+	}
+	return 1 - harmless
+}
+
+// normalizeForSafety lowercases text, undoes leetspeak and collapses everything that
+// isn't a letter into single spaces, so "Wh@ts-App" reads as "whats app"
+func normalizeForSafety(text string) string {
+	text = leetspeak.Replace(strings.ToLower(text))
+
+	var b strings.Builder
+	space := true
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+			space = false
+		} else if !space {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// contentHash returns a hash of the normalized message text for copy-paste detection
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(normalizeForSafety(text)))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadBlocklist reads blocklist terms from a file
+func loadBlocklist(path string) ([]safetyRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseBlocklist(f)
+}
+
+// parseBlocklist reads blocklist terms one per line, skipping blank lines and # comments
+func parseBlocklist(r io.Reader) ([]safetyRule, error) {
+	var rules []safetyRule
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		term := normalizeForSafety(scanner.Text())
+		if term == "" || strings.HasPrefix(strings.TrimSpace(scanner.Text()), "#") {
+			continue
+		}
+		rules = append(rules, safetyRule{
+			pattern: regexp.MustCompile(`\b` + regexp.QuoteMeta(term) + `\b`),
+			score:   blocklistTermScore,
+			label:   models.SafetyLabelHarassment,
+		})
+	}
+
+	return rules, scanner.Err()
+}
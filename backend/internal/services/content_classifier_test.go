@@ -0,0 +1,37 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultBlocklist(t *testing.T) {
+	rules, err := parseBlocklist(strings.NewReader(defaultBlocklist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) == 0 {
+		t.Fatal("default blocklist is empty")
+	}
+
+	blocked := func(text string) bool {
+		normalized := normalizeForSafety(text)
+		for _, rule := range rules {
+			if rule.pattern.MatchString(normalized) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, text := range []string{"you're a faggot", "F4GG0T", "what a retard", "shut up, tranny"} {
+		if !blocked(text) {
+			t.Errorf("%q is not blocked", text)
+		}
+	}
+	for _, text := range []string{"hey, how was your weekend?", "the coating is flame retardant", "raccoons in the garden"} {
+		if blocked(text) {
+			t.Errorf("%q is blocked", text)
+		}
+	}
+}
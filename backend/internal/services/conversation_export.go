@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// ErrInvalidExportFormat is returned for an export format other than json or html
+var ErrInvalidExportFormat = errors.New("format must be json or html")
+
+// ExportConversation returns every message in a match, with attachment references and
+// timestamps, for one of its participants. Each export is recorded in the export log.
+func (s *MessageService) ExportConversation(ctx context.Context, userID string, matchID int64, format string) (*models.ConversationExport, error) {
+	if format != models.ExportFormatJSON && format != models.ExportFormatHTML {
+		return nil, ErrInvalidExportFormat
+	}
+
+	db := s.GetDB()
+
+	export := &models.ConversationExport{
+		MatchID:    matchID,
+		ExportedBy: userID,
+		ExportedAt: time.Now(),
+		Format:     format,
+	}
+
+	// Check the user is part of the match
+	var user1ID, user2ID string
+	var blocked bool
+	err := db.QueryRowContext(ctx, `
+		SELECT m.user1_id, m.user2_id, m.created_at, `+blockedSQL("m.user1_id", "m.user2_id")+`
+		FROM matches m
+		WHERE m.id = $1 AND (m.user1_id = $2 OR m.user2_id = $2)
+	`, matchID, userID).Scan(&user1ID, &user2ID, &export.MatchedAt, &blocked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMatchNotFound
+		}
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrUserBlocked
+	}
+
+	// Participants, named from their profiles where they still have one
+	names := map[string]string{}
+	rows, err := db.QueryContext(ctx, `
+		SELECT user_id, name FROM profiles WHERE user_id = ANY($1)
+	`, pq.Array([]string{user1ID, user2ID}))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	export.Participants = []models.ExportParticipant{
+		{UserID: user1ID, Name: names[user1ID]},
+		{UserID: user2ID, Name: names[user2ID]},
+	}
+
+	// Every message, oldest first
+	messageRows, err := db.QueryContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE match_id = $1
+		ORDER BY id ASC
+	`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer messageRows.Close()
+
+	export.Messages = []models.Message{}
+	for messageRows.Next() {
+		var msg models.Message
+		if err := scanMessage(messageRows, &msg); err != nil {
+			return nil, err
+		}
+		export.Messages = append(export.Messages, msg)
+	}
+	if err := messageRows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.attachReactions(ctx, export.Messages); err != nil {
+		return nil, err
+	}
+	if s.attachmentService != nil {
+		if err := s.attachmentService.attachToMessages(ctx, userID, export.Messages); err != nil {
+			return nil, err
+		}
+	}
+
+	// Record the export before handing it over
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO conversation_exports (match_id, user_id, format, message_count, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, matchID, userID, format, len(export.Messages), export.ExportedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// exportTimeLayout is how times are shown in HTML exports
+const exportTimeLayout = "2 Jan 2006 15:04 MST"
+
+// conversationExportTemplate renders a conversation export as a standalone HTML page
+var conversationExportTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string { return t.UTC().Format(exportTimeLayout) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Conversation with {{.Partner}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 720px; margin: 2em auto; padding: 0 1em; color: #222; }
+header { border-bottom: 1px solid #ddd; margin-bottom: 1.5em; }
+.message { margin: 0 0 1em; }
+.meta { color: #777; font-size: 0.85em; }
+.mine .sender { color: #6b3fa0; }
+.unsent { color: #999; font-style: italic; }
+ul.attachments { margin: 0.25em 0; padding-left: 1.25em; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<header>
+<h1>Conversation with {{.Partner}}</h1>
+<p class="meta">Matched {{formatTime .Export.MatchedAt}} &middot; Exported {{formatTime .Export.ExportedAt}} &middot; {{len .Export.Messages}} messages</p>
+</header>
+{{range .Messages}}
+<div class="message{{if .Mine}} mine{{end}}">
+<div class="meta"><span class="sender">{{.Sender}}</span> &middot; {{formatTime .CreatedAt}}{{if .EditedAt}} &middot; edited {{formatTime .EditedAt}}{{end}}</div>
+{{if .Unsent}}<p class="unsent">Message unsent</p>{{else if .Text}}<p>{{.Text}}</p>{{end}}
+{{if .Attachments}}<ul class="attachments">
+{{range .Attachments}}<li><a href="{{.URL}}">{{.Kind}} ({{.ContentType}}, {{.Size}} bytes)</a> &middot; link expires {{formatTime .URLExpiresAt}}</li>
+{{end}}</ul>{{end}}
+{{if .Reactions}}<div class="meta">{{range .Reactions}}{{.Emoji}} {{end}}</div>{{end}}
+</div>
+{{else}}
+<p class="meta">No messages yet.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// exportMessageView is a message as shown in an HTML export
+type exportMessageView struct {
+	Sender      string
+	Mine        bool
+	Text        string
+	Unsent      bool
+	CreatedAt   time.Time
+	EditedAt    *time.Time
+	Attachments []models.Attachment
+	Reactions   []models.Reaction
+}
+
+// RenderConversationHTML writes a conversation export as an HTML page. Message text is
+// escaped by html/template.
+func RenderConversationHTML(w io.Writer, export *models.ConversationExport) error {
+	names := map[string]string{}
+	partner := "your match"
+	for _, participant := range export.Participants {
+		name := participant.Name
+		if name == "" {
+			name = "Deleted user"
+		}
+		names[participant.UserID] = name
+		if participant.UserID != export.ExportedBy && participant.Name != "" {
+			partner = participant.Name
+		}
+	}
+	names[export.ExportedBy] = "You"
+
+	messages := make([]exportMessageView, len(export.Messages))
+	for i, msg := range export.Messages {
+		messages[i] = exportMessageView{
+			Sender:      names[msg.SenderID],
+			Mine:        msg.SenderID == export.ExportedBy,
+			Text:        msg.Message,
+			Unsent:      msg.Deleted,
+			CreatedAt:   msg.CreatedAt,
+			EditedAt:    msg.EditedAt,
+			Attachments: msg.Attachments,
+			Reactions:   msg.Reactions,
+		}
+	}
+
+	return conversationExportTemplate.Execute(w, struct {
+		Export   *models.ConversationExport
+		Partner  string
+		Messages []exportMessageView
+	}{export, partner, messages})
+}
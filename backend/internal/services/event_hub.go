@@ -0,0 +1,166 @@
+package services
+
+import (
+	"log"
+	"sync"
+)
+
+// EventStream identifies which SSE endpoint an event is delivered on
+type EventStream string
+
+const (
+	// MessageStream carries chat events and is served on /events/messages
+	MessageStream EventStream = "messages"
+
+	// NotificationStream carries everything else and is served on /events/notifications
+	NotificationStream EventStream = "notifications"
+)
+
+// defaultEventBufferSize is how many events a connection may fall behind before it is dropped
+const defaultEventBufferSize = 32
+
+// Event is a single server-sent event addressed to one user. ID is the event's
+// position in the user's event log, or zero if it was not persisted.
+type Event struct {
+	ID     int64
+	Stream EventStream
+	Name   string
+	Data   []byte
+}
+
+// Subscription is one client connection registered with the hub
+type Subscription struct {
+	UserID string
+	Stream EventStream
+	events chan Event
+}
+
+// Events returns the queue of events for this connection. It is closed when the
+// subscription is removed from the hub.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// EventHub fans events out to the live connections of each user
+type EventHub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*Subscription]struct{}
+	bufferSize  int
+}
+
+// NewEventHub creates a new EventHub whose connections buffer up to bufferSize events
+func NewEventHub(bufferSize int) *EventHub {
+	if bufferSize <= 0 {
+		bufferSize = defaultEventBufferSize
+	}
+	return &EventHub{
+		subscribers: make(map[string]map[*Subscription]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Subscribe registers a new connection for a user on the given stream
+func (h *EventHub) Subscribe(userID string, stream EventStream) *Subscription {
+	sub := &Subscription{
+		UserID: userID,
+		Stream: stream,
+		events: make(chan Event, h.bufferSize),
+	}
+
+	h.mu.Lock()
+	if _, exists := h.subscribers[userID]; !exists {
+		h.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[userID][sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe removes a connection from the hub and closes its queue. It is safe to
+// call more than once.
+func (h *EventHub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs, exists := h.subscribers[sub.UserID]
+	if !exists {
+		return
+	}
+	if _, exists := subs[sub]; !exists {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.UserID)
+	}
+	close(sub.events)
+}
+
+// Publish queues an event for every connection the user has open on the event's
+// stream and returns how many connections it was queued for. Connections whose
+// queue is full are disconnected so the client can reconnect instead of silently
+// missing events.
+func (h *EventHub) Publish(userID string, event Event) int {
+	var delivered int
+	var slow []*Subscription
+
+	h.mu.RLock()
+	for sub := range h.subscribers[userID] {
+		if sub.Stream != event.Stream {
+			continue
+		}
+		select {
+		case sub.events <- event:
+			delivered++
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		log.Printf("Event queue full for user %s on %s stream, disconnecting", userID, sub.Stream)
+		h.Unsubscribe(sub)
+	}
+
+	return delivered
+}
+
+// Broadcast queues an event for every open connection. An event without a stream goes
+// out on every stream. Connections whose queue is full are disconnected, as in Publish.
+func (h *EventHub) Broadcast(event Event) int {
+	var delivered int
+	var slow []*Subscription
+
+	h.mu.RLock()
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			if event.Stream != "" && sub.Stream != event.Stream {
+				continue
+			}
+			select {
+			case sub.events <- event:
+				delivered++
+			default:
+				slow = append(slow, sub)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		log.Printf("Event queue full for user %s on %s stream, disconnecting", sub.UserID, sub.Stream)
+		h.Unsubscribe(sub)
+	}
+
+	return delivered
+}
+
+// ConnectionCount returns the number of open connections for a user across all streams
+func (h *EventHub) ConnectionCount(userID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[userID])
+}
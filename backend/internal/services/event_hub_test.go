@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receive reads the next event from a subscription, failing the test if none arrives
+func receive(t *testing.T, sub *Subscription) (Event, bool) {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		return event, ok
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+		return Event{}, false
+	}
+}
+
+func TestEventHubBuffersEachConnection(t *testing.T) {
+	hub := NewEventHub(4)
+	phone := hub.Subscribe("user-1", NotificationStream)
+	laptop := hub.Subscribe("user-1", NotificationStream)
+	chat := hub.Subscribe("user-1", MessageStream)
+	other := hub.Subscribe("user-2", NotificationStream)
+
+	for i := int64(1); i <= 4; i++ {
+		if delivered := hub.Publish("user-1", Event{ID: i, Stream: NotificationStream, Name: "match"}); delivered != 2 {
+			t.Fatalf("event %d delivered to %d connections, want 2", i, delivered)
+		}
+	}
+
+	// Each connection has its own queue holding every event, in order
+	for _, sub := range []*Subscription{phone, laptop} {
+		for i := int64(1); i <= 4; i++ {
+			event, ok := receive(t, sub)
+			if !ok || event.ID != i {
+				t.Fatalf("got event %d (open %v), want %d", event.ID, ok, i)
+			}
+		}
+	}
+
+	// Other streams and other users get nothing
+	for _, sub := range []*Subscription{chat, other} {
+		if n := len(sub.Events()); n != 0 {
+			t.Fatalf("%s on %s stream has %d queued events, want 0", sub.UserID, sub.Stream, n)
+		}
+	}
+}
+
+func TestEventHubDropsSlowConnections(t *testing.T) {
+	hub := NewEventHub(2)
+	slow := hub.Subscribe("user-1", NotificationStream)
+	fast := hub.Subscribe("user-1", NotificationStream)
+
+	for i := int64(1); i <= 3; i++ {
+		hub.Publish("user-1", Event{ID: i, Stream: NotificationStream})
+		if event, ok := receive(t, fast); !ok || event.ID != i {
+			t.Fatalf("fast connection got event %d (open %v), want %d", event.ID, ok, i)
+		}
+	}
+
+	// The slow connection keeps what it had queued, then its queue is closed
+	for i := int64(1); i <= 2; i++ {
+		if event, ok := receive(t, slow); !ok || event.ID != i {
+			t.Fatalf("slow connection got event %d (open %v), want %d", event.ID, ok, i)
+		}
+	}
+	if _, ok := receive(t, slow); ok {
+		t.Fatal("slow connection is still open after its queue overflowed")
+	}
+
+	if count := hub.ConnectionCount("user-1"); count != 1 {
+		t.Fatalf("user has %d connections, want 1", count)
+	}
+	if delivered := hub.Publish("user-1", Event{ID: 4, Stream: NotificationStream}); delivered != 1 {
+		t.Fatalf("event delivered to %d connections, want 1", delivered)
+	}
+}
+
+func TestEventHubUnsubscribe(t *testing.T) {
+	hub := NewEventHub(4)
+	sub := hub.Subscribe("user-1", MessageStream)
+
+	hub.Unsubscribe(sub)
+	hub.Unsubscribe(sub) // Safe to call again
+
+	if _, ok := receive(t, sub); ok {
+		t.Fatal("queue is still open after unsubscribing")
+	}
+	if count := hub.ConnectionCount("user-1"); count != 0 {
+		t.Fatalf("user has %d connections, want 0", count)
+	}
+	if delivered := hub.Publish("user-1", Event{Stream: MessageStream}); delivered != 0 {
+		t.Fatalf("event delivered to %d connections, want 0", delivered)
+	}
+}
+
+func TestEventHubBroadcast(t *testing.T) {
+	hub := NewEventHub(4)
+	subs := []*Subscription{
+		hub.Subscribe("user-1", NotificationStream),
+		hub.Subscribe("user-1", MessageStream),
+		hub.Subscribe("user-2", MessageStream),
+	}
+
+	if delivered := hub.Broadcast(Event{Name: "reset"}); delivered != len(subs) {
+		t.Fatalf("reset delivered to %d connections, want %d", delivered, len(subs))
+	}
+	for _, sub := range subs {
+		if event, ok := receive(t, sub); !ok || event.Name != "reset" {
+			t.Fatalf("%s on %s stream got %q (open %v), want reset", sub.UserID, sub.Stream, event.Name, ok)
+		}
+	}
+
+	// Events with a stream only go out on that stream
+	if delivered := hub.Broadcast(Event{Stream: MessageStream, Name: "reset"}); delivered != 2 {
+		t.Fatalf("message stream reset delivered to %d connections, want 2", delivered)
+	}
+}
+
+func TestStreamEventsSendsHeartbeatsAndUnregisters(t *testing.T) {
+	s := &NotificationService{
+		hub:               NewEventHub(4),
+		heartbeatInterval: 20 * time.Millisecond,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.StreamEvents(w, r, "user-1", NotificationStream); err != nil {
+			t.Errorf("StreamEvents: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Read events until two heartbeats arrive, checking a published event gets through
+	events := make(chan string)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+				events <- name
+			}
+		}
+	}()
+
+	published := false
+	heartbeats := 0
+	for heartbeats < 2 {
+		select {
+		case name, ok := <-events:
+			if !ok {
+				t.Fatal("stream closed early")
+			}
+			switch name {
+			case "connected":
+				if count := s.hub.ConnectionCount("user-1"); count != 1 {
+					t.Fatalf("user has %d connections while streaming, want 1", count)
+				}
+				s.hub.Publish("user-1", Event{ID: 1, Stream: NotificationStream, Name: "match", Data: []byte("{}")})
+			case "match":
+				published = true
+			case "heartbeat":
+				heartbeats++
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after %d heartbeats", heartbeats)
+		}
+	}
+	if !published {
+		t.Fatal("published event was not streamed before the heartbeats")
+	}
+
+	// Disconnecting removes the connection from the hub
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.hub.ConnectionCount("user-1") != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("connection still registered after the client disconnected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
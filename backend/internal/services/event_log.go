@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// defaultEventLogRetention is how long events stay available for replay
+const defaultEventLogRetention = 24 * time.Hour
+
+// maxReplayEvents caps how many missed events are replayed on a single reconnect
+const maxReplayEvents = 500
+
+// appendEventLog persists an event for replay and returns its id
+func (s *NotificationService) appendEventLog(ctx context.Context, userID string, stream EventStream, name string, data []byte) (int64, error) {
+	var id int64
+	err := s.GetDB().QueryRowContext(
+		ctx,
+		`INSERT INTO user_events (user_id, stream, name, data, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		userID, stream, name, data, time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// getEventsSince returns the user's events on a stream with an id greater than lastEventID.
+// complete is false when the log can no longer reproduce everything the client missed,
+// either because lastEventID has aged out of the retention window or because there are
+// more than maxReplayEvents to send.
+func (s *NotificationService) getEventsSince(ctx context.Context, userID string, stream EventStream, lastEventID int64) ([]Event, bool, error) {
+	// The last event the client saw must still be in the log, otherwise there is a gap
+	var known bool
+	err := s.GetDB().QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM user_events WHERE id = $1 AND user_id = $2)",
+		lastEventID, userID,
+	).Scan(&known)
+	if err != nil {
+		return nil, false, err
+	}
+	if !known {
+		return nil, false, nil
+	}
+
+	rows, err := s.GetDB().QueryContext(
+		ctx,
+		`SELECT id, name, data
+		FROM user_events
+		WHERE user_id = $1 AND stream = $2 AND id > $3
+		ORDER BY id ASC
+		LIMIT $4`,
+		userID, stream, lastEventID, maxReplayEvents+1,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		event := Event{Stream: stream}
+		if err := rows.Scan(&event.ID, &event.Name, &event.Data); err != nil {
+			return nil, false, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if len(events) > maxReplayEvents {
+		return nil, false, nil
+	}
+
+	return events, true, nil
+}
+
+// PruneEventLog deletes events older than the retention window
+func (s *NotificationService) PruneEventLog(ctx context.Context) (int64, error) {
+	result, err := s.GetDB().ExecContext(
+		ctx,
+		"DELETE FROM user_events WHERE created_at < $1",
+		time.Now().Add(-s.eventLogRetention),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// RunEventLogCleanup prunes the event log every interval until ctx is cancelled
+func (s *NotificationService) RunEventLogCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.PruneEventLog(ctx)
+			if err != nil {
+				log.Printf("Failed to prune event log: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Pruned %d events from the event log", deleted)
+			}
+		}
+	}
+}
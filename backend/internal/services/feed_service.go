@@ -37,7 +37,8 @@ func (s *FeedService) GetFeed(ctx context.Context, userID string, limit int, off
 		SELECT p.id
 		FROM profiles p
 		LEFT JOIN swipes s ON p.id = s.profile_id AND s.user_id = $1
-		WHERE s.id IS NULL AND p.user_id != $1
+		WHERE s.id IS NULL AND p.user_id != $1 AND p.onboarding_completed
+		  AND NOT ` + blockedSQL("$1", "p.user_id") + `
 	`
 	args := []interface{}{userID}
 	argCount := 1
@@ -62,6 +63,12 @@ func (s *FeedService) GetFeed(ctx context.Context, userID string, limit int, off
 		args = append(args, preferences.MaxAge)
 	}
 
+	// Apply attribute filters
+	if preferences != nil {
+		query, args = appendAttributeFilters(query, args, preferences.AttributeFilters)
+		argCount = len(args)
+	}
+
 	// Limit and offset
 	query += ` ORDER BY RANDOM() LIMIT $` + strconv.Itoa(argCount+1) + ` OFFSET $` + strconv.Itoa(argCount+2)
 	args = append(args, limit, offset)
@@ -88,11 +95,15 @@ func (s *FeedService) GetFeed(ctx context.Context, userID string, limit int, off
 	}
 
 	// Get full profiles
+	loaded, err := s.profileService.GetProfilesByIDs(ctx, profileIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	var profiles []map[string]interface{}
 	for _, profileID := range profileIDs {
-		profile, err := s.profileService.GetProfileByID(ctx, profileID)
-		if err != nil {
-			log.Printf("Failed to get profile %s: %v", profileID, err)
+		profile, ok := loaded[profileID]
+		if !ok {
 			continue
 		}
 
@@ -107,6 +118,7 @@ func (s *FeedService) GetFeed(ctx context.Context, userID string, limit int, off
 			"occupation":  profile.Occupation,
 			"photos":      profile.Photos,
 			"prompts":     profile.Prompts,
+			"attributes":  profile.Attributes,
 		}
 
 		profiles = append(profiles, profileMap)
@@ -152,7 +164,9 @@ func (s *FeedService) GetStandouts(ctx context.Context, userID string, limit int
 		ctx,
 		`SELECT s.profile_id 
 		FROM standouts s
+		JOIN profiles p ON p.id = s.profile_id AND p.onboarding_completed
 		WHERE s.user_id = $1 AND s.is_active = true
+		  AND NOT `+blockedSQL("$1", "p.user_id")+`
 		ORDER BY s.created_at DESC
 		LIMIT $2`,
 		userID, limit,
@@ -177,11 +191,15 @@ func (s *FeedService) GetStandouts(ctx context.Context, userID string, limit int
 	}
 
 	// Get full profiles
+	loaded, err := s.profileService.GetProfilesByIDs(ctx, profileIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	var standouts []map[string]interface{}
 	for _, profileID := range profileIDs {
-		profile, err := s.profileService.GetProfileByID(ctx, profileID)
-		if err != nil {
-			log.Printf("Failed to get profile %s: %v", profileID, err)
+		profile, ok := loaded[profileID]
+		if !ok {
 			continue
 		}
 
@@ -196,6 +214,7 @@ func (s *FeedService) GetStandouts(ctx context.Context, userID string, limit int
 			"occupation":  profile.Occupation,
 			"photos":      profile.Photos,
 			"prompts":     profile.Prompts,
+			"attributes":  profile.Attributes,
 			"standout_reason": "Popular profile", // Placeholder, would be determined by algorithm
 		}
 
@@ -221,7 +240,8 @@ func (s *FeedService) generateStandouts(ctx context.Context, userID string, coun
 		FROM profiles p
 		LEFT JOIN standouts s ON p.id = s.profile_id AND s.user_id = $1
 		LEFT JOIN swipes sw ON p.id = sw.profile_id AND sw.user_id = $1
-		WHERE s.id IS NULL AND sw.id IS NULL AND p.user_id != $1
+		WHERE s.id IS NULL AND sw.id IS NULL AND p.user_id != $1 AND p.onboarding_completed
+		  AND NOT ` + blockedSQL("$1", "p.user_id") + `
 	`
 	args := []interface{}{userID}
 	argCount := 1
@@ -246,6 +266,12 @@ func (s *FeedService) generateStandouts(ctx context.Context, userID string, coun
 		args = append(args, preferences.MaxAge)
 	}
 
+	// Apply attribute filters
+	if preferences != nil {
+		query, args = appendAttributeFilters(query, args, preferences.AttributeFilters)
+		argCount = len(args)
+	}
+
 	// Order by random and limit
 	query += ` ORDER BY RANDOM() LIMIT $` + strconv.Itoa(argCount+1)
 	args = append(args, count)
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// maxIcebreakerQuoteLength caps how much of an answer or comment is quoted in an opener
+const maxIcebreakerQuoteLength = 60
+
+// IcebreakerContext is what an IcebreakerEngine knows about a match when writing openers
+type IcebreakerContext struct {
+	MatchID         int64
+	UserID          string // The user the openers are for
+	PartnerName     string
+	PartnerPrompts  []models.ProfilePrompt
+	SharedInterests []string // Vices and interests set on both profiles
+	PartnerComment  string   // The comment the partner left when liking the user
+	UserComment     string   // The comment the user left when liking the partner
+	Limit           int
+}
+
+// IcebreakerEngine writes suggested openers for a match
+type IcebreakerEngine interface {
+	Generate(ctx context.Context, input IcebreakerContext) ([]models.Icebreaker, error)
+}
+
+// icebreakerTemplate is one way of writing an opener from a source
+type icebreakerTemplate struct {
+	key    string
+	source string
+	text   string
+}
+
+// defaultIcebreakerTemplates are the openers used by TemplateIcebreakerEngine. Keys are
+// recorded with each suggestion, so rename a template rather than changing its meaning.
+var defaultIcebreakerTemplates = []icebreakerTemplate{
+	{"prompt.story", models.IcebreakerSourcePrompt, `Your answer to "{{.Prompt}}" caught my eye. What's the story behind "{{quote .Answer}}"?`},
+	{"prompt.follow_up", models.IcebreakerSourcePrompt, `"{{quote .Answer}}"? Okay {{.Partner}}, I have follow-up questions.`},
+	{"prompt.tell_more", models.IcebreakerSourcePrompt, `I saw "{{quote .Answer}}" on your profile and need to know more.`},
+	{"interest.shared", models.IcebreakerSourceInterest, `Looks like we're both into {{.Interest}}. How did you get into it?`},
+	{"interest.compare", models.IcebreakerSourceInterest, `We both have {{.Interest}} on our profiles, so I have to ask: how seriously do you take it?`},
+	{"comment.reply", models.IcebreakerSourceLikeComment, `You said "{{quote .Comment}}" when you liked my profile, and I've been thinking of a reply ever since.`},
+	{"comment.own", models.IcebreakerSourceLikeComment, `So, did my "{{quote .Comment}}" comment win you over?`},
+	{"generic.highlight", models.IcebreakerSourceGeneric, `Hey {{.Partner}}! What's been the best part of your week?`},
+	{"generic.anywhere", models.IcebreakerSourceGeneric, `Hi {{.Partner}}, if you could be anywhere right now, where would it be?`},
+	{"generic.two_truths", models.IcebreakerSourceGeneric, `Hey {{.Partner}}, two truths and a lie. You go first?`},
+}
+
+// icebreakerData is passed to icebreaker templates
+type icebreakerData struct {
+	Partner  string
+	Prompt   string
+	Answer   string
+	Interest string
+	Comment  string
+}
+
+// TemplateIcebreakerEngine is the default IcebreakerEngine. It fills text templates from
+// the partner's prompts, shared interests and like comments and needs no external API.
+type TemplateIcebreakerEngine struct {
+	templates *template.Template
+	bySource  map[string][]string // Template keys for each source, in definition order
+}
+
+// NewTemplateIcebreakerEngine creates an engine using the default templates
+func NewTemplateIcebreakerEngine() *TemplateIcebreakerEngine {
+	e := &TemplateIcebreakerEngine{
+		templates: template.New("icebreakers").Funcs(template.FuncMap{"quote": quoteForIcebreaker}),
+		bySource:  map[string][]string{},
+	}
+	for _, t := range defaultIcebreakerTemplates {
+		template.Must(e.templates.New(t.key).Parse(t.text))
+		e.bySource[t.source] = append(e.bySource[t.source], t.key)
+	}
+	return e
+}
+
+// Generate writes up to input.Limit openers, the most personal first: prompt answers,
+// the like comments, shared interests and then generic openers to fill the rest. The
+// templates used are picked from the match and user so repeat requests are stable.
+func (e *TemplateIcebreakerEngine) Generate(ctx context.Context, input IcebreakerContext) ([]models.Icebreaker, error) {
+	partner := firstName(input.PartnerName)
+	icebreakers := []models.Icebreaker{}
+	seen := map[string]bool{}
+
+	// pick chooses a source's template for the nth opener from it, rotating through them
+	// so several openers from the same source don't read alike
+	pick := func(source string, n int) string {
+		keys := e.bySource[source]
+		return keys[(pickIndex(input.MatchID, input.UserID, source, len(keys))+n)%len(keys)]
+	}
+
+	add := func(source string, key string, data icebreakerData, promptID *int64) error {
+		if len(icebreakers) >= input.Limit {
+			return nil
+		}
+
+		data.Partner = partner
+		var b strings.Builder
+		if err := e.templates.ExecuteTemplate(&b, key, data); err != nil {
+			return err
+		}
+		text := strings.TrimSpace(b.String())
+		if text == "" || seen[text] {
+			return nil
+		}
+		seen[text] = true
+
+		icebreakers = append(icebreakers, models.Icebreaker{
+			MatchID:     input.MatchID,
+			Text:        text,
+			Source:      source,
+			TemplateKey: key,
+			PromptID:    promptID,
+		})
+		return nil
+	}
+
+	for i, prompt := range input.PartnerPrompts {
+		if strings.TrimSpace(prompt.Answer) == "" {
+			continue
+		}
+		promptID := prompt.PromptID
+		data := icebreakerData{Prompt: strings.TrimSpace(prompt.Text), Answer: prompt.Answer}
+		if err := add(models.IcebreakerSourcePrompt, pick(models.IcebreakerSourcePrompt, i), data, &promptID); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.PartnerComment != "" {
+		if err := add(models.IcebreakerSourceLikeComment, "comment.reply", icebreakerData{Comment: input.PartnerComment}, nil); err != nil {
+			return nil, err
+		}
+	}
+	if input.UserComment != "" {
+		if err := add(models.IcebreakerSourceLikeComment, "comment.own", icebreakerData{Comment: input.UserComment}, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, interest := range input.SharedInterests {
+		if err := add(models.IcebreakerSourceInterest, pick(models.IcebreakerSourceInterest, i), icebreakerData{Interest: interest}, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// Generic openers are all tried in turn so there are always some to fill with
+	for _, key := range e.bySource[models.IcebreakerSourceGeneric] {
+		if err := add(models.IcebreakerSourceGeneric, key, icebreakerData{}, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return icebreakers, nil
+}
+
+// pickIndex deterministically picks one of n templates for a user, match and seed, so
+// repeat requests suggest the same openers
+func pickIndex(matchID int64, userID string, seed string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	h.Write([]byte{0})
+	h.Write([]byte(seed))
+	return int((h.Sum32() + uint32(matchID)) % uint32(n))
+}
+
+// quoteForIcebreaker shortens text to fit in an opener, cutting at a word boundary
+func quoteForIcebreaker(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.TrimRight(text, ".!?,;: ")
+	if utf8.RuneCountInString(text) <= maxIcebreakerQuoteLength {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := string(runes[:maxIcebreakerQuoteLength])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, ".!?,;: ") + "…"
+}
+
+// firstName returns the first word of a display name
+func firstName(name string) string {
+	if fields := strings.Fields(name); len(fields) > 0 {
+		return fields[0]
+	}
+	return "there"
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// Icebreakers returned per request
+const (
+	defaultIcebreakerLimit = 5
+	maxIcebreakerLimit     = 10
+)
+
+// ErrMatchNotFound is returned when a match doesn't exist or the user isn't part of it
+var ErrMatchNotFound = errors.New("match not found or user not part of match")
+
+// IcebreakerService suggests first messages for a match and tracks which get replies
+type IcebreakerService struct {
+	BaseService
+	profileService *ProfileService
+	engine         IcebreakerEngine
+}
+
+// NewIcebreakerService creates a new icebreaker service using the template engine
+func NewIcebreakerService(db *sql.DB) *IcebreakerService {
+	return &IcebreakerService{
+		BaseService:    NewBaseService(db),
+		profileService: NewProfileService(db),
+		engine:         NewTemplateIcebreakerEngine(),
+	}
+}
+
+// SetIcebreakerEngine replaces the engine used to write openers
+func (s *IcebreakerService) SetIcebreakerEngine(engine IcebreakerEngine) {
+	s.engine = engine
+}
+
+// GetIcebreakers suggests openers for a match from the partner's prompts, interests both
+// users share and the like comments that led to the match. Every suggestion is recorded
+// so sends and replies can be attributed to the template that wrote it.
+func (s *IcebreakerService) GetIcebreakers(ctx context.Context, userID string, matchID int64, limit int) ([]models.Icebreaker, error) {
+	if limit <= 0 {
+		limit = defaultIcebreakerLimit
+	}
+	if limit > maxIcebreakerLimit {
+		limit = maxIcebreakerLimit
+	}
+
+	db := s.GetDB()
+
+	// Check the user is part of the match and find the partner
+	var partnerID string
+	var blocked bool
+	err := db.QueryRowContext(ctx, `
+		SELECT CASE WHEN m.user1_id = $1 THEN m.user2_id ELSE m.user1_id END,
+		       `+blockedSQL("m.user1_id", "m.user2_id")+`
+		FROM matches m
+		WHERE m.id = $2 AND (m.user1_id = $1 OR m.user2_id = $1)
+	`, userID, matchID).Scan(&partnerID, &blocked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMatchNotFound
+		}
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrUserBlocked
+	}
+
+	partner, err := s.profileService.GetProfileByUserID(ctx, partnerID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.profileService.GetProfileByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	input := IcebreakerContext{
+		MatchID:         matchID,
+		UserID:          userID,
+		PartnerName:     partner.Name,
+		PartnerPrompts:  partner.Prompts,
+		SharedInterests: sharedInterests(profile.Vices, partner.Vices),
+		Limit:           limit,
+	}
+
+	// The comments each of them left on the other's like
+	err = db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE((SELECT message FROM swipes WHERE user_id = $1 AND profile_id = $4 AND is_like = true), ''),
+			COALESCE((SELECT message FROM swipes WHERE user_id = $2 AND profile_id = $3 AND is_like = true), '')
+	`, partnerID, userID, partner.ID, profile.ID).Scan(&input.PartnerComment, &input.UserComment)
+	if err != nil {
+		return nil, err
+	}
+
+	icebreakers, err := s.engine.Generate(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(icebreakers) > limit {
+		icebreakers = icebreakers[:limit]
+	}
+
+	if err := s.recordSuggestions(ctx, userID, matchID, icebreakers); err != nil {
+		return nil, err
+	}
+
+	return icebreakers, nil
+}
+
+// recordSuggestions stores the suggestions shown to a user and fills in their ids. A
+// suggestion shown again keeps its id and counts another showing.
+func (s *IcebreakerService) recordSuggestions(ctx context.Context, userID string, matchID int64, icebreakers []models.Icebreaker) error {
+	tx, err := s.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for i := range icebreakers {
+		icebreaker := &icebreakers[i]
+		icebreaker.MatchID = matchID
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO icebreaker_suggestions (match_id, user_id, source, template_key, prompt_id, text, created_at, last_shown_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+			ON CONFLICT (match_id, user_id, text) DO UPDATE
+			SET times_shown = icebreaker_suggestions.times_shown + 1, last_shown_at = $7
+			RETURNING id, created_at
+		`, matchID, userID, icebreaker.Source, icebreaker.TemplateKey, icebreaker.PromptID, icebreaker.Text, now).Scan(&icebreaker.ID, &icebreaker.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// trackIcebreakerMessage records a sent message against the icebreaker it came from,
+// matched by id or by its exact text, and marks icebreakers the partner sent as replied to
+func trackIcebreakerMessage(ctx context.Context, tx *sql.Tx, userID string, matchID int64, messageID int64, icebreakerID int64, text string, now time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE icebreaker_suggestions
+		SET message_id = $3, used_at = $4
+		WHERE match_id = $1 AND user_id = $2 AND used_at IS NULL AND (id = $5 OR text = $6)
+	`, matchID, userID, messageID, now, icebreakerID, text)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE icebreaker_suggestions
+		SET replied_at = $3
+		WHERE match_id = $1 AND user_id <> $2 AND used_at IS NOT NULL AND replied_at IS NULL
+	`, matchID, userID, now)
+	return err
+}
+
+// sharedInterests returns the vices and interests set on both profiles, readable and sorted
+func sharedInterests(a, b map[string]bool) []string {
+	var shared []string
+	for key, set := range a {
+		if set && b[key] {
+			shared = append(shared, strings.ReplaceAll(key, "_", " "))
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
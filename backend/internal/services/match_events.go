@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// matchPayload is the payload of match.created and match.expired events
+type matchPayload struct {
+	MatchID int64  `json:"match_id"`
+	User1ID string `json:"user1_id"`
+	User2ID string `json:"user2_id"`
+}
+
+// turnReminderPayload is the payload of match.turn_reminder events
+type turnReminderPayload struct {
+	MatchID   int64  `json:"match_id"`
+	UserID    string `json:"user_id"`    // Who is being nudged
+	PartnerID string `json:"partner_id"` // Who sent the unanswered message
+}
+
+// outboxDedupeKey identifies the notification an event sends to a user, so a redelivered
+// event doesn't notify them twice
+func outboxDedupeKey(event OutboxEvent, userID string) string {
+	return fmt.Sprintf("%s:%d:%s", event.Topic, event.ID, userID)
+}
+
+// handleMatchCreated tells both users about a new match
+func (s *MatchingService) handleMatchCreated(ctx context.Context, event OutboxEvent) error {
+	if s.notificationService == nil {
+		return nil
+	}
+
+	var payload matchPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		log.Printf("Dropping malformed %s event %d: %v", event.Topic, event.ID, err)
+		return nil
+	}
+
+	profiles, err := s.profileService.GetProfilesByUserIDs(ctx, []string{payload.User1ID, payload.User2ID})
+	if err != nil {
+		return err
+	}
+	profile1, profile2 := profiles[payload.User1ID], profiles[payload.User2ID]
+	if profile1 == nil || profile2 == nil {
+		// One of them has deleted their profile since
+		return nil
+	}
+
+	err = s.notificationService.SendNotification(ctx, payload.User1ID, "match", map[string]interface{}{
+		"match_id":   payload.MatchID,
+		"profile_id": profile2.ID,
+		"message":    "You matched with " + profile2.Name,
+		"dedupe_key": outboxDedupeKey(event, payload.User1ID),
+	})
+	if err != nil {
+		return err
+	}
+	return s.notificationService.SendNotification(ctx, payload.User2ID, "match", map[string]interface{}{
+		"match_id":   payload.MatchID,
+		"profile_id": profile1.ID,
+		"message":    "You matched with " + profile1.Name,
+		"dedupe_key": outboxDedupeKey(event, payload.User2ID),
+	})
+}
+
+// handleTurnReminder nudges a user that it's their turn to reply
+func (s *MatchingService) handleTurnReminder(ctx context.Context, event OutboxEvent) error {
+	if s.notificationService == nil {
+		return nil
+	}
+
+	var payload turnReminderPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		log.Printf("Dropping malformed %s event %d: %v", event.Topic, event.ID, err)
+		return nil
+	}
+
+	message := "It's your turn to reply"
+	if partner, err := s.profileService.GetProfileByUserID(ctx, payload.PartnerID); err == nil {
+		message = "It's your turn to reply to " + partner.Name
+	}
+	return s.notificationService.SendNotification(ctx, payload.UserID, NotificationYourTurn, map[string]interface{}{
+		"target_id":  payload.MatchID,
+		"match_id":   payload.MatchID,
+		"message":    message,
+		"dedupe_key": outboxDedupeKey(event, payload.UserID),
+	})
+}
+
+// handleMatchExpired tells both users their match has expired
+func (s *MatchingService) handleMatchExpired(ctx context.Context, event OutboxEvent) error {
+	if s.notificationService == nil {
+		return nil
+	}
+
+	var payload matchPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		log.Printf("Dropping malformed %s event %d: %v", event.Topic, event.ID, err)
+		return nil
+	}
+
+	if err := s.notifyMatchExpired(ctx, event, payload.MatchID, payload.User1ID, payload.User2ID); err != nil {
+		return err
+	}
+	return s.notifyMatchExpired(ctx, event, payload.MatchID, payload.User2ID, payload.User1ID)
+}
+
+// notifyMatchExpired tells a user their match with the partner has expired
+func (s *MatchingService) notifyMatchExpired(ctx context.Context, event OutboxEvent, matchID int64, userID string, partnerID string) error {
+	message := "Your match has expired"
+	if partner, err := s.profileService.GetProfileByUserID(ctx, partnerID); err == nil {
+		message = "Your match with " + partner.Name + " has expired"
+	}
+	return s.notificationService.SendNotification(ctx, userID, NotificationMatchExpired, map[string]interface{}{
+		"target_id":  matchID,
+		"match_id":   matchID,
+		"message":    message,
+		"dedupe_key": outboxDedupeKey(event, userID),
+	})
+}
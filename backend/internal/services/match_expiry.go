@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// defaultTurnReminderAfter is how long a message can go unanswered before its recipient
+// is nudged that it's their turn
+const defaultTurnReminderAfter = 72 * time.Hour
+
+// matchJobBatchSize is how many matches each reminder or expiry query claims at once
+const matchJobBatchSize = 100
+
+// Notification types sent by the match jobs
+const (
+	NotificationYourTurn     = "your_turn"
+	NotificationMatchExpired = "match_expired"
+)
+
+// ErrMatchExpired is returned when sending a message in a match that has expired
+var ErrMatchExpired = errors.New("this match has expired")
+
+// expiresAt returns when a match will expire if nobody sends another message, or nil if
+// matches don't expire
+func (s *MatchingService) expiresAt(lastMessageAt time.Time) *time.Time {
+	if s.matchExpiry <= 0 {
+		return nil
+	}
+	expiresAt := lastMessageAt.Add(s.matchExpiry)
+	return &expiresAt
+}
+
+// SendTurnReminders nudges users who haven't answered their match's last message within
+// the reminder period. Each unanswered message is nudged about at most once, the
+// reminder is queued in the outbox with the claim. Returns the number of reminders queued.
+func (s *MatchingService) SendTurnReminders(ctx context.Context) (int, error) {
+	if s.turnReminderAfter <= 0 || s.outbox == nil {
+		return 0, nil
+	}
+
+	sent := 0
+	for {
+		tx, err := s.GetDB().BeginTx(ctx, nil)
+		if err != nil {
+			return sent, err
+		}
+
+		now := time.Now()
+		rows, err := tx.QueryContext(ctx, `
+			UPDATE matches m
+			SET turn_reminder_sent_at = $1
+			FROM (
+				SELECT candidate.id, last.sender_id
+				FROM matches candidate
+				CROSS JOIN LATERAL (
+					SELECT sender_id, created_at FROM messages
+					WHERE match_id = candidate.id
+					ORDER BY id DESC
+					LIMIT 1
+				) last
+				WHERE candidate.expired_at IS NULL
+				  AND candidate.last_message_at < $2
+				  AND last.created_at < $2
+				  AND (candidate.turn_reminder_sent_at IS NULL OR candidate.turn_reminder_sent_at < last.created_at)
+				LIMIT $3
+				FOR UPDATE OF candidate SKIP LOCKED
+			) due
+			WHERE m.id = due.id
+			RETURNING m.id, CASE WHEN m.user1_id = due.sender_id THEN m.user2_id ELSE m.user1_id END, due.sender_id
+		`, now, now.Add(-s.turnReminderAfter), matchJobBatchSize)
+		if err != nil {
+			tx.Rollback()
+			return sent, err
+		}
+
+		var reminders []turnReminderPayload
+		for rows.Next() {
+			var r turnReminderPayload
+			if err := rows.Scan(&r.MatchID, &r.UserID, &r.PartnerID); err != nil {
+				rows.Close()
+				tx.Rollback()
+				return sent, err
+			}
+			reminders = append(reminders, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			tx.Rollback()
+			return sent, err
+		}
+
+		for _, r := range reminders {
+			if err := enqueueOutbox(ctx, tx, outboxTopicMatchTurnReminder, r); err != nil {
+				tx.Rollback()
+				return sent, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return sent, err
+		}
+		sent += len(reminders)
+		if len(reminders) > 0 {
+			s.outbox.Wake()
+		}
+
+		if len(reminders) < matchJobBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// ExpireMatches expires matches without a message within the expiry period and queues
+// notifications for both users in the outbox. Does nothing unless MATCH_EXPIRY is set.
+// Returns the number of matches expired.
+func (s *MatchingService) ExpireMatches(ctx context.Context) (int, error) {
+	if s.matchExpiry <= 0 {
+		return 0, nil
+	}
+
+	expired := 0
+	for {
+		tx, err := s.GetDB().BeginTx(ctx, nil)
+		if err != nil {
+			return expired, err
+		}
+
+		now := time.Now()
+		rows, err := tx.QueryContext(ctx, `
+			UPDATE matches
+			SET expired_at = $1
+			WHERE id IN (
+				SELECT id FROM matches
+				WHERE expired_at IS NULL AND last_message_at < $2
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, user1_id, user2_id
+		`, now, now.Add(-s.matchExpiry), matchJobBatchSize)
+		if err != nil {
+			tx.Rollback()
+			return expired, err
+		}
+
+		var matches []matchPayload
+		for rows.Next() {
+			var match matchPayload
+			if err := rows.Scan(&match.MatchID, &match.User1ID, &match.User2ID); err != nil {
+				rows.Close()
+				tx.Rollback()
+				return expired, err
+			}
+			matches = append(matches, match)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			tx.Rollback()
+			return expired, err
+		}
+
+		if s.outbox != nil {
+			for _, match := range matches {
+				if err := enqueueOutbox(ctx, tx, outboxTopicMatchExpired, match); err != nil {
+					tx.Rollback()
+					return expired, err
+				}
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return expired, err
+		}
+		expired += len(matches)
+		if s.outbox != nil && len(matches) > 0 {
+			s.outbox.Wake()
+		}
+
+		if len(matches) < matchJobBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// RunMatchJobs sends "your turn" reminders and expires stale matches every interval
+// until ctx is cancelled
+func (s *MatchingService) RunMatchJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reminded, err := s.SendTurnReminders(ctx)
+			if err != nil {
+				log.Printf("Failed to send your turn reminders: %v", err)
+			}
+			if reminded > 0 {
+				log.Printf("Sent %d your turn reminders", reminded)
+			}
+
+			expired, err := s.ExpireMatches(ctx)
+			if err != nil {
+				log.Printf("Failed to expire matches: %v", err)
+			}
+			if expired > 0 {
+				log.Printf("Expired %d matches", expired)
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// Notification types sent when a user is liked
+const (
+	NotificationNewLike = "new_like"
+	NotificationNewRose = "new_rose"
+)
+
+// defaultLikeNotificationCooldown is how long a liker has to wait before liking the same
+// user again notifies them again, so toggling a like doesn't spam them
+const defaultLikeNotificationCooldown = 24 * time.Hour
+
+// maxLikesInbox caps how many likes the likes inbox lists
+const maxLikesInbox = 100
+
+// swipeLikedPayload is the payload of swipe.liked events
+type swipeLikedPayload struct {
+	SwipeID int64  `json:"swipe_id"`
+	LikerID string `json:"liker_id"`
+	LikeeID string `json:"likee_id"`
+	IsRose  bool   `json:"is_rose"`
+}
+
+// queueLikeNotification queues a like or rose notification for the liked user in tx,
+// unless the liker already notified them of one within the cooldown. A rose after a like
+// still notifies. Reports whether a notification was queued.
+func (s *MatchingService) queueLikeNotification(ctx context.Context, tx *sql.Tx, swipeID int64, likerID string, likeeID string, isRose bool) (bool, error) {
+	if s.outbox == nil {
+		return false, nil
+	}
+
+	notificationType := NotificationNewLike
+	if isRose {
+		notificationType = NotificationNewRose
+	}
+
+	now := time.Now()
+	var claimed bool
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO like_notifications (liker_id, likee_id, type, notified_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (liker_id, likee_id, type) DO UPDATE
+		SET notified_at = $4
+		WHERE like_notifications.notified_at < $5
+		RETURNING true
+	`, likerID, likeeID, notificationType, now, now.Add(-s.likeCooldown)).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = enqueueOutbox(ctx, tx, outboxTopicSwipeLiked, swipeLikedPayload{
+		SwipeID: swipeID,
+		LikerID: likerID,
+		LikeeID: likeeID,
+		IsRose:  isRose,
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// handleSwipeLiked tells a user they've been liked or sent a rose. Premium members see
+// who it was, everyone else a redacted preview. Likes taken back or turned into a match
+// before delivery aren't notified.
+func (s *MatchingService) handleSwipeLiked(ctx context.Context, event OutboxEvent) error {
+	if s.notificationService == nil {
+		return nil
+	}
+
+	var payload swipeLikedPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		log.Printf("Dropping malformed %s event %d: %v", event.Topic, event.ID, err)
+		return nil
+	}
+
+	var isLike, matched bool
+	var comment sql.NullString
+	err := s.GetDB().QueryRowContext(ctx, `
+		SELECT s.is_like, s.message,
+		       EXISTS (
+		           SELECT 1 FROM matches
+		           WHERE (user1_id = $2 AND user2_id = $3) OR (user1_id = $3 AND user2_id = $2)
+		       )
+		FROM swipes s
+		WHERE s.id = $1
+	`, payload.SwipeID, payload.LikerID, payload.LikeeID).Scan(&isLike, &comment, &matched)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !isLike || matched {
+		return nil
+	}
+
+	premium, err := isPremium(ctx, s.GetDB(), payload.LikeeID)
+	if err != nil {
+		return err
+	}
+
+	notificationType := NotificationNewLike
+	message := "Someone liked you"
+	if payload.IsRose {
+		notificationType = NotificationNewRose
+		message = "Someone sent you a rose"
+	}
+	preview := &models.LikePreview{SwipeID: payload.SwipeID, Redacted: true}
+	data := map[string]interface{}{
+		"target_id":  payload.SwipeID,
+		"preview":    preview,
+		"dedupe_key": outboxDedupeKey(event, payload.LikeeID),
+	}
+
+	if premium {
+		liker, err := s.profileService.GetProfileByUserID(ctx, payload.LikerID)
+		if err != nil {
+			// The liker has deleted their profile since
+			return nil
+		}
+		preview.Redacted = false
+		preview.Name = liker.Name
+		preview.PhotoURL = primaryPhotoURL(liker)
+		preview.Comment = comment.String
+		data["profile_id"] = liker.ID
+		if payload.IsRose {
+			message = liker.Name + " sent you a rose"
+		} else {
+			message = liker.Name + " liked you"
+		}
+	}
+	data["message"] = message
+
+	return s.notificationService.SendNotification(ctx, payload.LikeeID, notificationType, data)
+}
+
+// GetLikes lists the likes and roses the user has received from people they haven't
+// swiped on yet, roses first and then the most recent. Only premium members see who sent
+// them, everyone else gets redacted entries.
+func (s *MatchingService) GetLikes(ctx context.Context, userID string) ([]models.Swipe, error) {
+	db := s.GetDB()
+
+	premium, err := isPremium(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT s.id, s.user_id, s.profile_id, s.is_like, COALESCE(s.message, ''), s.is_rose, s.created_at
+		FROM swipes s
+		JOIN profiles me ON me.id = s.profile_id
+		WHERE me.user_id = $1
+		  AND s.is_like = true
+		  AND NOT EXISTS (
+		      SELECT 1 FROM swipes back
+		      JOIN profiles them ON them.id = back.profile_id
+		      WHERE back.user_id = $1 AND them.user_id = s.user_id
+		  )
+		  AND NOT `+blockedSQL("$1", "s.user_id")+`
+		ORDER BY s.is_rose DESC, s.created_at DESC
+		LIMIT $2
+	`, userID, maxLikesInbox)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	likes := []models.Swipe{}
+	likerIDs := []string{}
+	for rows.Next() {
+		var like models.Swipe
+		if err := rows.Scan(&like.ID, &like.UserID, &like.ProfileID, &like.IsLike, &like.Message, &like.IsRose, &like.CreatedAt); err != nil {
+			return nil, err
+		}
+		likes = append(likes, like)
+		likerIDs = append(likerIDs, like.UserID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !premium {
+		for i := range likes {
+			likes[i].UserID = ""
+			likes[i].Message = ""
+			likes[i].Redacted = true
+		}
+		return likes, nil
+	}
+
+	profiles, err := s.profileService.GetProfilesByUserIDs(ctx, likerIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range likes {
+		likes[i].Profile = profiles[likes[i].UserID]
+	}
+
+	return likes, nil
+}
+
+// isPremium reports whether the user is a premium member
+func isPremium(ctx context.Context, db *sql.DB, userID string) (bool, error) {
+	var premium bool
+	err := db.QueryRowContext(ctx, `SELECT is_premium FROM users WHERE id = $1`, userID).Scan(&premium)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return premium, err
+}
+
+// primaryPhotoURL returns the URL of the profile's primary photo, or its first photo
+func primaryPhotoURL(profile *models.Profile) string {
+	for _, photo := range profile.Photos {
+		if photo.IsPrimary {
+			return photo.URL
+		}
+	}
+	if len(profile.Photos) > 0 {
+		return profile.Photos[0].URL
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueueLikeNotificationCooldown(t *testing.T) {
+	db, _ := openTestDB(t)
+	ctx := context.Background()
+
+	svc := NewMatchingService(db)
+	svc.SetOutbox(NewOutboxDispatcher(db))
+	svc.likeCooldown = defaultLikeNotificationCooldown
+
+	likerID := createTestUser(t, db)
+	otherLikerID := createTestUser(t, db)
+	likeeID := createTestUser(t, db)
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM outbox_events WHERE topic = $1 AND payload->>'likee_id' = $2`, outboxTopicSwipeLiked, likeeID)
+	})
+
+	// expireCooldown makes the liker's last notification older than the cooldown
+	expireCooldown := func(t *testing.T) {
+		t.Helper()
+		_, err := db.Exec(`
+			UPDATE like_notifications SET notified_at = $3
+			WHERE liker_id = $1 AND likee_id = $2
+		`, likerID, likeeID, time.Now().Add(-svc.likeCooldown-time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		name       string
+		likerID    string
+		isRose     bool
+		before     func(t *testing.T)
+		wantQueued bool
+	}{
+		{"first like", likerID, false, nil, true},
+		{"like again within the cooldown", likerID, false, nil, false},
+		{"rose after a like", likerID, true, nil, true},
+		{"rose again within the cooldown", likerID, true, nil, false},
+		{"someone else's like", otherLikerID, false, nil, true},
+		{"like after the cooldown", likerID, false, expireCooldown, true},
+		{"rose after the cooldown", likerID, true, nil, true},
+	}
+	for i, step := range steps {
+		if step.before != nil {
+			step.before(t)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		queued, err := svc.queueLikeNotification(ctx, tx, int64(i+1), step.likerID, likeeID, step.isRose)
+		if err != nil {
+			tx.Rollback()
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if queued != step.wantQueued {
+			t.Errorf("%s: queued = %v, want %v", step.name, queued, step.wantQueued)
+		}
+	}
+}
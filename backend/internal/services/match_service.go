@@ -3,10 +3,15 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/utils"
 )
 
 // MatchingService handles matching operations
@@ -14,13 +19,21 @@ type MatchingService struct {
 	BaseService
 	profileService      *ProfileService
 	notificationService *NotificationService
+	outbox              *OutboxDispatcher
+	turnReminderAfter   time.Duration // Zero disables "your turn" reminders
+	matchExpiry         time.Duration // Zero disables match expiry
+	likeCooldown        time.Duration // How long before the same liker can notify a user again
 }
 
 // NewMatchingService creates a new matching service
 func NewMatchingService(db *sql.DB) *MatchingService {
+	config := utils.NewConfig()
 	return &MatchingService{
-		BaseService:     NewBaseService(db),
-		profileService:  NewProfileService(db),
+		BaseService:       NewBaseService(db),
+		profileService:    NewProfileService(db),
+		turnReminderAfter: config.GetEnvDuration("TURN_REMINDER_AFTER", defaultTurnReminderAfter),
+		matchExpiry:       config.GetEnvDuration("MATCH_EXPIRY", 0),
+		likeCooldown:      config.GetEnvDuration("LIKE_NOTIFICATION_COOLDOWN", defaultLikeNotificationCooldown),
 	}
 }
 
@@ -29,6 +42,16 @@ func (s *MatchingService) SetNotificationService(notificationService *Notificati
 	s.notificationService = notificationService
 }
 
+// SetOutbox sets the outbox that match side effects are delivered through and registers
+// their consumers
+func (s *MatchingService) SetOutbox(outbox *OutboxDispatcher) {
+	s.outbox = outbox
+	outbox.Register(outboxTopicMatchCreated, "notify_match", s.handleMatchCreated)
+	outbox.Register(outboxTopicMatchTurnReminder, "notify_turn_reminder", s.handleTurnReminder)
+	outbox.Register(outboxTopicMatchExpired, "notify_match_expired", s.handleMatchExpired)
+	outbox.Register(outboxTopicSwipeLiked, "notify_like", s.handleSwipeLiked)
+}
+
 // GetDiscoverProfiles retrieves profiles for the discover feed
 func (s *MatchingService) GetDiscoverProfiles(ctx context.Context, userID string, limit int) ([]map[string]interface{}, error) {
 	// Create and use FeedService for this
@@ -36,9 +59,12 @@ func (s *MatchingService) GetDiscoverProfiles(ctx context.Context, userID string
 	return feedService.GetFeed(ctx, userID, limit, 0)
 }
 
-// CreateSwipe creates a swipe and checks for a match
-func (s *MatchingService) CreateSwipe(ctx context.Context, userID string, profileID string, isLike bool) (*models.Match, error) {
+// CreateSwipe records a swipe and checks for a match. A like or rose that doesn't make a
+// match notifies the liked user, at most once per liker within the cooldown.
+func (s *MatchingService) CreateSwipe(ctx context.Context, userID string, input models.SwipeInput) (*models.Match, error) {
 	db := s.GetDB()
+	profileID := input.ProfileID
+	isLike := input.IsLike || input.IsRose
 
 	// Get profile's user_id
 	var profileUserID string
@@ -58,11 +84,13 @@ func (s *MatchingService) CreateSwipe(ctx context.Context, userID string, profil
 	defer tx.Rollback()
 
 	// Record the swipe
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO swipes (user_id, profile_id, is_like, created_at)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (user_id, profile_id) DO UPDATE SET is_like = $3
-	`, userID, profileID, isLike, time.Now())
+	var swipeID int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO swipes (user_id, profile_id, is_like, message, is_rose, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+		ON CONFLICT (user_id, profile_id) DO UPDATE SET is_like = $3, message = NULLIF($4, ''), is_rose = $5
+		RETURNING id
+	`, userID, profileID, isLike, input.Message, input.IsRose && isLike, time.Now()).Scan(&swipeID)
 	
 	if err != nil {
 		return nil, err
@@ -87,10 +115,19 @@ func (s *MatchingService) CreateSwipe(ctx context.Context, userID string, profil
 		return nil, err
 	}
 
-	// If there's no mutual like, just return
+	// If there's no mutual like, let the other user know they've been liked
 	if !mutualLike {
-		err = tx.Commit()
-		return nil, err
+		queued, err := s.queueLikeNotification(ctx, tx, swipeID, userID, profileUserID, input.IsRose)
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		if queued {
+			s.outbox.Wake()
+		}
+		return nil, nil
 	}
 
 	// Create a match
@@ -129,6 +166,12 @@ func (s *MatchingService) CreateSwipe(ctx context.Context, userID string, profil
 		if err != nil {
 			return nil, err
 		}
+
+		// Both users are told about the match once it is committed
+		err = enqueueOutbox(ctx, tx, outboxTopicMatchCreated, matchPayload{MatchID: matchID, User1ID: user1ID, User2ID: user2ID})
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		// Get existing match ID
 		err = tx.QueryRowContext(ctx, `
@@ -146,28 +189,8 @@ func (s *MatchingService) CreateSwipe(ctx context.Context, userID string, profil
 		return nil, err
 	}
 
-	// Send notification if needed
-	if s.notificationService != nil && !matchExists {
-		// Get profiles for match notification
-		profile1, _ := s.profileService.GetProfileByUserID(ctx, user1ID)
-		profile2, _ := s.profileService.GetProfileByUserID(ctx, user2ID)
-
-		// Send notification to both users
-		if profile1 != nil && profile2 != nil {
-			go func() {
-				matchCtx := context.Background()
-				s.notificationService.SendNotification(matchCtx, user1ID, "match", map[string]interface{}{
-					"match_id":  matchID,
-					"profile_id": profile2.ID,
-					"message":   "You matched with " + profile2.Name,
-				})
-				s.notificationService.SendNotification(matchCtx, user2ID, "match", map[string]interface{}{
-					"match_id":  matchID,
-					"profile_id": profile1.ID,
-					"message":   "You matched with " + profile1.Name,
-				})
-			}()
-		}
+	if s.outbox != nil && !matchExists {
+		s.outbox.Wake()
 	}
 
 	// Return the match details
@@ -180,112 +203,204 @@ func (s *MatchingService) CreateSwipe(ctx context.Context, userID string, profil
 	}, nil
 }
 
-// GetMatches retrieves all matches for a user
-func (s *MatchingService) GetMatches(ctx context.Context, userID string) ([]models.MatchWithProfile, error) {
+// Matches returned per page
+const (
+	defaultMatchPageSize = 50
+	maxMatchPageSize     = 100
+)
+
+// ErrInvalidMatchCursor is returned when a matches cursor can't be decoded
+var ErrInvalidMatchCursor = errors.New("invalid cursor")
+
+// GetMatches retrieves a page of the user's matches that haven't expired, pinned matches
+// first and then the most recent conversation first. Archived matches are only listed
+// when params.Archived is set. The matches, unread counts, last messages and partner profiles are
+// loaded with a fixed number of queries however many matches the user has.
+func (s *MatchingService) GetMatches(ctx context.Context, userID string, params models.MatchPageParams) (*models.MatchPage, error) {
 	db := s.GetDB()
 
-	rows, err := db.QueryContext(ctx, `
-		SELECT m.id, 
-			m.user1_id, 
-			m.user2_id, 
-			m.created_at, 
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultMatchPageSize
+	}
+	if limit > maxMatchPageSize {
+		limit = maxMatchPageSize
+	}
+
+	// Matches after the cursor, with the partner and their unread count. One extra row
+	// tells us whether there is another page.
+	query := `
+		SELECT m.id,
+			m.user1_id,
+			m.user2_id,
+			m.created_at,
 			m.last_message_at,
-			CASE 
-				WHEN m.user1_id = $1 THEN m.user1_last_read 
-				ELSE m.user2_last_read 
-			END AS last_read
+			partner.user_id,
+			unread.count,
+			COALESCE(ms.archived, false),
+			COALESCE(ms.muted, false),
+			COALESCE(ms.pinned, false)
 		FROM matches m
-		WHERE m.user1_id = $1 OR m.user2_id = $1
-		ORDER BY m.last_message_at DESC
-	`, userID)
+		LEFT JOIN match_states ms ON ms.match_id = m.id AND ms.user_id = $1
+		CROSS JOIN LATERAL (
+			SELECT CASE WHEN m.user1_id = $1 THEN m.user2_id ELSE m.user1_id END AS user_id,
+				CASE WHEN m.user1_id = $1 THEN m.user1_last_read ELSE m.user2_last_read END AS last_read
+		) partner
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS count
+			FROM messages
+			WHERE match_id = m.id AND sender_id = partner.user_id AND created_at > partner.last_read
+		) unread
+		WHERE (m.user1_id = $1 OR m.user2_id = $1)
+		  AND m.expired_at IS NULL
+		  AND NOT ` + blockedSQL("m.user1_id", "m.user2_id") + `
+		  AND COALESCE(ms.archived, false) = $3`
+	args := []interface{}{userID, limit + 1, params.Archived}
+	if params.Cursor != "" {
+		cursorPinned, cursorTime, cursorID, err := decodeMatchCursor(params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (COALESCE(ms.pinned, false), m.last_message_at, m.id) < ($4, $5, $6)`
+		args = append(args, cursorPinned, cursorTime, cursorID)
+	}
+	query += `
+		ORDER BY COALESCE(ms.pinned, false) DESC, m.last_message_at DESC, m.id DESC
+		LIMIT $2`
 
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var matches []models.MatchWithProfile
+	var matches []models.Match
+	var partnerIDs []string
 	for rows.Next() {
 		var match models.Match
-		var lastRead time.Time
-
+		var partnerID string
 		if err := rows.Scan(
 			&match.ID,
-			&match.User1ID, 
-			&match.User2ID, 
-			&match.CreatedAt, 
+			&match.User1ID,
+			&match.User2ID,
+			&match.CreatedAt,
 			&match.LastMessageAt,
-			&lastRead,
+			&partnerID,
+			&match.UnreadCount,
+			&match.Archived,
+			&match.Muted,
+			&match.Pinned,
 		); err != nil {
 			return nil, err
 		}
+		matches = append(matches, match)
+		partnerIDs = append(partnerIDs, partnerID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		// Determine partner's ID
-		var partnerID string
-		if match.User1ID == userID {
-			partnerID = match.User2ID
-		} else {
-			partnerID = match.User1ID
-		}
-
-		// Get the partner's profile
-		profile, err := s.profileService.GetProfileByUserID(ctx, partnerID)
-		if err != nil {
-			continue
-		}
+	page := &models.MatchPage{Matches: []models.MatchWithProfile{}}
+	if len(matches) > limit {
+		matches, partnerIDs = matches[:limit], partnerIDs[:limit]
+		last := matches[limit-1]
+		page.NextCursor = encodeMatchCursor(last.Pinned, last.LastMessageAt, last.ID)
+	}
+	if len(matches) == 0 {
+		return page, nil
+	}
 
-		// Get unread count
-		var unreadCount int
-		err = db.QueryRowContext(ctx, `
-			SELECT COUNT(*) 
-			FROM messages 
-			WHERE match_id = $1 AND sender_id = $2 AND created_at > $3
-		`, match.ID, partnerID, lastRead).Scan(&unreadCount)
-		
-		if err != nil {
-			unreadCount = 0
-		}
+	// Last message of every match on the page
+	matchIDs := make([]int64, len(matches))
+	for i, match := range matches {
+		matchIDs[i] = match.ID
+	}
+	lastMessages, err := s.getLastMessages(ctx, matchIDs)
+	if err != nil {
+		return nil, err
+	}
 
-		// Get last message if any
-		var lastMessage models.Message
-		err = db.QueryRowContext(ctx, `
-			SELECT id, match_id, sender_id, message, is_read, created_at 
-			FROM messages 
-			WHERE match_id = $1 
-			ORDER BY created_at DESC LIMIT 1
-		`, match.ID).Scan(
-			&lastMessage.ID,
-			&lastMessage.MatchID,
-			&lastMessage.SenderID,
-			&lastMessage.Message,
-			&lastMessage.IsRead,
-			&lastMessage.CreatedAt,
-		)
+	// Partner profiles
+	profiles, err := s.profileService.GetProfilesByUserIDs(ctx, partnerIDs)
+	if err != nil {
+		return nil, err
+	}
 
-		if err != nil && err != sql.ErrNoRows {
+	for i, match := range matches {
+		profile, ok := profiles[partnerIDs[i]]
+		if !ok {
 			continue
 		}
 
-		if err == nil {
-			match.LastMessage = &lastMessage
+		if lastMessage, ok := lastMessages[match.ID]; ok {
+			match.LastMessage = lastMessage
+			match.YourTurn = lastMessage.SenderID == partnerIDs[i]
 		}
-		
-		match.UnreadCount = unreadCount
+		match.ExpiresAt = s.expiresAt(match.LastMessageAt)
 
-		// Create MatchWithProfile
-		matchWithProfile := models.MatchWithProfile{
+		page.Matches = append(page.Matches, models.MatchWithProfile{
 			Match:   match,
 			Profile: *profile,
-		}
-
-		matches = append(matches, matchWithProfile)
+		})
 	}
 
-	if err := rows.Err(); err != nil {
+	return page, nil
+}
+
+// getLastMessages returns the most recent message of each match, keyed by match id
+func (s *MatchingService) getLastMessages(ctx context.Context, matchIDs []int64) (map[int64]*models.Message, error) {
+	rows, err := s.GetDB().QueryContext(ctx, `
+		SELECT DISTINCT ON (match_id) `+messageColumns+`
+		FROM messages
+		WHERE match_id = ANY($1)
+		ORDER BY match_id, id DESC
+	`, pq.Array(matchIDs))
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return matches, nil
+	lastMessages := make(map[int64]*models.Message, len(matchIDs))
+	for rows.Next() {
+		var msg models.Message
+		if err := scanMessage(rows, &msg); err != nil {
+			return nil, err
+		}
+		lastMessages[msg.MatchID] = &msg
+	}
+
+	return lastMessages, rows.Err()
+}
+
+// encodeMatchCursor returns an opaque cursor for the matches after this one
+func encodeMatchCursor(pinned bool, lastMessageAt time.Time, matchID int64) string {
+	raw := strconv.FormatBool(pinned) + ":" + strconv.FormatInt(lastMessageAt.UnixNano(), 10) + ":" + strconv.FormatInt(matchID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeMatchCursor reverses encodeMatchCursor
+func decodeMatchCursor(cursor string) (bool, time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return false, time.Time{}, 0, ErrInvalidMatchCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return false, time.Time{}, 0, ErrInvalidMatchCursor
+	}
+	pinned, err := strconv.ParseBool(parts[0])
+	if err != nil {
+		return false, time.Time{}, 0, ErrInvalidMatchCursor
+	}
+	unixNano, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return false, time.Time{}, 0, ErrInvalidMatchCursor
+	}
+	matchID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return false, time.Time{}, 0, ErrInvalidMatchCursor
+	}
+	return pinned, time.Unix(0, unixNano), matchID, nil
 }
 
 // MarkAsRead marks a match as read by the user
@@ -377,16 +492,10 @@ func (s *MatchingService) GetConversation(ctx context.Context, userID string, ma
 	// Get the messages
 	rows, err := s.GetDB().QueryContext(
 		ctx,
-		`SELECT 
-			m.id, 
-			m.match_id, 
-			m.sender_id, 
-			m.message, 
-			m.is_read,
-			m.created_at
-		FROM messages m
-		WHERE m.match_id = $1
-		ORDER BY m.created_at ASC`,
+		`SELECT `+messageColumns+`
+		FROM messages
+		WHERE match_id = $1
+		ORDER BY created_at ASC`,
 		matchID,
 	)
 	if err != nil {
@@ -398,14 +507,7 @@ func (s *MatchingService) GetConversation(ctx context.Context, userID string, ma
 	for rows.Next() {
 		var msg models.Message
 
-		err := rows.Scan(
-			&msg.ID,
-			&msg.MatchID,
-			&msg.SenderID,
-			&msg.Message,
-			&msg.IsRead,
-			&msg.CreatedAt,
-		)
+		err := scanMessage(rows, &msg)
 		if err != nil {
 			return nil, err
 		}
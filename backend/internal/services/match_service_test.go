@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// benchmarkMatches is how many matches the benchmark user has, one full page
+const benchmarkMatches = defaultMatchPageSize
+
+// seedMatches creates a user matched with n others, each with a full profile and a short
+// conversation, and returns the user's id. Everything is deleted when the benchmark ends.
+func seedMatches(b *testing.B, db *sql.DB, n int) string {
+	b.Helper()
+	ctx := context.Background()
+
+	var userIDs []string
+	var promptID int64
+	b.Cleanup(func() {
+		for _, id := range userIDs {
+			db.Exec(`DELETE FROM users WHERE id = $1`, id)
+		}
+		db.Exec(`DELETE FROM prompts WHERE id = $1`, promptID)
+	})
+
+	exec := func(query string, args ...interface{}) {
+		b.Helper()
+		if _, err := db.ExecContext(ctx, query, args...); err != nil {
+			b.Fatal(err)
+		}
+	}
+	newUser := func(name string) string {
+		b.Helper()
+		var userID, profileID string
+		err := db.QueryRowContext(ctx, `
+			INSERT INTO users (id, email, password_hash)
+			VALUES (gen_random_uuid(), gen_random_uuid() || '@bench.example', 'x')
+			RETURNING id
+		`).Scan(&userID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		userIDs = append(userIDs, userID)
+
+		err = db.QueryRowContext(ctx, `
+			INSERT INTO profiles (id, user_id, name, bio, date_of_birth, gender, location, occupation, onboarding_completed)
+			VALUES (gen_random_uuid(), $1, $2, 'Bio', '1995-06-01', 'woman', 'London', 'Engineer', true)
+			RETURNING id
+		`, userID, name).Scan(&profileID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for position := 0; position < 3; position++ {
+			exec(`
+				INSERT INTO photos (profile_id, url, is_primary, position)
+				VALUES ($1, $2, $3, $4)
+			`, profileID, fmt.Sprintf("https://photos.example/%s/%d.jpg", profileID, position), position == 0, position)
+		}
+		exec(`INSERT INTO profile_prompts (profile_id, prompt_id, answer) VALUES ($1, $2, 'Answer')`, profileID, promptID)
+		return userID
+	}
+
+	if err := db.QueryRowContext(ctx, `INSERT INTO prompts (text) VALUES ('Benchmark prompt') RETURNING id`).Scan(&promptID); err != nil {
+		b.Fatal(err)
+	}
+
+	userID := newUser("Benchmark user")
+	for i := 0; i < n; i++ {
+		partnerID := newUser(fmt.Sprintf("Partner %d", i))
+		var matchID int64
+		err := db.QueryRowContext(ctx, `
+			INSERT INTO matches (user1_id, user2_id, last_message_at)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`, userID, partnerID, time.Now().Add(-time.Duration(i)*time.Minute)).Scan(&matchID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j, senderID := range []string{partnerID, userID, partnerID} {
+			exec(`
+				INSERT INTO messages (match_id, sender_id, message, created_at)
+				VALUES ($1, $2, $3, $4)
+			`, matchID, senderID, fmt.Sprintf("Message %d", j), time.Now().Add(-time.Duration(i)*time.Minute+time.Duration(j)*time.Second))
+		}
+	}
+
+	return userID
+}
+
+// getMatchesPerRow loads a page of matches the way GetMatches did before it was batched,
+// with a profile, unread count and last message query for every match
+func getMatchesPerRow(ctx context.Context, s *MatchingService, userID string, limit int) ([]models.MatchWithProfile, error) {
+	db := s.GetDB()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.id, m.user1_id, m.user2_id, m.created_at, m.last_message_at,
+			CASE WHEN m.user1_id = $1 THEN m.user1_last_read ELSE m.user2_last_read END
+		FROM matches m
+		WHERE (m.user1_id = $1 OR m.user2_id = $1) AND m.expired_at IS NULL
+		ORDER BY m.last_message_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []models.MatchWithProfile
+	for rows.Next() {
+		var match models.Match
+		var lastRead time.Time
+		if err := rows.Scan(&match.ID, &match.User1ID, &match.User2ID, &match.CreatedAt, &match.LastMessageAt, &lastRead); err != nil {
+			return nil, err
+		}
+		partnerID := match.User1ID
+		if partnerID == userID {
+			partnerID = match.User2ID
+		}
+
+		profile, err := s.profileService.GetProfileByUserID(ctx, partnerID)
+		if err != nil {
+			continue
+		}
+
+		err = db.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM messages
+			WHERE match_id = $1 AND sender_id = $2 AND created_at > $3
+		`, match.ID, partnerID, lastRead).Scan(&match.UnreadCount)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastMessage models.Message
+		err = scanMessage(db.QueryRowContext(ctx, `
+			SELECT `+messageColumns+`
+			FROM messages
+			WHERE match_id = $1
+			ORDER BY created_at DESC LIMIT 1
+		`, match.ID), &lastMessage)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if err == nil {
+			match.LastMessage = &lastMessage
+			match.YourTurn = lastMessage.SenderID == partnerID
+		}
+
+		matches = append(matches, models.MatchWithProfile{Match: match, Profile: *profile})
+	}
+	return matches, rows.Err()
+}
+
+// BenchmarkGetMatches compares loading a page of matches one row at a time with the
+// batched loading GetMatches does
+func BenchmarkGetMatches(b *testing.B) {
+	db, _ := openTestDB(b)
+	userID := seedMatches(b, db, benchmarkMatches)
+	s := NewMatchingService(db)
+	ctx := context.Background()
+
+	b.Run("per_row", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			matches, err := getMatchesPerRow(ctx, s, userID, benchmarkMatches)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(matches) != benchmarkMatches {
+				b.Fatalf("loaded %d matches, want %d", len(matches), benchmarkMatches)
+			}
+		}
+	})
+
+	b.Run("batched", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			page, err := s.GetMatches(ctx, userID, models.MatchPageParams{Limit: benchmarkMatches})
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(page.Matches) != benchmarkMatches {
+				b.Fatalf("loaded %d matches, want %d", len(page.Matches), benchmarkMatches)
+			}
+		}
+	})
+}
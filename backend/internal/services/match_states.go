@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// ErrInvalidMatchFlag is returned for a flag other than archived, muted or pinned
+var ErrInvalidMatchFlag = errors.New("flag must be archived, muted or pinned")
+
+// matchFlagColumns maps each match flag to its match_states column
+var matchFlagColumns = map[string]string{
+	models.MatchFlagArchived: "archived",
+	models.MatchFlagMuted:    "muted",
+	models.MatchFlagPinned:   "pinned",
+}
+
+// SetMatchFlag turns one of the user's flags on a match on or off and returns the
+// match's flags for the user
+func (s *MatchingService) SetMatchFlag(ctx context.Context, userID string, matchID int64, flag string, value bool) (*models.MatchState, error) {
+	column, ok := matchFlagColumns[flag]
+	if !ok {
+		return nil, ErrInvalidMatchFlag
+	}
+
+	db := s.GetDB()
+
+	var exists bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM matches WHERE id = $1 AND (user1_id = $2 OR user2_id = $2)
+		)
+	`, matchID, userID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrMatchNotFound
+	}
+
+	state := models.MatchState{MatchID: matchID}
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO match_states (match_id, user_id, `+column+`, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (match_id, user_id) DO UPDATE
+		SET `+column+` = $3, updated_at = $4
+		RETURNING archived, muted, pinned, updated_at
+	`, matchID, userID, value, time.Now()).Scan(&state.Archived, &state.Muted, &state.Pinned, &state.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// isMatchMuted reports whether the user has muted the match
+func isMatchMuted(ctx context.Context, db *sql.DB, userID string, matchID int64) (bool, error) {
+	var muted bool
+	err := db.QueryRowContext(ctx, `
+		SELECT muted FROM match_states WHERE match_id = $1 AND user_id = $2
+	`, matchID, userID).Scan(&muted)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return muted, err
+}
@@ -0,0 +1,510 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// defaultMessageEditWindow is how long after sending a message it can be edited or unsent
+const defaultMessageEditWindow = 15 * time.Minute
+
+// maxEmojiLength caps the size of a reaction in bytes, enough for multi-codepoint emoji
+const maxEmojiLength = 32
+
+// Errors returned when changing existing messages
+var (
+	ErrMessageNotFound   = errors.New("message not found or user not part of conversation")
+	ErrNotMessageSender  = errors.New("only the sender can change this message")
+	ErrEditWindowExpired = errors.New("message can no longer be changed")
+	ErrMessageUnsent     = errors.New("message has been unsent")
+	ErrInvalidEmoji      = errors.New("reaction must be a single emoji")
+)
+
+// EditMessage replaces the text of one of the user's messages, keeping the previous
+// version in the edit history, and tells the match partner about the change. The new
+// text goes through the same safety pipeline as a new message.
+func (s *MessageService) EditMessage(ctx context.Context, userID string, messageID int64, input models.EditMessageInput) (*models.Message, error) {
+	text := input.Message
+
+	tx, err := s.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	msg, err := s.getMessageForChange(ctx, tx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCanChange(msg, userID); err != nil {
+		return nil, err
+	}
+
+	// Nothing to record if the text didn't change
+	if msg.Message == text {
+		return msg, tx.Commit()
+	}
+
+	// Screen the new text, otherwise an edit could slip past what sending it would catch
+	screened := models.MessageInput{MatchID: msg.MatchID, Message: text, Confirmed: input.Confirmed}
+	hash := contentHash(text)
+	action, classification, err := s.screenMessage(ctx, tx, userID, screened, hash, messageID)
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case models.SafetyBlock:
+		s.reportMessage(userID, screened, classification, action)
+		return nil, ErrMessageBlocked
+	case models.SafetyHold:
+		if !input.Confirmed {
+			return nil, ErrMessageNeedsConfirmation
+		}
+	}
+	safetyWarning := action == models.SafetyWarn || action == models.SafetyHold
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO message_edits (message_id, previous_message, edited_at)
+		VALUES ($1, $2, $3)
+	`, messageID, msg.Message, now)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE messages
+		SET message = $1, edited_at = $2, safety_warning = $3, content_hash = $4
+		WHERE id = $5
+	`, text, now, safetyWarning, hash, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Edits saved past a hold are reported like messages sent past one
+	if action == models.SafetyHold && s.outbox != nil {
+		err = enqueueOutbox(ctx, tx, outboxTopicModerationReport, messageReport(userID, screened, classification, action))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	if action == models.SafetyHold && s.outbox != nil {
+		s.outbox.Wake()
+	}
+
+	msg.Message = text
+	msg.Edited = true
+	msg.EditedAt = &now
+	msg.SafetyWarning = safetyWarning
+
+	s.publishMessageUpdate(ctx, userID, msg.MatchID, models.MessageUpdateEvent{
+		Type:          models.MessageEdited,
+		MatchID:       msg.MatchID,
+		MessageID:     messageID,
+		UserID:        userID,
+		Message:       text,
+		SafetyWarning: safetyWarning,
+		At:            now,
+	})
+
+	return msg, nil
+}
+
+// UnsendMessage soft-deletes one of the user's messages. The message stays in the
+// conversation as a tombstone without its text, edit history, reactions or attachments.
+func (s *MessageService) UnsendMessage(ctx context.Context, userID string, messageID int64) error {
+	tx, err := s.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	msg, err := s.getMessageForChange(ctx, tx, userID, messageID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCanChange(msg, userID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		UPDATE messages
+		SET message = '', deleted_at = $1
+		WHERE id = $2
+	`, now, messageID)
+	if err != nil {
+		return err
+	}
+
+	// Unsent text shouldn't survive in the history
+	if _, err = tx.ExecContext(ctx, "DELETE FROM message_edits WHERE message_id = $1", messageID); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM message_reactions WHERE message_id = $1", messageID); err != nil {
+		return err
+	}
+	var attachmentKeys []string
+	if s.attachmentService != nil {
+		if attachmentKeys, err = s.attachmentService.unlinkMessageAttachments(ctx, tx, messageID); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(attachmentKeys) > 0 {
+		s.attachmentService.deleteBlobs(ctx, attachmentKeys)
+	}
+
+	s.publishMessageUpdate(ctx, userID, msg.MatchID, models.MessageUpdateEvent{
+		Type:      models.MessageUnsent,
+		MatchID:   msg.MatchID,
+		MessageID: messageID,
+		UserID:    userID,
+		At:        now,
+	})
+
+	return nil
+}
+
+// GetMessageEdits returns the previous versions of a message, oldest first
+func (s *MessageService) GetMessageEdits(ctx context.Context, userID string, messageID int64) ([]models.MessageEdit, error) {
+	db := s.GetDB()
+
+	msg, err := s.getMessageForChange(ctx, db, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Deleted {
+		return nil, ErrMessageUnsent
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, message_id, previous_message, edited_at
+		FROM message_edits
+		WHERE message_id = $1
+		ORDER BY edited_at ASC, id ASC
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []models.MessageEdit{}
+	for rows.Next() {
+		var edit models.MessageEdit
+		if err := rows.Scan(&edit.ID, &edit.MessageID, &edit.PreviousMessage, &edit.EditedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return edits, nil
+}
+
+// SetReaction sets the user's reaction to a message in one of their matches, replacing
+// any reaction they already left on it
+func (s *MessageService) SetReaction(ctx context.Context, userID string, messageID int64, emoji string) (*models.Reaction, error) {
+	emoji = strings.TrimSpace(emoji)
+	if !isEmoji(emoji) {
+		return nil, ErrInvalidEmoji
+	}
+
+	db := s.GetDB()
+	msg, err := s.getMessageForChange(ctx, db, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Deleted {
+		return nil, ErrMessageUnsent
+	}
+
+	reaction := &models.Reaction{
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emoji,
+		CreatedAt: time.Now(),
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO message_reactions (message_id, user_id, emoji, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (message_id, user_id)
+		DO UPDATE SET emoji = EXCLUDED.emoji, created_at = EXCLUDED.created_at
+	`, messageID, userID, emoji, reaction.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishMessageUpdate(ctx, userID, msg.MatchID, models.MessageUpdateEvent{
+		Type:      models.ReactionAdded,
+		MatchID:   msg.MatchID,
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emoji,
+		At:        reaction.CreatedAt,
+	})
+
+	return reaction, nil
+}
+
+// RemoveReaction removes the user's reaction from a message
+func (s *MessageService) RemoveReaction(ctx context.Context, userID string, messageID int64) error {
+	db := s.GetDB()
+	msg, err := s.getMessageForChange(ctx, db, userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.ExecContext(ctx, `
+		DELETE FROM message_reactions
+		WHERE message_id = $1 AND user_id = $2
+	`, messageID, userID)
+	if err != nil {
+		return err
+	}
+
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return nil
+	}
+
+	s.publishMessageUpdate(ctx, userID, msg.MatchID, models.MessageUpdateEvent{
+		Type:      models.ReactionRemoved,
+		MatchID:   msg.MatchID,
+		MessageID: messageID,
+		UserID:    userID,
+		At:        time.Now(),
+	})
+
+	return nil
+}
+
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// getMessageForChange loads a message from one of the user's matches, unless either of
+// them has blocked the other. Inside a transaction the row stays locked until it ends.
+func (s *MessageService) getMessageForChange(ctx context.Context, q queryRower, userID string, messageID int64) (*models.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE id = $1 AND match_id IN (
+			SELECT m.id FROM matches m
+			WHERE (m.user1_id = $2 OR m.user2_id = $2)
+			  AND NOT ` + blockedSQL("m.user1_id", "m.user2_id") + `
+		)`
+	if _, ok := q.(*sql.Tx); ok {
+		query += " FOR UPDATE"
+	}
+
+	var msg models.Message
+	if err := scanMessage(q.QueryRowContext(ctx, query, messageID, userID), &msg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// checkCanChange checks that the user may edit or unsend a message
+func (s *MessageService) checkCanChange(msg *models.Message, userID string) error {
+	if msg.SenderID != userID {
+		return ErrNotMessageSender
+	}
+	if msg.Deleted {
+		return ErrMessageUnsent
+	}
+	if time.Since(msg.CreatedAt) > s.editWindow {
+		return ErrEditWindowExpired
+	}
+	return nil
+}
+
+// publishMessageUpdate sends a change to an existing message to the match partner. The
+// change is already saved, so failing to deliver it live is only logged.
+func (s *MessageService) publishMessageUpdate(ctx context.Context, userID string, matchID int64, event models.MessageUpdateEvent) {
+	if s.notificationService == nil {
+		return
+	}
+
+	partnerID, err := s.getMatchPartnerID(ctx, userID, matchID)
+	if err == nil {
+		err = s.notificationService.PublishEvent(ctx, partnerID, MessageStream, event.Type, event)
+	}
+	if err != nil {
+		log.Printf("Failed to publish %s event for message %d: %v", event.Type, event.MessageID, err)
+	}
+}
+
+// Code points that only make sense inside an emoji sequence
+const (
+	zeroWidthJoiner   = '\u200D'     // Joins emoji into one, as in family emoji
+	emojiPresentation = '\uFE0F'     // VS16, asks for the emoji form of a symbol
+	keycapMark        = '\u20E3'     // Turns 0-9, # or * into a keycap
+	skinToneFirst     = '\U0001F3FB' // Fitzpatrick skin tone modifiers
+	skinToneLast      = '\U0001F3FF'
+	blackFlag         = '\U0001F3F4' // Base of subdivision flags such as England's
+	tagFirst          = '\U000E0020' // Tag characters spelling out the subdivision
+	tagLast           = '\U000E007E'
+	cancelTag         = '\U000E007F' // Ends a tag sequence
+)
+
+// extendedPictographic approximates the Unicode Extended_Pictographic property, the
+// code points emoji are built from. The unicode package doesn't include it.
+var extendedPictographic = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00A9, Hi: 0x00A9, Stride: 1}, {Lo: 0x00AE, Hi: 0x00AE, Stride: 1},
+		{Lo: 0x203C, Hi: 0x203C, Stride: 1}, {Lo: 0x2049, Hi: 0x2049, Stride: 1},
+		{Lo: 0x2122, Hi: 0x2122, Stride: 1}, {Lo: 0x2139, Hi: 0x2139, Stride: 1},
+		{Lo: 0x2194, Hi: 0x2199, Stride: 1}, {Lo: 0x21A9, Hi: 0x21AA, Stride: 1},
+		{Lo: 0x231A, Hi: 0x231B, Stride: 1}, {Lo: 0x2328, Hi: 0x2328, Stride: 1},
+		{Lo: 0x2388, Hi: 0x2388, Stride: 1}, {Lo: 0x23CF, Hi: 0x23CF, Stride: 1},
+		{Lo: 0x23E9, Hi: 0x23F3, Stride: 1}, {Lo: 0x23F8, Hi: 0x23FA, Stride: 1},
+		{Lo: 0x24C2, Hi: 0x24C2, Stride: 1}, {Lo: 0x25AA, Hi: 0x25AB, Stride: 1},
+		{Lo: 0x25B6, Hi: 0x25B6, Stride: 1}, {Lo: 0x25C0, Hi: 0x25C0, Stride: 1},
+		{Lo: 0x25FB, Hi: 0x25FE, Stride: 1}, {Lo: 0x2600, Hi: 0x2605, Stride: 1},
+		{Lo: 0x2607, Hi: 0x2612, Stride: 1}, {Lo: 0x2614, Hi: 0x2685, Stride: 1},
+		{Lo: 0x2690, Hi: 0x2705, Stride: 1}, {Lo: 0x2708, Hi: 0x2712, Stride: 1},
+		{Lo: 0x2714, Hi: 0x2714, Stride: 1}, {Lo: 0x2716, Hi: 0x2716, Stride: 1},
+		{Lo: 0x271D, Hi: 0x271D, Stride: 1}, {Lo: 0x2721, Hi: 0x2721, Stride: 1},
+		{Lo: 0x2728, Hi: 0x2728, Stride: 1}, {Lo: 0x2733, Hi: 0x2734, Stride: 1},
+		{Lo: 0x2744, Hi: 0x2744, Stride: 1}, {Lo: 0x2747, Hi: 0x2747, Stride: 1},
+		{Lo: 0x274C, Hi: 0x274C, Stride: 1}, {Lo: 0x274E, Hi: 0x274E, Stride: 1},
+		{Lo: 0x2753, Hi: 0x2755, Stride: 1}, {Lo: 0x2757, Hi: 0x2757, Stride: 1},
+		{Lo: 0x2763, Hi: 0x2767, Stride: 1}, {Lo: 0x2795, Hi: 0x2797, Stride: 1},
+		{Lo: 0x27A1, Hi: 0x27A1, Stride: 1}, {Lo: 0x27B0, Hi: 0x27B0, Stride: 1},
+		{Lo: 0x27BF, Hi: 0x27BF, Stride: 1}, {Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2B05, Hi: 0x2B07, Stride: 1}, {Lo: 0x2B1B, Hi: 0x2B1C, Stride: 1},
+		{Lo: 0x2B50, Hi: 0x2B50, Stride: 1}, {Lo: 0x2B55, Hi: 0x2B55, Stride: 1},
+		{Lo: 0x3030, Hi: 0x3030, Stride: 1}, {Lo: 0x303D, Hi: 0x303D, Stride: 1},
+		{Lo: 0x3297, Hi: 0x3297, Stride: 1}, {Lo: 0x3299, Hi: 0x3299, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1F000, Hi: 0x1F0FF, Stride: 1}, // Mahjong, domino and playing cards
+		{Lo: 0x1F10D, Hi: 0x1F10F, Stride: 1},
+		{Lo: 0x1F12F, Hi: 0x1F12F, Stride: 1},
+		{Lo: 0x1F16C, Hi: 0x1F171, Stride: 1},
+		{Lo: 0x1F17E, Hi: 0x1F17F, Stride: 1},
+		{Lo: 0x1F18E, Hi: 0x1F18E, Stride: 1},
+		{Lo: 0x1F191, Hi: 0x1F19A, Stride: 1},
+		{Lo: 0x1F1AD, Hi: 0x1F1E5, Stride: 1},
+		{Lo: 0x1F201, Hi: 0x1F20F, Stride: 1},
+		{Lo: 0x1F21A, Hi: 0x1F21A, Stride: 1},
+		{Lo: 0x1F22F, Hi: 0x1F22F, Stride: 1},
+		{Lo: 0x1F232, Hi: 0x1F23A, Stride: 1},
+		{Lo: 0x1F23C, Hi: 0x1F23F, Stride: 1},
+		{Lo: 0x1F249, Hi: 0x1F3FA, Stride: 1}, // Pictographs, emoticons, transport
+		{Lo: 0x1F400, Hi: 0x1F53D, Stride: 1},
+		{Lo: 0x1F546, Hi: 0x1F64F, Stride: 1},
+		{Lo: 0x1F680, Hi: 0x1F6FF, Stride: 1},
+		{Lo: 0x1F774, Hi: 0x1F77F, Stride: 1},
+		{Lo: 0x1F7D5, Hi: 0x1F7FF, Stride: 1},
+		{Lo: 0x1F80C, Hi: 0x1F80F, Stride: 1},
+		{Lo: 0x1F848, Hi: 0x1F84F, Stride: 1},
+		{Lo: 0x1F85A, Hi: 0x1F85F, Stride: 1},
+		{Lo: 0x1F888, Hi: 0x1F88F, Stride: 1},
+		{Lo: 0x1F8AE, Hi: 0x1F8FF, Stride: 1},
+		{Lo: 0x1F90C, Hi: 0x1F93A, Stride: 1}, // Supplemental symbols and pictographs
+		{Lo: 0x1F93C, Hi: 0x1F945, Stride: 1},
+		{Lo: 0x1F947, Hi: 0x1FAFF, Stride: 1},
+		{Lo: 0x1FC00, Hi: 0x1FFFD, Stride: 1},
+	},
+}
+
+// isEmoji reports whether s is a single emoji: a pictograph optionally followed by VS16,
+// a skin tone or flag tags, a keycap, or a flag made of two regional indicators, with
+// several of them joined by ZWJ counting as one
+func isEmoji(s string) bool {
+	if s == "" || len(s) > maxEmojiLength || !utf8.ValidString(s) {
+		return false
+	}
+
+	runes := []rune(s)
+	i := 0
+	for {
+		next, ok := emojiElementEnd(runes, i)
+		if !ok {
+			return false
+		}
+		i = next
+		if i == len(runes) {
+			return true
+		}
+		if runes[i] != zeroWidthJoiner {
+			return false
+		}
+		i++
+	}
+}
+
+// emojiElementEnd matches one emoji, without ZWJ, starting at runes[i] and returns where
+// it ends
+func emojiElementEnd(runes []rune, i int) (int, bool) {
+	if i >= len(runes) {
+		return 0, false
+	}
+	r := runes[i]
+	i++
+
+	switch {
+	case (r >= '0' && r <= '9') || r == '#' || r == '*':
+		// Keycap, with or without VS16
+		if i < len(runes) && runes[i] == emojiPresentation {
+			i++
+		}
+		if i < len(runes) && runes[i] == keycapMark {
+			return i + 1, true
+		}
+		return 0, false
+	case unicode.Is(unicode.Regional_Indicator, r):
+		// Flags are a pair of regional indicators
+		if i < len(runes) && unicode.Is(unicode.Regional_Indicator, runes[i]) {
+			return i + 1, true
+		}
+		return 0, false
+	case unicode.Is(extendedPictographic, r):
+	default:
+		return 0, false
+	}
+
+	if r == blackFlag && i < len(runes) && runes[i] >= tagFirst && runes[i] <= tagLast {
+		// Subdivision flag, tags up to a cancel tag
+		for i < len(runes) && runes[i] >= tagFirst && runes[i] <= tagLast {
+			i++
+		}
+		if i < len(runes) && runes[i] == cancelTag {
+			return i + 1, true
+		}
+		return 0, false
+	}
+	if i < len(runes) && (runes[i] == emojiPresentation || (runes[i] >= skinToneFirst && runes[i] <= skinToneLast)) {
+		i++
+	}
+	return i, true
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+func TestIsEmoji(t *testing.T) {
+	valid := []string{
+		"👍",
+		"👍🏽",
+		"❤️",
+		"❤",
+		"🇺🇸",
+		"#️⃣",
+		"1️⃣",
+		"👨‍👩‍👧",
+		"🏳️‍🌈",
+		"🏴󠁧󠁢󠁥󠁮󠁧󠁿",
+		"©️",
+	}
+	for _, s := range valid {
+		if !isEmoji(s) {
+			t.Errorf("isEmoji(%q) = false, want true", s)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"a",
+		"1",
+		"123",
+		"$",
+		"+",
+		"<",
+		"#",
+		"👍 ",
+		"👍a",
+		"🇺",
+		"‍👍",
+		"👍‍",
+		"️",
+		"🏽",
+		"👍👍👍👍👍👍👍👍👍",
+	}
+	for _, s := range invalid {
+		if isEmoji(s) {
+			t.Errorf("isEmoji(%q) = true, want false", s)
+		}
+	}
+}
+
+// stubClassifier gives every message the same score
+type stubClassifier struct {
+	score float64
+}
+
+func (c stubClassifier) Classify(ctx context.Context, input ClassificationInput) (*models.Classification, error) {
+	return &models.Classification{Score: c.score, Labels: []string{"test"}}, nil
+}
+
+func TestEditMessageScreening(t *testing.T) {
+	db, _ := openTestDB(t)
+	ctx := context.Background()
+
+	senderID := createTestUser(t, db)
+	partnerID := createTestUser(t, db)
+	matchID := createTestMatch(t, db, senderID, partnerID)
+
+	svc := NewMessageService(db)
+	sent, err := svc.SendMessage(ctx, senderID, models.MessageInput{MatchID: matchID, Message: "hey there"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assertUnchanged checks a rejected edit left the message and its history alone
+	assertUnchanged := func(t *testing.T) {
+		t.Helper()
+		var text string
+		var edits int
+		err := db.QueryRowContext(ctx, `
+			SELECT message, (SELECT COUNT(*) FROM message_edits WHERE message_id = $1)
+			FROM messages WHERE id = $1
+		`, sent.ID).Scan(&text, &edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text != "hey there" || edits != 0 {
+			t.Errorf("message = %q with %d edits, want it unchanged", text, edits)
+		}
+	}
+
+	t.Run("block", func(t *testing.T) {
+		svc.SetContentClassifier(stubClassifier{score: safetyBlockScore})
+		_, err := svc.EditMessage(ctx, senderID, sent.ID, models.EditMessageInput{Message: "blocked", Confirmed: true})
+		if !errors.Is(err, ErrMessageBlocked) {
+			t.Fatalf("EditMessage() error = %v, want ErrMessageBlocked", err)
+		}
+		assertUnchanged(t)
+	})
+
+	t.Run("hold", func(t *testing.T) {
+		svc.SetContentClassifier(stubClassifier{score: safetyHoldScore})
+		_, err := svc.EditMessage(ctx, senderID, sent.ID, models.EditMessageInput{Message: "held"})
+		if !errors.Is(err, ErrMessageNeedsConfirmation) {
+			t.Fatalf("EditMessage() error = %v, want ErrMessageNeedsConfirmation", err)
+		}
+		assertUnchanged(t)
+
+		msg, err := svc.EditMessage(ctx, senderID, sent.ID, models.EditMessageInput{Message: "held", Confirmed: true})
+		if err != nil {
+			t.Fatalf("EditMessage() confirmed error = %v", err)
+		}
+		if msg.Message != "held" || !msg.SafetyWarning {
+			t.Errorf("EditMessage() = %q with warning %v, want \"held\" with a warning", msg.Message, msg.SafetyWarning)
+		}
+	})
+
+	t.Run("allow", func(t *testing.T) {
+		svc.SetContentClassifier(stubClassifier{score: 0})
+		msg, err := svc.EditMessage(ctx, senderID, sent.ID, models.EditMessageInput{Message: "all good"})
+		if err != nil {
+			t.Fatalf("EditMessage() error = %v", err)
+		}
+		if msg.SafetyWarning {
+			t.Error("EditMessage() kept the safety warning on an allowed edit")
+		}
+	})
+}
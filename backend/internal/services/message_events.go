@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// messageSentPayload is the payload of message.sent events
+type messageSentPayload struct {
+	MessageID   int64  `json:"message_id"`
+	MatchID     int64  `json:"match_id"`
+	SenderID    string `json:"sender_id"`
+	RecipientID string `json:"recipient_id"`
+}
+
+// handleMessageSent notifies the recipient of a new message. The message is read again so
+// an edit or deletion made before delivery is respected.
+func (s *MessageService) handleMessageSent(ctx context.Context, event OutboxEvent) error {
+	if s.notificationService == nil {
+		return nil
+	}
+
+	var payload messageSentPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		log.Printf("Dropping malformed %s event %d: %v", event.Topic, event.ID, err)
+		return nil
+	}
+
+	var msg models.Message
+	err := scanMessage(s.GetDB().QueryRowContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE id = $1
+	`, payload.MessageID), &msg)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if msg.Deleted {
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"match_id":   payload.MatchID,
+		"sender_id":  payload.SenderID,
+		"message":    msg.Message,
+		"message_id": msg.ID,
+		"dedupe_key": outboxDedupeKey(event, payload.RecipientID),
+	}
+	if msg.SafetyWarning {
+		data["safety_warning"] = true
+	}
+	if s.attachmentService != nil {
+		messages := []models.Message{msg}
+		if err := s.attachmentService.attachToMessages(ctx, payload.RecipientID, messages); err != nil {
+			return err
+		}
+		if len(messages[0].Attachments) > 0 {
+			data["attachments"] = messages[0].Attachments
+		}
+	}
+
+	return s.notificationService.SendNotification(ctx, payload.RecipientID, "message", data)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// messageClassifierSource identifies reports filed by the message safety pipeline
+const messageClassifierSource = "message_classifier"
+
+// Errors returned when the safety pipeline stops a message
+var (
+	ErrMessageNeedsConfirmation = errors.New("this message may come across as hurtful or unsafe, confirm to send it anyway")
+	ErrMessageBlocked           = errors.New("this message was not sent because it goes against our community guidelines")
+)
+
+// SetContentClassifier replaces the classifier used to screen outgoing messages
+func (s *MessageService) SetContentClassifier(classifier ContentClassifier) {
+	s.classifier = classifier
+}
+
+// screenMessage runs an outgoing message, or the new text of an edited one, through the
+// content classifier and returns the action to take. editedID is the id of the message
+// being edited, or 0 for a new message. If the classifier fails the message is allowed,
+// an outage shouldn't stop people from talking.
+func (s *MessageService) screenMessage(ctx context.Context, tx *sql.Tx, userID string, input models.MessageInput, hash string, editedID int64) (string, *models.Classification, error) {
+	if s.classifier == nil || input.Message == "" {
+		return models.SafetyAllow, nil, nil
+	}
+
+	var isFirstMessage bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT NOT EXISTS(
+			SELECT 1 FROM messages
+			WHERE match_id = $1 AND sender_id = $2 AND ($3 = 0 OR id < $3)
+		)
+	`, input.MatchID, userID, editedID).Scan(&isFirstMessage)
+	if err != nil {
+		return "", nil, err
+	}
+
+	classification, err := s.classifier.Classify(ctx, ClassificationInput{
+		SenderID:       userID,
+		MatchID:        input.MatchID,
+		Message:        input.Message,
+		ContentHash:    hash,
+		IsFirstMessage: isFirstMessage,
+	})
+	if err != nil {
+		log.Printf("Failed to classify message from user %s, allowing it: %v", userID, err)
+		return models.SafetyAllow, nil, nil
+	}
+
+	return safetyAction(classification.Score), classification, nil
+}
+
+// messageReport builds the moderation report for a message stopped or flagged by the
+// safety pipeline
+func messageReport(userID string, input models.MessageInput, classification *models.Classification, action string) models.ModerationReport {
+	matchID := input.MatchID
+	return models.ModerationReport{
+		SubjectType:   models.ModerationSubjectMessage,
+		SubjectUserID: userID,
+		MatchID:       &matchID,
+		Content:       input.Message,
+		Source:        messageClassifierSource,
+		Action:        action,
+		Score:         classification.Score,
+		Labels:        classification.Labels,
+	}
+}
+
+// reportMessage files a moderation report for a blocked message. Blocked messages are
+// never committed, so they can't go through the outbox. Reporting is best effort,
+// failures are only logged.
+func (s *MessageService) reportMessage(userID string, input models.MessageInput, classification *models.Classification, action string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := s.moderationService.Report(ctx, messageReport(userID, input, classification, action))
+	if err != nil {
+		log.Printf("Failed to report message from user %s to moderation: %v", userID, err)
+	}
+}
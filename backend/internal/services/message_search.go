@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// maxSearchQueryLength caps the length of a search query in characters
+const maxSearchQueryLength = 200
+
+// maxSearchResults caps how many results a single search can return
+const maxSearchResults = 50
+
+// Snippet markers passed to ts_headline. They are stripped from the message first, survive
+// HTML escaping and are swapped for <mark> tags afterwards.
+const (
+	snippetStartMarker = "\x01"
+	snippetStopMarker  = "\x02"
+)
+
+// snippetOptions configures ts_headline
+const snippetOptions = "StartSel=" + snippetStartMarker + ", StopSel=" + snippetStopMarker +
+	`, MaxWords=25, MinWords=8, ShortWord=2, MaxFragments=2, FragmentDelimiter=" … "`
+
+// ErrInvalidSearchQuery is returned when a search query is empty or too long
+var ErrInvalidSearchQuery = errors.New("search query must be between 1 and 200 characters")
+
+// SearchMessages searches the text of messages in the user's conversations, best matches
+// first. Unsent messages and conversations where either user has blocked the other are
+// left out. The query supports web search syntax: "quoted phrases", or and -excluded.
+func (s *MessageService) SearchMessages(ctx context.Context, userID string, query string, limit int, offset int) ([]models.MessageSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLength {
+		return nil, ErrInvalidSearchQuery
+	}
+	if limit <= 0 || limit > maxSearchResults {
+		limit = maxSearchResults
+	}
+
+	rows, err := s.GetDB().QueryContext(ctx, `
+		WITH search AS (
+			SELECT websearch_to_tsquery('english', $2) AS query
+		)
+		SELECT
+			msg.id,
+			msg.match_id,
+			msg.sender_id,
+			ts_headline('english', translate(msg.message, $6, ''), search.query, $5),
+			ts_rank(msg.search_vector, search.query) AS rank,
+			msg.created_at,
+			partner.user_id,
+			COALESCE(p.name, ''),
+			COALESCE(photo.url, '')
+		FROM search, messages msg
+		JOIN matches m ON m.id = msg.match_id
+		CROSS JOIN LATERAL (
+			SELECT CASE WHEN m.user1_id = $1 THEN m.user2_id ELSE m.user1_id END AS user_id
+		) partner
+		LEFT JOIN profiles p ON p.user_id = partner.user_id
+		LEFT JOIN LATERAL (
+			SELECT url FROM photos
+			WHERE profile_id = p.id
+			ORDER BY is_primary DESC, position ASC, id ASC
+			LIMIT 1
+		) photo ON true
+		WHERE (m.user1_id = $1 OR m.user2_id = $1)
+		  AND msg.deleted_at IS NULL
+		  AND msg.search_vector @@ search.query
+		  AND NOT `+blockedSQL("m.user1_id", "m.user2_id")+`
+		ORDER BY rank DESC, msg.created_at DESC, msg.id DESC
+		LIMIT $3 OFFSET $4
+	`, userID, query, limit, offset, snippetOptions, snippetStartMarker+snippetStopMarker)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.MessageSearchResult{}
+	for rows.Next() {
+		var result models.MessageSearchResult
+		var snippet string
+		if err := rows.Scan(
+			&result.MessageID,
+			&result.MatchID,
+			&result.SenderID,
+			&snippet,
+			&result.Rank,
+			&result.CreatedAt,
+			&result.Match.PartnerID,
+			&result.Match.PartnerName,
+			&result.Match.PartnerPhotoURL,
+		); err != nil {
+			return nil, err
+		}
+		result.Match.ID = result.MatchID
+		result.Snippet = highlightSnippet(snippet)
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// highlightSnippet escapes a ts_headline snippet for HTML and turns its markers into
+// <mark> tags
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	return strings.NewReplacer(snippetStartMarker, "<mark>", snippetStopMarker, "</mark>").Replace(escaped)
+}
@@ -6,20 +6,66 @@ import (
 	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/utils"
 )
 
+// messageColumns is the column list scanned by scanMessage
+const messageColumns = `id, match_id, sender_id, message, is_read, status, delivered_at, read_at, edited_at, deleted_at, safety_warning, created_at`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMessage scans a row selected with messageColumns
+func scanMessage(row rowScanner, msg *models.Message) error {
+	err := row.Scan(
+		&msg.ID,
+		&msg.MatchID,
+		&msg.SenderID,
+		&msg.Message,
+		&msg.IsRead,
+		&msg.Status,
+		&msg.DeliveredAt,
+		&msg.ReadAt,
+		&msg.EditedAt,
+		&msg.DeletedAt,
+		&msg.SafetyWarning,
+		&msg.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	msg.Edited = msg.EditedAt != nil
+	msg.Deleted = msg.DeletedAt != nil
+	return nil
+}
+
 // MessageService handles message operations
 type MessageService struct {
 	BaseService
-	matchingService    *MatchingService
+	matchingService     *MatchingService
 	notificationService *NotificationService
+	settingsService     *SettingsService
+	attachmentService   *AttachmentService
+	moderationService   *ModerationService
+	outbox              *OutboxDispatcher
+	classifier          ContentClassifier
+	editWindow          time.Duration
 }
 
 // NewMessageService creates a new message service
 func NewMessageService(db *sql.DB) *MessageService {
+	config := utils.NewConfig()
 	return &MessageService{
-		BaseService: NewBaseService(db),
+		BaseService:       NewBaseService(db),
+		settingsService:   NewSettingsService(db),
+		moderationService: NewModerationService(db),
+		classifier:        NewRulesClassifier(db),
+		editWindow:        config.GetEnvDuration("MESSAGE_EDIT_WINDOW", defaultMessageEditWindow),
 	}
 }
 
@@ -29,6 +75,18 @@ func (s *MessageService) Initialize(matchingService *MatchingService, notificati
 	s.notificationService = notificationService
 }
 
+// SetAttachmentService enables photo and voice note attachments on messages
+func (s *MessageService) SetAttachmentService(attachmentService *AttachmentService) {
+	s.attachmentService = attachmentService
+}
+
+// SetOutbox sets the outbox that message side effects are delivered through and registers
+// the message notification consumer. Moderation reports are filed by ModerationService.
+func (s *MessageService) SetOutbox(outbox *OutboxDispatcher) {
+	s.outbox = outbox
+	outbox.Register(outboxTopicMessageSent, "notify_message", s.handleMessageSent)
+}
+
 // SendMessage sends a message in a conversation
 func (s *MessageService) SendMessage(ctx context.Context, userID string, input models.MessageInput) (*models.Message, error) {
 	db := s.GetDB()
@@ -43,6 +101,7 @@ func (s *MessageService) SendMessage(ctx context.Context, userID string, input m
 	// Check if match exists and the user is part of it
 	var user1ID, user2ID string
 	var lastRead time.Time
+	var expired, blocked bool
 	err = tx.QueryRowContext(ctx, `
 		SELECT 
 			m.user1_id, 
@@ -50,10 +109,12 @@ func (s *MessageService) SendMessage(ctx context.Context, userID string, input m
 			CASE 
 				WHEN m.user1_id = $1 THEN m.user1_last_read 
 				ELSE m.user2_last_read 
-			END as last_read
+			END as last_read,
+			m.expired_at IS NOT NULL,
+			`+blockedSQL("m.user1_id", "m.user2_id")+`
 		FROM matches m
 		WHERE m.id = $2 AND (m.user1_id = $1 OR m.user2_id = $1)
-	`, userID, input.MatchID).Scan(&user1ID, &user2ID, &lastRead)
+	`, userID, input.MatchID).Scan(&user1ID, &user2ID, &lastRead, &expired, &blocked)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -61,6 +122,12 @@ func (s *MessageService) SendMessage(ctx context.Context, userID string, input m
 		}
 		return nil, err
 	}
+	if expired {
+		return nil, ErrMatchExpired
+	}
+	if blocked {
+		return nil, ErrUserBlocked
+	}
 
 	// Get the recipient ID
 	var recipientID string
@@ -70,18 +137,52 @@ func (s *MessageService) SendMessage(ctx context.Context, userID string, input m
 		recipientID = user1ID
 	}
 
+	// Run the message through the safety pipeline
+	hash := contentHash(input.Message)
+	action, classification, err := s.screenMessage(ctx, tx, userID, input, hash, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case models.SafetyBlock:
+		s.reportMessage(userID, input, classification, action)
+		return nil, ErrMessageBlocked
+	case models.SafetyHold:
+		if !input.Confirmed {
+			return nil, ErrMessageNeedsConfirmation
+		}
+	}
+	safetyWarning := action == models.SafetyWarn || action == models.SafetyHold
+
 	// Create the message
 	now := time.Now()
 	var messageID int64
 	err = tx.QueryRowContext(ctx, `
-		INSERT INTO messages (match_id, sender_id, message, is_read, created_at)
-		VALUES ($1, $2, $3, false, $4)
+		INSERT INTO messages (match_id, sender_id, message, is_read, safety_warning, content_hash, created_at)
+		VALUES ($1, $2, $3, false, $4, $5, $6)
 		RETURNING id
-	`, input.MatchID, userID, input.Message, now).Scan(&messageID)
+	`, input.MatchID, userID, input.Message, safetyWarning, hash, now).Scan(&messageID)
 	if err != nil {
 		return nil, err
 	}
 
+	// Attach the photos and voice notes uploaded for this message
+	var attachments []models.Attachment
+	if len(input.AttachmentIDs) > 0 {
+		if s.attachmentService == nil {
+			return nil, errors.New("attachments are not enabled")
+		}
+		attachments, err = s.attachmentService.linkAttachments(ctx, tx, userID, input.MatchID, messageID, input.AttachmentIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Attribute the message to the icebreaker it was sent from, if any
+	if err = trackIcebreakerMessage(ctx, tx, userID, input.MatchID, messageID, input.IcebreakerID, input.Message, now); err != nil {
+		return nil, err
+	}
+
 	// Update the match's last message time
 	_, err = tx.ExecContext(ctx, `
 		UPDATE matches
@@ -92,85 +193,126 @@ func (s *MessageService) SendMessage(ctx context.Context, userID string, input m
 		return nil, err
 	}
 
+	// Queue the recipient's notification, and the moderation report for messages sent
+	// past a hold, to go out once the message is committed
+	if s.outbox != nil {
+		err = enqueueOutbox(ctx, tx, outboxTopicMessageSent, messageSentPayload{
+			MessageID:   messageID,
+			MatchID:     input.MatchID,
+			SenderID:    userID,
+			RecipientID: recipientID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if action == models.SafetyHold {
+			err = enqueueOutbox(ctx, tx, outboxTopicModerationReport, messageReport(userID, input, classification, action))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
+	if s.outbox != nil {
+		s.outbox.Wake()
+	}
 
 	// Create and return the message
 	message := &models.Message{
-		ID:        messageID,
-		MatchID:   input.MatchID,
-		SenderID:  userID,
-		Message:   input.Message,
-		IsRead:    false,
-		CreatedAt: now,
-	}
-
-	// Send notification asynchronously
-	if s.notificationService != nil {
-		go func() {
-			notifyCtx := context.Background()
-			data := map[string]interface{}{
-				"match_id":   input.MatchID,
-				"sender_id":  userID,
-				"message":    input.Message,
-				"message_id": messageID,
-			}
-			_ = s.notificationService.SendNotification(notifyCtx, recipientID, "message", data)
-		}()
+		ID:            messageID,
+		MatchID:       input.MatchID,
+		SenderID:      userID,
+		Message:       input.Message,
+		IsRead:        false,
+		Status:        models.MessageStatusSent,
+		SafetyWarning: safetyWarning,
+		CreatedAt:     now,
+	}
+	if len(attachments) > 0 {
+		message.Attachments = s.attachmentService.signedFor(attachments, userID)
 	}
 
 	return message, nil
 }
 
-// GetMessages retrieves messages for a conversation
-func (s *MessageService) GetMessages(ctx context.Context, userID string, matchID int64, limit, offset int) ([]models.Message, error) {
+// maxMessagePageSize caps how many messages a single page can return
+const maxMessagePageSize = 100
+
+// GetMessages retrieves a page of messages for a conversation, newest first. Pages are
+// keyed on message ids so messages arriving while the user scrolls don't shift them.
+// Reading messages does not mark them as read, use MarkReadUpTo for that.
+func (s *MessageService) GetMessages(ctx context.Context, userID string, matchID int64, params models.MessagePageParams) (*models.MessagePage, error) {
 	db := s.GetDB()
 
+	if params.BeforeID > 0 && params.AfterID > 0 {
+		return nil, errors.New("only one of before_id and after_id can be set")
+	}
+	if params.Limit <= 0 || params.Limit > maxMessagePageSize {
+		params.Limit = maxMessagePageSize
+	}
+
 	// Verify match and user participation
-	var exists bool
+	var blocked bool
 	err := db.QueryRowContext(ctx, `
-		SELECT EXISTS(
-			SELECT 1 FROM matches
-			WHERE id = $1 AND (user1_id = $2 OR user2_id = $2)
-		)
-	`, matchID, userID).Scan(&exists)
-
+		SELECT `+blockedSQL("m.user1_id", "m.user2_id")+`
+		FROM matches m
+		WHERE m.id = $1 AND (m.user1_id = $2 OR m.user2_id = $2)
+	`, matchID, userID).Scan(&blocked)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("match not found or user not part of match")
+	}
 	if err != nil {
 		return nil, err
 	}
-
-	if !exists {
-		return nil, errors.New("match not found or user not part of match")
+	if blocked {
+		return nil, ErrUserBlocked
 	}
 
-	// Get messages with pagination
-	rows, err := db.QueryContext(ctx, `
-		SELECT id, match_id, sender_id, message, is_read, created_at
-		FROM messages
-		WHERE match_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`, matchID, limit, offset)
+	// Fetch one extra row to know whether there is another page in that direction
+	var query string
+	var args []interface{}
+	switch {
+	case params.AfterID > 0:
+		query = `
+			SELECT `+messageColumns+`
+			FROM messages
+			WHERE match_id = $1 AND id > $2
+			ORDER BY id ASC
+			LIMIT $3`
+		args = []interface{}{matchID, params.AfterID, params.Limit + 1}
+	case params.BeforeID > 0:
+		query = `
+			SELECT `+messageColumns+`
+			FROM messages
+			WHERE match_id = $1 AND id < $2
+			ORDER BY id DESC
+			LIMIT $3`
+		args = []interface{}{matchID, params.BeforeID, params.Limit + 1}
+	default:
+		query = `
+			SELECT `+messageColumns+`
+			FROM messages
+			WHERE match_id = $1
+			ORDER BY id DESC
+			LIMIT $2`
+		args = []interface{}{matchID, params.Limit + 1}
+	}
 
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	// Parse the results
-	var messages []models.Message
+	messages := []models.Message{}
 	for rows.Next() {
 		var msg models.Message
-		if err := rows.Scan(
-			&msg.ID,
-			&msg.MatchID,
-			&msg.SenderID,
-			&msg.Message,
-			&msg.IsRead,
-			&msg.CreatedAt,
-		); err != nil {
+		if err := scanMessage(rows, &msg); err != nil {
 			return nil, err
 		}
 		messages = append(messages, msg)
@@ -180,51 +322,40 @@ func (s *MessageService) GetMessages(ctx context.Context, userID string, matchID
 		return nil, err
 	}
 
-	// Mark the messages as read if they're from the other user
-	// We do this in a separate transaction, and it's okay if it fails
-	go func() {
-		tx, err := db.Begin()
-		if err != nil {
-			return
-		}
-		defer tx.Rollback()
-
-		// Update messages
-		_, err = tx.Exec(`
-			UPDATE messages
-			SET is_read = true
-			WHERE match_id = $1 AND sender_id != $2 AND is_read = false
-		`, matchID, userID)
-		if err != nil {
-			return
-		}
+	hasMore := len(messages) > params.Limit
+	if hasMore {
+		messages = messages[:params.Limit]
+	}
 
-		// Update the user's last read time on the match
-		_, err = tx.Exec(`
-			UPDATE matches
-			SET user1_last_read = NOW()
-			WHERE id = $1 AND user1_id = $2
-		`, matchID, userID)
-		if err != nil {
-			return
+	if err := s.attachReactions(ctx, messages); err != nil {
+		return nil, err
+	}
+	if s.attachmentService != nil {
+		if err := s.attachmentService.attachToMessages(ctx, userID, messages); err != nil {
+			return nil, err
 		}
+	}
 
-		_, err = tx.Exec(`
-			UPDATE matches
-			SET user2_last_read = NOW()
-			WHERE id = $1 AND user2_id = $2
-		`, matchID, userID)
-		if err != nil {
-			return
+	page := &models.MessagePage{Messages: messages}
+	switch {
+	case params.AfterID > 0:
+		// Fetched oldest first, return newest first like every other page
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
 		}
+		page.HasMoreAfter = hasMore
+		page.HasMoreBefore = true
+	case params.BeforeID > 0:
+		page.HasMoreBefore = hasMore
+		page.HasMoreAfter = true
+	default:
+		page.HasMoreBefore = hasMore
+	}
 
-		_ = tx.Commit()
-	}()
-
-	return messages, nil
+	return page, nil
 }
 
-// MarkMessageAsRead marks a message as read
+// MarkMessageAsRead marks a message, and everything the partner sent before it, as read
 func (s *MessageService) MarkMessageAsRead(ctx context.Context, userID string, messageID int64) error {
 	db := s.GetDB()
 
@@ -250,40 +381,202 @@ func (s *MessageService) MarkMessageAsRead(ctx context.Context, userID string, m
 		return errors.New("cannot mark your own message as read")
 	}
 
-	// Start a transaction
-	tx, err := db.BeginTx(ctx, nil)
+	return s.MarkReadUpTo(ctx, userID, matchID, messageID)
+}
+
+// SetTyping tells the match partner that the user started or stopped typing
+func (s *MessageService) SetTyping(ctx context.Context, userID string, matchID int64, typing bool) error {
+	partnerID, err := s.getMatchPartnerID(ctx, userID, matchID)
+	if err != nil {
+		return err
+	}
+
+	if s.notificationService == nil {
+		return nil
+	}
+
+	eventType := models.ChatTypingStop
+	if typing {
+		eventType = models.ChatTypingStart
+	}
+
+	return s.notificationService.PublishTransientEvent(ctx, partnerID, MessageStream, eventType, models.TypingEvent{
+		Type:    eventType,
+		MatchID: matchID,
+		UserID:  userID,
+		SentAt:  time.Now(),
+	})
+}
+
+// MarkReadUpTo marks the partner's messages in a match as read up to and including
+// messageID and sends a read receipt to the partner. If the user has turned read
+// receipts off, the messages only count as read for the user's own unread counts and
+// the partner sees them as delivered.
+func (s *MessageService) MarkReadUpTo(ctx context.Context, userID string, matchID int64, messageID int64) error {
+	partnerID, err := s.getMatchPartnerID(ctx, userID, matchID)
+	if err != nil {
+		return err
+	}
+
+	shareReceipts, err := s.settingsService.ReadReceiptsEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.GetDB().BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	// Mark the message as read
-	_, err = tx.ExecContext(ctx, `
+	// Mark the partner's messages as read
+	now := time.Now()
+	status := models.MessageStatusDelivered
+	if shareReceipts {
+		status = models.MessageStatusRead
+	}
+	result, err := tx.ExecContext(ctx, `
 		UPDATE messages
-		SET is_read = true
-		WHERE id = $1
-	`, messageID)
+		SET is_read = true,
+		    status = $4,
+		    delivered_at = COALESCE(delivered_at, $5),
+		    read_at = CASE WHEN $6 THEN $5 ELSE NULL END
+		WHERE match_id = $1 AND sender_id = $2 AND id <= $3 AND is_read = false
+	`, matchID, partnerID, messageID, status, now, shareReceipts)
+	if err != nil {
+		return err
+	}
+
+	marked, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 
 	// Update the user's last read time on the match
-	if isUser1 := userID == senderID; isUser1 {
-		_, err = tx.ExecContext(ctx, `
-			UPDATE matches
-			SET user1_last_read = NOW()
-			WHERE id = $1
-		`, matchID)
-	} else {
-		_, err = tx.ExecContext(ctx, `
-			UPDATE matches
-			SET user2_last_read = NOW()
-			WHERE id = $1
-		`, matchID)
+	_, err = tx.ExecContext(ctx, `
+		UPDATE matches
+		SET user1_last_read = $1
+		WHERE id = $2 AND user1_id = $3
+	`, now, matchID, userID)
+	if err != nil {
+		return err
 	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE matches
+		SET user2_last_read = $1
+		WHERE id = $2 AND user2_id = $3
+	`, now, matchID, userID)
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	if marked == 0 || !shareReceipts {
+		return nil
+	}
+	return s.sendReceipt(ctx, partnerID, models.ChatRead, userID, matchID, messageID)
+}
+
+// MarkDeliveredUpTo marks the partner's messages in a match as delivered up to and
+// including messageID and sends a delivered receipt to the partner
+func (s *MessageService) MarkDeliveredUpTo(ctx context.Context, userID string, matchID int64, messageID int64) error {
+	partnerID, err := s.getMatchPartnerID(ctx, userID, matchID)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.GetDB().ExecContext(ctx, `
+		UPDATE messages
+		SET status = $4, delivered_at = $5
+		WHERE match_id = $1 AND sender_id = $2 AND id <= $3 AND status = $6
+	`, matchID, partnerID, messageID, models.MessageStatusDelivered, time.Now(), models.MessageStatusSent)
+	if err != nil {
+		return err
+	}
+
+	marked, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if marked == 0 {
+		return nil
+	}
+	return s.sendReceipt(ctx, partnerID, models.ChatDelivered, userID, matchID, messageID)
+}
+
+// sendReceipt publishes a read or delivered receipt to the sender of the messages
+func (s *MessageService) sendReceipt(ctx context.Context, senderID string, receiptType string, userID string, matchID int64, messageID int64) error {
+	if s.notificationService == nil {
+		return nil
+	}
+
+	return s.notificationService.PublishEvent(ctx, senderID, MessageStream, receiptType, models.ReceiptEvent{
+		Type:      receiptType,
+		MatchID:   matchID,
+		UserID:    userID,
+		MessageID: messageID,
+		At:        time.Now(),
+	})
+}
+
+// attachReactions loads the reactions for a page of messages in a single query
+func (s *MessageService) attachReactions(ctx context.Context, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(messages))
+	index := make(map[int64]int, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+		index[msg.ID] = i
+	}
+
+	rows, err := s.GetDB().QueryContext(ctx, `
+		SELECT message_id, user_id, emoji, created_at
+		FROM message_reactions
+		WHERE message_id = ANY($1)
+		ORDER BY created_at ASC
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reaction models.Reaction
+		if err := rows.Scan(&reaction.MessageID, &reaction.UserID, &reaction.Emoji, &reaction.CreatedAt); err != nil {
+			return err
+		}
+		i := index[reaction.MessageID]
+		messages[i].Reactions = append(messages[i].Reactions, reaction)
+	}
+
+	return rows.Err()
+}
+
+// getMatchPartnerID returns the other participant of a match the user belongs to
+func (s *MessageService) getMatchPartnerID(ctx context.Context, userID string, matchID int64) (string, error) {
+	var user1ID, user2ID string
+	err := s.GetDB().QueryRowContext(ctx, `
+		SELECT user1_id, user2_id
+		FROM matches
+		WHERE id = $1 AND (user1_id = $2 OR user2_id = $2)
+	`, matchID, userID).Scan(&user1ID, &user2ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.New("match not found or user not part of match")
+		}
+		return "", err
+	}
+
+	if user1ID == userID {
+		return user2ID, nil
+	}
+	return user1ID, nil
 }
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// ModerationService records content flagged for review by the moderation team
+type ModerationService struct {
+	BaseService
+}
+
+// NewModerationService creates a new moderation service
+func NewModerationService(db *sql.DB) *ModerationService {
+	return &ModerationService{
+		BaseService: NewBaseService(db),
+	}
+}
+
+// SetOutbox registers the consumer that files moderation reports queued in the outbox
+// along with the content they are about
+func (s *ModerationService) SetOutbox(outbox *OutboxDispatcher) {
+	outbox.Register(outboxTopicModerationReport, "moderation", s.handleModerationReport)
+}
+
+// Report files a moderation report and returns it with its id
+func (s *ModerationService) Report(ctx context.Context, report models.ModerationReport) (*models.ModerationReport, error) {
+	if report.Labels == nil {
+		report.Labels = []string{}
+	}
+	report.Status = "open"
+	report.CreatedAt = time.Now()
+
+	err := s.GetDB().QueryRowContext(ctx, `
+		INSERT INTO moderation_reports (subject_type, subject_user_id, match_id, photo_id, content, source, action, score, labels, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`,
+		report.SubjectType,
+		report.SubjectUserID,
+		report.MatchID,
+		report.PhotoID,
+		report.Content,
+		report.Source,
+		report.Action,
+		report.Score,
+		pq.Array(report.Labels),
+		report.Status,
+		report.CreatedAt,
+	).Scan(&report.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// handleModerationReport files a moderation report queued in the outbox
+func (s *ModerationService) handleModerationReport(ctx context.Context, event OutboxEvent) error {
+	var report models.ModerationReport
+	if err := json.Unmarshal(event.Payload, &report); err != nil {
+		log.Printf("Dropping malformed %s event %d: %v", event.Topic, event.ID, err)
+		return nil
+	}
+
+	_, err := s.Report(ctx, report)
+	return err
+}
+
+// BanPhoto bans a photo's image, so uploads of the same image are blocked from then on
+// on every profile. The photo itself is left for the caller to remove.
+func (s *ModerationService) BanPhoto(ctx context.Context, photoID int64, reason string) error {
+	result, err := s.GetDB().ExecContext(ctx, `
+		INSERT INTO banned_photo_hashes (dhash, reason, banned_at)
+		SELECT dhash, $2, NOW() FROM photos WHERE id = $1 AND dhash IS NOT NULL
+		ON CONFLICT (dhash) DO NOTHING
+	`, photoID, reason)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		var exists bool
+		if err := s.GetDB().QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM photos WHERE id = $1 AND dhash IS NOT NULL)`, photoID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrPhotoNotFound
+		}
+	}
+	return nil
+}
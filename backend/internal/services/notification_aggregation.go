@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/push"
+	"github.com/vibe-code-hinge/backend/internal/utils"
+)
+
+// pushAggregateBatchSize is how many ended aggregation windows are claimed at once
+const pushAggregateBatchSize = 100
+
+// aggregationRule says how pushes of one notification type are collapsed. The first
+// push in a window is sent straight away, later ones are counted and summed up in one
+// push when the window ends.
+type aggregationRule struct {
+	notificationType string
+	window           time.Duration // The default, overridden by AGGREGATE_<TYPE>_WINDOW
+	perMatch         bool          // Keep a separate window for each match
+	title            string
+	summary          string // text/template given .Count
+}
+
+// defaultAggregationRules are the notification types whose pushes are aggregated.
+// Types without a rule are always pushed one by one.
+var defaultAggregationRules = []aggregationRule{
+	{NotificationNewLike, time.Hour, false, "New likes", `You have {{.Count}} new {{plural .Count "like" "likes"}}`},
+	{NotificationNewRose, time.Hour, false, "New roses", `You have {{.Count}} new {{plural .Count "rose" "roses"}}`},
+	{"match", 30 * time.Minute, false, "New matches", `You have {{.Count}} new {{plural .Count "match" "matches"}}`},
+	{"message", 5 * time.Minute, true, "New messages", `You have {{.Count}} new {{plural .Count "message" "messages"}}`},
+	{NotificationMatchExpired, time.Hour, false, "Matches expired", `{{.Count}} more of your matches {{plural .Count "has" "have"}} expired`},
+}
+
+// aggregationSummaries holds the summary template of each rule, named by type
+var aggregationSummaries = func() *template.Template {
+	t := template.New("summaries").Funcs(template.FuncMap{"plural": plural})
+	for _, rule := range defaultAggregationRules {
+		template.Must(t.New(rule.notificationType).Parse(rule.summary))
+	}
+	return t
+}()
+
+// plural picks the singular or plural form of a word for a count
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// aggregationRulesFromConfig returns the aggregation rules keyed by type, with windows
+// from AGGREGATE_<TYPE>_WINDOW where set. A window of 0 turns aggregation off for a type.
+func aggregationRulesFromConfig(config *utils.Config) map[string]aggregationRule {
+	rules := map[string]aggregationRule{}
+	for _, rule := range defaultAggregationRules {
+		key := "AGGREGATE_" + strings.ToUpper(rule.notificationType) + "_WINDOW"
+		rule.window = config.GetEnvDuration(key, rule.window)
+		if rule.window > 0 {
+			rules[rule.notificationType] = rule
+		}
+	}
+	return rules
+}
+
+// aggregatePush records a push in its type's aggregation window and reports whether it
+// was folded into the window's summary instead of being sent now. A push that opens a
+// window is sent now, as is one of a type without an aggregation rule.
+func (s *NotificationService) aggregatePush(ctx context.Context, userID string, notificationType string, matchID int64) (bool, error) {
+	rule, ok := s.aggregationRules[notificationType]
+	if !ok {
+		return false, nil
+	}
+
+	groupKey := ""
+	if rule.perMatch && matchID != 0 {
+		groupKey = strconv.FormatInt(matchID, 10)
+	}
+
+	// A window that has ended with nothing pending is reopened. One that has ended with
+	// pushes pending keeps counting until the flush job sums it up.
+	now := time.Now()
+	var pending int
+	err := s.GetDB().QueryRowContext(ctx, `
+		INSERT INTO push_aggregates (user_id, type, group_key, window_ends_at, pending_count)
+		VALUES ($1, $2, $3, $4, 0)
+		ON CONFLICT (user_id, type, group_key) DO UPDATE
+		SET pending_count = CASE
+		        WHEN push_aggregates.window_ends_at <= $5 AND push_aggregates.pending_count = 0 THEN 0
+		        ELSE push_aggregates.pending_count + 1
+		    END,
+		    window_ends_at = CASE
+		        WHEN push_aggregates.window_ends_at <= $5 AND push_aggregates.pending_count = 0 THEN $4
+		        ELSE push_aggregates.window_ends_at
+		    END
+		RETURNING pending_count
+	`, userID, notificationType, groupKey, now.Add(rule.window), now).Scan(&pending)
+	if err != nil {
+		return false, err
+	}
+
+	return pending > 0, nil
+}
+
+// FlushPushAggregates sends one summary push for every aggregation window that has
+// ended with pushes pending, and opens a new window so a burst that is still going
+// keeps being summed up. Returns the number of summaries sent.
+func (s *NotificationService) FlushPushAggregates(ctx context.Context) (int, error) {
+	if s.pushService == nil {
+		return 0, nil
+	}
+
+	sent := 0
+	for {
+		now := time.Now()
+		rows, err := s.GetDB().QueryContext(ctx, `
+			DELETE FROM push_aggregates
+			WHERE (user_id, type, group_key) IN (
+				SELECT user_id, type, group_key FROM push_aggregates
+				WHERE window_ends_at <= $1
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING user_id, type, group_key, pending_count
+		`, now, pushAggregateBatchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		type aggregate struct {
+			userID           string
+			notificationType string
+			groupKey         string
+			count            int
+		}
+		var aggregates []aggregate
+		for rows.Next() {
+			var a aggregate
+			if err := rows.Scan(&a.userID, &a.notificationType, &a.groupKey, &a.count); err != nil {
+				rows.Close()
+				return sent, err
+			}
+			aggregates = append(aggregates, a)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return sent, err
+		}
+
+		for _, a := range aggregates {
+			rule, ok := s.aggregationRules[a.notificationType]
+			if !ok || a.count == 0 {
+				continue
+			}
+
+			_, err := s.GetDB().ExecContext(ctx, `
+				INSERT INTO push_aggregates (user_id, type, group_key, window_ends_at, pending_count)
+				VALUES ($1, $2, $3, $4, 0)
+				ON CONFLICT (user_id, type, group_key) DO NOTHING
+			`, a.userID, a.notificationType, a.groupKey, now.Add(rule.window))
+			if err != nil {
+				log.Printf("Failed to reopen %s aggregation window for user %s: %v", a.notificationType, a.userID, err)
+			}
+
+			// The user may have turned pushes off since the window opened
+			channels, quietHours, err := s.settingsService.DeliveryPreferences(ctx, a.userID, a.notificationType)
+			if err != nil {
+				log.Printf("Failed to load notification preferences for user %s, using defaults: %v", a.userID, err)
+			}
+			if !channels.Push {
+				continue
+			}
+
+			var body strings.Builder
+			if err := aggregationSummaries.ExecuteTemplate(&body, a.notificationType, struct{ Count int }{a.count}); err != nil {
+				log.Printf("Failed to write %s summary push: %v", a.notificationType, err)
+				continue
+			}
+			data := map[string]string{
+				"type":       a.notificationType,
+				"aggregated": "true",
+				"count":      strconv.Itoa(a.count),
+			}
+			if rule.perMatch && a.groupKey != "" {
+				data["match_id"] = a.groupKey
+			}
+
+			msg := push.Message{Title: rule.title, Body: body.String(), Data: data}
+			if err := s.deliverPush(ctx, a.userID, a.notificationType, msg, quietHours); err != nil {
+				log.Printf("Failed to push %s summary to user %s: %v", a.notificationType, a.userID, err)
+				continue
+			}
+			sent++
+		}
+
+		if len(aggregates) < pushAggregateBatchSize {
+			return sent, nil
+		}
+	}
+}
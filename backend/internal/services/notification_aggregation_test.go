@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/utils"
+)
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "likes"},
+		{1, "like"},
+		{2, "likes"},
+		{-1, "likes"},
+	}
+	for _, tt := range tests {
+		if got := plural(tt.n, "like", "likes"); got != tt.want {
+			t.Errorf("plural(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestAggregationSummaries(t *testing.T) {
+	tests := []struct {
+		notificationType string
+		count            int
+		want             string
+	}{
+		{NotificationNewLike, 1, "You have 1 new like"},
+		{NotificationNewLike, 4, "You have 4 new likes"},
+		{NotificationNewRose, 1, "You have 1 new rose"},
+		{NotificationNewRose, 2, "You have 2 new roses"},
+		{"match", 1, "You have 1 new match"},
+		{"match", 3, "You have 3 new matches"},
+		{"message", 1, "You have 1 new message"},
+		{"message", 12, "You have 12 new messages"},
+		{NotificationMatchExpired, 1, "1 more of your matches has expired"},
+		{NotificationMatchExpired, 5, "5 more of your matches have expired"},
+	}
+	for _, tt := range tests {
+		var body strings.Builder
+		if err := aggregationSummaries.ExecuteTemplate(&body, tt.notificationType, struct{ Count int }{tt.count}); err != nil {
+			t.Fatalf("%s summary: %v", tt.notificationType, err)
+		}
+		if body.String() != tt.want {
+			t.Errorf("%s summary of %d = %q, want %q", tt.notificationType, tt.count, body.String(), tt.want)
+		}
+	}
+}
+
+func TestAggregationRulesFromConfig(t *testing.T) {
+	t.Setenv("AGGREGATE_MESSAGE_WINDOW", "0")
+	t.Setenv("AGGREGATE_NEW_LIKE_WINDOW", "15m")
+	t.Setenv("AGGREGATE_MATCH_WINDOW", "soon")
+
+	rules := aggregationRulesFromConfig(utils.NewConfig())
+	if _, ok := rules["message"]; ok {
+		t.Error("a window of 0 should turn aggregation off")
+	}
+	if got := rules[NotificationNewLike].window; got != 15*time.Minute {
+		t.Errorf("new_like window = %s, want 15m", got)
+	}
+	if got := rules["match"].window; got != 30*time.Minute {
+		t.Errorf("match window = %s, want the 30m default for an unreadable value", got)
+	}
+}
+
+func TestAggregatePush(t *testing.T) {
+	db, _ := openTestDB(t)
+	ctx := context.Background()
+	svc := NewNotificationService(db)
+	userID := createTestUser(t, db)
+
+	// endWindow moves the user's window for a type into the past
+	endWindow := func(t *testing.T, notificationType string) {
+		t.Helper()
+		_, err := db.Exec(`
+			UPDATE push_aggregates SET window_ends_at = NOW() - INTERVAL '1 second'
+			WHERE user_id = $1 AND type = $2
+		`, userID, notificationType)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending := func(t *testing.T, notificationType string) int {
+		t.Helper()
+		var count int
+		err := db.QueryRow(`
+			SELECT COALESCE(SUM(pending_count), 0) FROM push_aggregates WHERE user_id = $1 AND type = $2
+		`, userID, notificationType).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	steps := []struct {
+		name             string
+		notificationType string
+		matchID          int64
+		before           func(t *testing.T)
+		wantAggregated   bool
+		wantPending      int
+	}{
+		{"first opens the window", NotificationNewLike, 0, nil, false, 0},
+		{"second is counted", NotificationNewLike, 0, nil, true, 1},
+		{"third is counted", NotificationNewLike, 0, nil, true, 2},
+		{"ended window with pushes pending keeps counting", NotificationNewLike, 0, func(t *testing.T) { endWindow(t, NotificationNewLike) }, true, 3},
+		{"other types have their own window", NotificationNewRose, 0, nil, false, 0},
+		{"ended window with nothing pending reopens", NotificationNewRose, 0, func(t *testing.T) { endWindow(t, NotificationNewRose) }, false, 0},
+		{"messages open a window per match", "message", 1, nil, false, 0},
+		{"same match is counted", "message", 1, nil, true, 1},
+		{"other match opens its own", "message", 2, nil, false, 1},
+		{"types without a rule are sent", "profile_view", 0, nil, false, 0},
+		{"and never counted", "profile_view", 0, nil, false, 0},
+	}
+	for _, step := range steps {
+		if step.before != nil {
+			step.before(t)
+		}
+		aggregated, err := svc.aggregatePush(ctx, userID, step.notificationType, step.matchID)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if aggregated != step.wantAggregated {
+			t.Errorf("%s: aggregated = %v, want %v", step.name, aggregated, step.wantAggregated)
+		}
+		if got := pending(t, step.notificationType); got != step.wantPending {
+			t.Errorf("%s: %d pending, want %d", step.name, got, step.wantPending)
+		}
+	}
+}
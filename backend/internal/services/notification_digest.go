@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	htmltemplate "html/template"
+	"log"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vibe-code-hinge/backend/internal/mail"
+)
+
+// defaultDigestHour is the local hour daily digests are sent at
+const defaultDigestHour = 9
+
+// Digest contents
+const (
+	maxDigestNotifications = 200 // Unread notifications read per digest
+	maxDigestItems         = 5   // Notifications listed under each section
+)
+
+// digestMinInterval keeps a user from getting two digests a day when their timezone
+// changes or the job runs late
+const digestMinInterval = 20 * time.Hour
+
+// digestSectionTitles are the digest section headings, in the order sections are shown
+var digestSectionTitles = []struct{ notificationType, title string }{
+	{"message", "Messages"},
+	{"match", "New matches"},
+	{NotificationNewLike, "Likes"},
+	{NotificationNewRose, "Roses"},
+	{"your_turn", "Your turn"},
+	{"standouts", "Standouts"},
+	{"match_expired", "Expired matches"},
+}
+
+// digestSection is one notification type in a digest
+type digestSection struct {
+	Title string
+	Count int
+	Items []string // The most recent notification messages
+	More  int      // How many more there are beyond Items
+}
+
+// digestData is passed to the digest templates
+type digestData struct {
+	Name     string // First name, or "there"
+	Total    int
+	Sections []digestSection
+}
+
+// digestTextTemplate renders the plain text digest
+var digestTextTemplate = template.Must(template.New("digest").Parse(`Hi {{.Name}},
+
+Here's what you missed on Vibe.
+{{range .Sections}}
+{{.Title}} ({{.Count}})
+{{range .Items}}  - {{.}}
+{{end}}{{if .More}}  ...and {{.More}} more
+{{end}}{{end}}
+Open Vibe to catch up. You can change which notifications are emailed to you in your notification settings.
+`))
+
+// digestHTMLTemplate renders the HTML digest, html/template escapes notification text
+var digestHTMLTemplate = htmltemplate.Must(htmltemplate.New("digest").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Your Vibe digest</title>
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', sans-serif; max-width: 560px; margin: 0 auto; padding: 1em; color: #222;">
+<p>Hi {{.Name}},</p>
+<p>Here's what you missed on Vibe.</p>
+{{range .Sections}}
+<h2 style="font-size: 1.1em; margin: 1.5em 0 0.5em;">{{.Title}} <span style="color: #777;">({{.Count}})</span></h2>
+<ul style="padding-left: 1.25em; margin: 0;">
+{{range .Items}}<li>{{.}}</li>
+{{end}}{{if .More}}<li style="color: #777;">...and {{.More}} more</li>{{end}}
+</ul>
+{{end}}
+<p style="color: #777; font-size: 0.85em; margin-top: 2em;">You can change which notifications are emailed to you in your notification settings.</p>
+</body>
+</html>
+`))
+
+// SetMailer sets the mailer used for email digests. Digests aren't sent without one.
+func (s *NotificationService) SetMailer(mailer mail.Mailer) {
+	s.mailer = mailer
+}
+
+// SendDailyDigests emails users whose local time is the digest hour a digest of their
+// unread notifications since the last one, covering the types they get by email. Users
+// are claimed before their digest is built, so each gets at most one a day even with
+// several instances running. Returns the number of digests sent.
+func (s *NotificationService) SendDailyDigests(ctx context.Context) (int, error) {
+	if s.mailer == nil {
+		return 0, nil
+	}
+
+	db := s.GetDB()
+	now := time.Now()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT u.id, u.email, COALESCE(MIN(p.name), ''), array_agg(np.type)
+		FROM users u
+		JOIN notification_preferences np ON np.user_id = u.id AND np.email = true
+		LEFT JOIN user_settings us ON us.user_id = u.id
+		LEFT JOIN profiles p ON p.user_id = u.id
+		WHERE EXTRACT(HOUR FROM $1::TIMESTAMPTZ AT TIME ZONE COALESCE(us.timezone, 'UTC')) = $2
+		  AND (us.last_digest_at IS NULL OR us.last_digest_at < $3)
+		GROUP BY u.id, u.email
+	`, now, s.digestHour, now.Add(-digestMinInterval))
+	if err != nil {
+		return 0, err
+	}
+
+	type recipient struct {
+		userID string
+		email  string
+		name   string
+		types  []string
+	}
+	var recipients []recipient
+	for rows.Next() {
+		var r recipient
+		if err := rows.Scan(&r.userID, &r.email, &r.name, pq.Array(&r.types)); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		recipients = append(recipients, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, r := range recipients {
+		// Claim the user, learning when their last digest was sent
+		var lastDigestAt sql.NullTime
+		err := db.QueryRowContext(ctx, `
+			WITH previous AS (
+				SELECT last_digest_at FROM user_settings WHERE user_id = $1
+			)
+			INSERT INTO user_settings (user_id, last_digest_at)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id) DO UPDATE
+			SET last_digest_at = $2
+			WHERE user_settings.last_digest_at IS NULL OR user_settings.last_digest_at < $3
+			RETURNING (SELECT last_digest_at FROM previous)
+		`, r.userID, now, now.Add(-digestMinInterval)).Scan(&lastDigestAt)
+		if err == sql.ErrNoRows {
+			// Another instance got there first
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to claim digest for user %s: %v", r.userID, err)
+			continue
+		}
+
+		since := now.Add(-24 * time.Hour)
+		if lastDigestAt.Valid && lastDigestAt.Time.After(since) {
+			since = lastDigestAt.Time
+		}
+
+		email, err := s.buildDigest(ctx, r.userID, r.email, r.name, r.types, since)
+		if err != nil {
+			log.Printf("Failed to build digest for user %s: %v", r.userID, err)
+			continue
+		}
+		if email == nil {
+			continue
+		}
+
+		if err := s.mailer.Send(ctx, *email); err != nil {
+			log.Printf("Failed to send digest to user %s: %v", r.userID, err)
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// buildDigest renders a user's digest of unread notifications of the given types since
+// a time, or returns nil when there is nothing to tell them
+func (s *NotificationService) buildDigest(ctx context.Context, userID string, to string, name string, types []string, since time.Time) (*mail.Email, error) {
+	rows, err := s.GetDB().QueryContext(ctx, `
+		SELECT type, COALESCE(message, '')
+		FROM notifications
+		WHERE user_id = $1 AND is_read = false AND created_at > $2 AND type = ANY($3)
+		ORDER BY id DESC
+		LIMIT $4
+	`, userID, since, pq.Array(types), maxDigestNotifications)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := map[string]*digestSection{}
+	total := 0
+	for rows.Next() {
+		var notificationType, message string
+		if err := rows.Scan(&notificationType, &message); err != nil {
+			return nil, err
+		}
+
+		section, ok := sections[notificationType]
+		if !ok {
+			section = &digestSection{}
+			sections[notificationType] = section
+		}
+		section.Count++
+		total++
+		if len(section.Items) < maxDigestItems && strings.TrimSpace(message) != "" {
+			section.Items = append(section.Items, quoteForIcebreaker(message))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, nil
+	}
+
+	data := digestData{Name: firstName(name), Total: total}
+	for _, heading := range digestSectionTitles {
+		if section, ok := sections[heading.notificationType]; ok {
+			section.Title = heading.title
+			section.More = section.Count - len(section.Items)
+			data.Sections = append(data.Sections, *section)
+		}
+	}
+
+	var text, html strings.Builder
+	if err := digestTextTemplate.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := digestHTMLTemplate.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	subject := "You have 1 unread notification on Vibe"
+	if total > 1 {
+		subject = "You have " + strconv.Itoa(total) + " unread notifications on Vibe"
+	}
+
+	return &mail.Email{To: to, Subject: subject, Text: text.String(), HTML: html.String()}, nil
+}
+
+// RunNotificationJobs sums up aggregated pushes, sends pushes deferred by quiet hours
+// and sends daily digests every interval until ctx is cancelled. Digests go out in the
+// first run within each user's digest hour, so interval should be well under an hour.
+func (s *NotificationService) RunNotificationJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			summarized, err := s.FlushPushAggregates(ctx)
+			if err != nil {
+				log.Printf("Failed to flush push aggregates: %v", err)
+			}
+			if summarized > 0 {
+				log.Printf("Sent %d summary pushes", summarized)
+			}
+
+			deferred, err := s.SendDeferredPushes(ctx)
+			if err != nil {
+				log.Printf("Failed to send deferred pushes: %v", err)
+			}
+			if deferred > 0 {
+				log.Printf("Sent %d deferred pushes", deferred)
+			}
+
+			digests, err := s.SendDailyDigests(ctx)
+			if err != nil {
+				log.Printf("Failed to send daily digests: %v", err)
+			}
+			if digests > 0 {
+				log.Printf("Sent %d daily digests", digests)
+			}
+		}
+	}
+}
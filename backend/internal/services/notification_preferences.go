@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// Errors returned when updating notification preferences
+var (
+	ErrUnknownNotificationType = errors.New("unknown notification type")
+	ErrInvalidQuietHours       = errors.New("quiet hours start and end must be different HH:MM times")
+	ErrInvalidTimezone         = errors.New("unknown timezone")
+)
+
+// quietHoursLayout is how quiet hours start and end times are written
+const quietHoursLayout = "15:04"
+
+// defaultChannelPreferences are used for notification types a user hasn't changed
+var defaultChannelPreferences = models.ChannelPreferences{InApp: true, Push: true, Email: false}
+
+// defaultQuietHours are used until a user sets their own
+var defaultQuietHours = models.QuietHours{Enabled: false, Start: "22:00", End: "07:00", Timezone: "UTC"}
+
+// GetNotificationPreferences retrieves a user's channel preferences for every
+// notification type and their quiet hours, falling back to defaults for anything unset
+func (s *SettingsService) GetNotificationPreferences(ctx context.Context, userID string) (*models.NotificationPreferences, error) {
+	db := s.GetDB()
+
+	preferences := &models.NotificationPreferences{
+		UserID:     userID,
+		Types:      make(map[string]models.ChannelPreferences, len(models.NotificationPreferenceTypes)),
+		QuietHours: defaultQuietHours,
+	}
+	for _, notificationType := range models.NotificationPreferenceTypes {
+		preferences.Types[notificationType] = defaultChannelPreferences
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT type, in_app, push, email
+		FROM notification_preferences
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var notificationType string
+		var channels models.ChannelPreferences
+		if err := rows.Scan(&notificationType, &channels.InApp, &channels.Push, &channels.Email); err != nil {
+			return nil, err
+		}
+		preferences.Types[notificationType] = channels
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = db.QueryRowContext(ctx, `
+		SELECT quiet_hours_enabled, to_char(quiet_hours_start, 'HH24:MI'), to_char(quiet_hours_end, 'HH24:MI'), timezone
+		FROM user_settings
+		WHERE user_id = $1
+	`, userID).Scan(&preferences.QuietHours.Enabled, &preferences.QuietHours.Start, &preferences.QuietHours.End, &preferences.QuietHours.Timezone)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+// UpdateNotificationPreferences updates the notification types and quiet hours fields
+// present in the input
+func (s *SettingsService) UpdateNotificationPreferences(ctx context.Context, userID string, input models.NotificationPreferencesInput) (*models.NotificationPreferences, error) {
+	preferences, err := s.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for notificationType, update := range input.Types {
+		channels, ok := preferences.Types[notificationType]
+		if !ok {
+			return nil, ErrUnknownNotificationType
+		}
+		if update.InApp != nil {
+			channels.InApp = *update.InApp
+		}
+		if update.Push != nil {
+			channels.Push = *update.Push
+		}
+		if update.Email != nil {
+			channels.Email = *update.Email
+		}
+		preferences.Types[notificationType] = channels
+	}
+
+	if update := input.QuietHours; update != nil {
+		quietHours := &preferences.QuietHours
+		if update.Enabled != nil {
+			quietHours.Enabled = *update.Enabled
+		}
+		if update.Start != nil {
+			quietHours.Start = *update.Start
+		}
+		if update.End != nil {
+			quietHours.End = *update.End
+		}
+		if update.Timezone != nil {
+			quietHours.Timezone = *update.Timezone
+		}
+
+		start, startErr := time.Parse(quietHoursLayout, quietHours.Start)
+		end, endErr := time.Parse(quietHoursLayout, quietHours.End)
+		if startErr != nil || endErr != nil || start.Equal(end) {
+			return nil, ErrInvalidQuietHours
+		}
+		if _, err := time.LoadLocation(quietHours.Timezone); err != nil || quietHours.Timezone == "" || quietHours.Timezone == "Local" {
+			return nil, ErrInvalidTimezone
+		}
+	}
+
+	tx, err := s.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for notificationType := range input.Types {
+		channels := preferences.Types[notificationType]
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO notification_preferences (user_id, type, in_app, push, email, updated_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			ON CONFLICT (user_id, type) DO UPDATE
+			SET in_app = $3, push = $4, email = $5, updated_at = NOW()
+		`, userID, notificationType, channels.InApp, channels.Push, channels.Email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if input.QuietHours != nil {
+		quietHours := preferences.QuietHours
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO user_settings (user_id, quiet_hours_enabled, quiet_hours_start, quiet_hours_end, timezone)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id) DO UPDATE
+			SET quiet_hours_enabled = $2, quiet_hours_start = $3, quiet_hours_end = $4, timezone = $5, updated_at = NOW()
+		`, userID, quietHours.Enabled, quietHours.Start, quietHours.End, quietHours.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+// DeliveryPreferences returns the channels a notification type is delivered on for the
+// user and their quiet hours. The defaults are returned along with any error, so callers
+// can still deliver when preferences can't be read.
+func (s *SettingsService) DeliveryPreferences(ctx context.Context, userID string, notificationType string) (models.ChannelPreferences, models.QuietHours, error) {
+	channels := defaultChannelPreferences
+	quietHours := defaultQuietHours
+
+	var inApp, push, email, quietEnabled sql.NullBool
+	var quietStart, quietEnd, timezone sql.NullString
+	err := s.GetDB().QueryRowContext(ctx, `
+		SELECT np.in_app, np.push, np.email,
+		       us.quiet_hours_enabled, to_char(us.quiet_hours_start, 'HH24:MI'), to_char(us.quiet_hours_end, 'HH24:MI'), us.timezone
+		FROM (SELECT $1::UUID AS user_id) u
+		LEFT JOIN notification_preferences np ON np.user_id = u.user_id AND np.type = $2
+		LEFT JOIN user_settings us ON us.user_id = u.user_id
+	`, userID, notificationType).Scan(&inApp, &push, &email, &quietEnabled, &quietStart, &quietEnd, &timezone)
+	if err != nil {
+		return channels, quietHours, err
+	}
+
+	if inApp.Valid {
+		channels = models.ChannelPreferences{InApp: inApp.Bool, Push: push.Bool, Email: email.Bool}
+	}
+	if quietEnabled.Valid {
+		quietHours = models.QuietHours{
+			Enabled:  quietEnabled.Bool,
+			Start:    quietStart.String,
+			End:      quietEnd.String,
+			Timezone: timezone.String,
+		}
+	}
+
+	return channels, quietHours, nil
+}
@@ -8,75 +8,105 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
 
+	"github.com/vibe-code-hinge/backend/internal/mail"
 	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/utils"
 )
 
+// defaultHeartbeatInterval is how often idle SSE connections receive a heartbeat event
+const defaultHeartbeatInterval = 25 * time.Second
+
+// reconnectDelay is the retry hint sent to SSE clients, in milliseconds
+const reconnectDelay = 3000
+
+// ErrStreamingUnsupported is returned when the response writer cannot be flushed
+var ErrStreamingUnsupported = errors.New("streaming not supported")
+
 // NotificationService handles notifications and SSE operations
 type NotificationService struct {
 	BaseService
-	clients    map[string]map[chan []byte]bool
-	clientsMux sync.RWMutex
+	hub               *EventHub
+	heartbeatInterval time.Duration
+	broker            Broker
+	eventLogRetention time.Duration
+	pushService       *PushService
+	settingsService   *SettingsService
+	aggregationRules  map[string]aggregationRule // Keyed by notification type
+	mailer            mail.Mailer
+	digestHour        int // Local hour daily digests are sent at
 }
 
 // NewNotificationService creates a new NotificationService
 func NewNotificationService(db *sql.DB) *NotificationService {
-	return &NotificationService{
-		BaseService: NewBaseService(db),
-		clients:     make(map[string]map[chan []byte]bool),
-		clientsMux:  sync.RWMutex{},
+	config := utils.NewConfig()
+	s := &NotificationService{
+		BaseService:       NewBaseService(db),
+		hub:               NewEventHub(defaultEventBufferSize),
+		heartbeatInterval: defaultHeartbeatInterval,
+		eventLogRetention: config.GetEnvDuration("EVENT_LOG_RETENTION", defaultEventLogRetention),
+		settingsService:   NewSettingsService(db),
+		aggregationRules:  aggregationRulesFromConfig(config),
+		digestHour:        config.GetEnvInt("DIGEST_HOUR", defaultDigestHour),
 	}
+	s.SetBroker(NewMemoryBroker())
+	return s
+}
+
+// SetBroker sets the broker used to fan events out to every instance. Events received
+// from the broker are delivered to this instance's connections.
+func (s *NotificationService) SetBroker(broker Broker) {
+	if s.broker != nil {
+		if err := s.broker.Close(); err != nil {
+			log.Printf("Failed to close event broker: %v", err)
+		}
+	}
+
+	broker.Subscribe(func(userID string, event Event) {
+		if userID == "" {
+			s.hub.Broadcast(event)
+			return
+		}
+		s.hub.Publish(userID, event)
+	})
+	s.broker = broker
 }
 
-// StreamEvents streams events to the client using SSE
-func (s *NotificationService) StreamEvents(w http.ResponseWriter, r *http.Request, userID string) error {
+// SetPushService sets the service used to push notifications to users who aren't
+// connected
+func (s *NotificationService) SetPushService(pushService *PushService) {
+	s.pushService = pushService
+}
+
+// StreamEvents streams the user's events on the given stream to the client using SSE.
+// When the client reconnects with a Last-Event-ID header, the events it missed are
+// replayed from the event log before switching to live delivery. It blocks until the
+// client disconnects or the connection is dropped by the hub.
+func (s *NotificationService) StreamEvents(w http.ResponseWriter, r *http.Request, userID string, stream EventStream) error {
+	rc := http.NewResponseController(w)
+
 	// Set headers for SSE
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("X-Accel-Buffering", "no")
 
-	// Create a channel for this client
-	messageChan := make(chan []byte)
-
-	// Register client
-	s.clientsMux.Lock()
-	if _, exists := s.clients[userID]; !exists {
-		s.clients[userID] = make(map[chan []byte]bool)
+	// Make sure we can actually stream before registering the client
+	if err := rc.Flush(); err != nil {
+		return ErrStreamingUnsupported
 	}
-	s.clients[userID][messageChan] = true
-	s.clientsMux.Unlock()
 
-	// Ensure client is removed when connection is closed
-	defer func() {
-		s.clientsMux.Lock()
-		delete(s.clients[userID], messageChan)
-		if len(s.clients[userID]) == 0 {
-			delete(s.clients, userID)
-		}
-		s.clientsMux.Unlock()
-		close(messageChan)
-	}()
-
-	// Create channel to notify when client disconnects
-	notify := r.Context().Done()
-	go func() {
-		<-notify
-		// Connection is closed, clean up
-		s.clientsMux.Lock()
-		delete(s.clients[userID], messageChan)
-		if len(s.clients[userID]) == 0 {
-			delete(s.clients, userID)
-		}
-		s.clientsMux.Unlock()
-		close(messageChan)
-	}()
+	// Streams outlive the server's write timeout, so lift it for this connection
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
 
-	// Send test event
-	pingTicker := time.NewTicker(30 * time.Second)
-	defer pingTicker.Stop()
+	// Register client before replaying so nothing published meanwhile is missed,
+	// and make sure it is removed when the connection is closed
+	sub := s.hub.Subscribe(userID, stream)
+	defer s.hub.Unsubscribe(sub)
 
 	// Send "connected" event
 	initialEvent := models.NotificationEvent{
@@ -85,34 +115,172 @@ func (s *NotificationService) StreamEvents(w http.ResponseWriter, r *http.Reques
 		Message:   "Connected to event stream",
 		Timestamp: time.Now(),
 	}
-	initialEventData, _ := json.Marshal(initialEvent)
-	fmt.Fprintf(w, "data: %s\n\n", initialEventData)
-	w.(http.Flusher).Flush()
+	initialEventData, err := json.Marshal(initialEvent)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "retry: %d\n", reconnectDelay); err != nil {
+		return nil
+	}
+	if err := writeEvent(w, rc, Event{Name: "connected", Data: initialEventData}); err != nil {
+		return nil
+	}
 
-	// Keep connection alive and send messages when they arrive
+	// Replay anything the client missed while it was disconnected
+	var lastSentID int64
+	if lastEventID, ok := lastEventIDFromRequest(r); ok {
+		missed, complete, err := s.getEventsSince(r.Context(), userID, stream, lastEventID)
+		if err != nil {
+			return err
+		}
+		if !complete {
+			// The gap can't be filled from the log, so the client has to refetch its state
+			if err := writeEvent(w, rc, Event{Name: "reset", Data: []byte("{}")}); err != nil {
+				return nil
+			}
+		}
+		for _, event := range missed {
+			if err := writeEvent(w, rc, event); err != nil {
+				return nil
+			}
+			lastSentID = event.ID
+		}
+	}
+
+	heartbeatTicker := time.NewTicker(s.heartbeatInterval)
+	defer heartbeatTicker.Stop()
+
+	// Keep connection alive and send events when they arrive
 	for {
 		select {
-		case <-pingTicker.C:
-			// Send ping to keep connection alive
-			fmt.Fprintf(w, "event: ping\ndata: {\"time\": \"%s\"}\n\n", time.Now().Format(time.RFC3339))
-			w.(http.Flusher).Flush()
-		case msg, ok := <-messageChan:
+		case <-r.Context().Done():
+			// Client disconnected
+			return nil
+		case t := <-heartbeatTicker.C:
+			data := fmt.Sprintf(`{"time":"%s"}`, t.Format(time.RFC3339))
+			if err := writeEvent(w, rc, Event{Name: "heartbeat", Data: []byte(data)}); err != nil {
+				return nil
+			}
+		case event, ok := <-sub.Events():
 			if !ok {
-				// Channel closed, return
+				// Dropped by the hub, the client will reconnect
 				return nil
 			}
-			// Send message to client
-			fmt.Fprintf(w, "data: %s\n\n", msg)
-			w.(http.Flusher).Flush()
-		case <-notify:
-			// Client disconnected
-			return nil
+			// Skip live events that were already sent during replay
+			if event.ID != 0 && event.ID <= lastSentID {
+				continue
+			}
+			if err := writeEvent(w, rc, event); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// lastEventIDFromRequest reads the id of the last event the client received, from the
+// Last-Event-ID header sent by EventSource or the last_event_id query parameter
+func lastEventIDFromRequest(r *http.Request) (int64, bool) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// writeEvent writes a single SSE event and flushes it to the client. Events without an
+// id (connected, heartbeat) leave the client's Last-Event-ID unchanged.
+func writeEvent(w http.ResponseWriter, rc *http.ResponseController, event Event) error {
+	if event.ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.ID); err != nil {
+			return err
 		}
 	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, event.Data); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+// PublishEvent records a real-time event in the user's event log and sends it to
+// their open connections on every instance
+func (s *NotificationService) PublishEvent(ctx context.Context, userID string, stream EventStream, name string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Deliver live even if persisting fails, the event just can't be replayed
+	id, err := s.appendEventLog(ctx, userID, stream, name, data)
+	if err != nil {
+		log.Printf("Failed to append event to event log: %v", err)
+	}
+
+	return s.broker.Publish(ctx, userID, Event{
+		ID:     id,
+		Stream: stream,
+		Name:   name,
+		Data:   data,
+	})
+}
+
+// PublishTransientEvent sends a real-time event to the user's open connections on every
+// instance without recording it, for ephemeral state such as typing indicators
+func (s *NotificationService) PublishTransientEvent(ctx context.Context, userID string, stream EventStream, name string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return s.broker.Publish(ctx, userID, Event{
+		Stream: stream,
+		Name:   name,
+		Data:   data,
+	})
 }
 
-// SendNotification sends a notification to a user
+// Subscribe registers a connection for the user's events on the given stream
+func (s *NotificationService) Subscribe(userID string, stream EventStream) *Subscription {
+	return s.hub.Subscribe(userID, stream)
+}
+
+// Unsubscribe removes a connection registered with Subscribe
+func (s *NotificationService) Unsubscribe(sub *Subscription) {
+	s.hub.Unsubscribe(sub)
+}
+
+// SendNotification sends a notification to a user. Notifications about a match the user
+// has muted are dropped, except that messages still reach the message stream, flagged as
+// muted, so an open conversation keeps updating. Otherwise the user's preferences for the
+// type decide whether it goes to the inbox and live stream and whether it is pushed, with
+// pushes during quiet hours held back until they end. A "dedupe_key" in data makes
+// sending the same notification again a no-op for each channel it already reached, so
+// an outbox consumer can return any error and be retried.
 func (s *NotificationService) SendNotification(ctx context.Context, userID string, notificationType string, data map[string]interface{}) error {
+	var muted bool
+	if matchID, ok := data["match_id"].(int64); ok {
+		var err error
+		if muted, err = isMatchMuted(ctx, s.GetDB(), userID, matchID); err != nil {
+			log.Printf("Failed to check whether match %d is muted: %v", matchID, err)
+		}
+	}
+	if muted && notificationType != "message" {
+		return nil
+	}
+
+	// The user's channel preferences for this type decide where it is delivered
+	channels, quietHours, err := s.settingsService.DeliveryPreferences(ctx, userID, notificationType)
+	if err != nil {
+		log.Printf("Failed to load notification preferences for user %s, using defaults: %v", userID, err)
+	}
+
 	// Store notification in database
 	now := time.Now()
 	var targetID int64
@@ -123,32 +291,48 @@ func (s *NotificationService) SendNotification(ctx context.Context, userID strin
 	}
 	
 	message, _ := data["message"].(string)
+	notificationData := notificationDataFrom(data)
+
+	dedupeKey, _ := data["dedupe_key"].(string)
 
 	// Create notification record
-	_, err := s.GetDB().ExecContext(
-		ctx,
-		`INSERT INTO notifications (user_id, type, target_id, message, is_read, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)`,
-		userID, notificationType, targetID, message, false, now,
-	)
-	if err != nil {
-		log.Printf("Failed to save notification: %v", err)
-		// Continue even if DB save fails - we can still deliver the in-memory notification
+	var notificationID int64
+	if !muted && channels.InApp {
+		dataJSON, err := json.Marshal(notificationData)
+		if err != nil {
+			return err
+		}
+		err = s.GetDB().QueryRowContext(
+			ctx,
+			`INSERT INTO notifications (user_id, type, target_id, message, data, is_read, created_at, dedupe_key)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+			ON CONFLICT (dedupe_key) DO NOTHING
+			RETURNING id`,
+			userID, notificationType, targetID, message, string(dataJSON), false, now, dedupeKey,
+		).Scan(&notificationID)
+		if err == sql.ErrNoRows {
+			// Already in the inbox from an earlier attempt, which may have failed later on
+			err = s.GetDB().QueryRowContext(ctx, `
+				SELECT id FROM notifications WHERE dedupe_key = $1
+			`, dedupeKey).Scan(&notificationID)
+		}
+		if err != nil {
+			return fmt.Errorf("save notification: %w", err)
+		}
 	}
 
-	// Create event for SSE
-	event := models.NotificationEvent{
-		Type:      notificationType,
-		UserID:    userID,
-		TargetID:  targetID,
-		Message:   message,
-		Timestamp: now,
+	// Users without an open stream on this instance are sent a push instead. Presence is
+	// per instance, so a user connected elsewhere may get both.
+	if !muted && channels.Push && s.pushService != nil && s.hub.ConnectionCount(userID) == 0 {
+		err := s.deliverOnce(ctx, dedupeKey, deliveryChannelPush, func() error {
+			return s.pushNotification(ctx, userID, notificationType, notificationID, message, notificationData, data, quietHours)
+		})
+		if err != nil {
+			return err
+		}
 	}
 
-	// When type is "message", create a MessageEvent instead
-	var eventData []byte
-	var err2 error
-	
+	// Message events go to the message stream, everything else to the notification stream
 	if notificationType == "message" {
 		// For message events, get the data we need
 		var matchID int64
@@ -168,40 +352,103 @@ func (s *NotificationService) SendNotification(ctx context.Context, userID strin
 		if val, ok := data["message_id"].(int64); ok {
 			messageID = val
 		}
+		attachments, _ := data["attachments"].([]models.Attachment)
+		safetyWarning, _ := data["safety_warning"].(bool)
 		
 		messageEvent := models.MessageEvent{
-			Type:      "message",
-			MessageID: messageID,
-			MatchID:   matchID,
-			SenderID:  senderID,
-			Message:   messageText,
-			CreatedAt: now,
+			Type:        "message",
+			MessageID:   messageID,
+			MatchID:     matchID,
+			SenderID:    senderID,
+			Message:     messageText,
+			Attachments:   attachments,
+			SafetyWarning: safetyWarning,
+			Muted:         muted,
+			CreatedAt:     now,
 		}
-		eventData, err2 = json.Marshal(messageEvent)
-	} else {
-		eventData, err2 = json.Marshal(event)
+		return s.deliverOnce(ctx, dedupeKey, deliveryChannelStream, func() error {
+			return s.PublishEvent(ctx, userID, MessageStream, "message", messageEvent)
+		})
 	}
-	
-	if err2 != nil {
-		return err2
-	}
-
-	// Send to connected clients
-	s.clientsMux.RLock()
-	if clients, exists := s.clients[userID]; exists {
-		for clientChan := range clients {
-			select {
-			case clientChan <- eventData:
-				// Message sent
-			default:
-				// Channel buffer full, skip this message
-				log.Printf("Channel buffer full for user %s, skipping message", userID)
-			}
-		}
+
+	// Chat needs message events whatever the preferences, other events are in-app only
+	if !channels.InApp {
+		return nil
 	}
-	s.clientsMux.RUnlock()
 
-	return nil
+	// Create event for SSE
+	event := models.NotificationEvent{
+		Type:           notificationType,
+		NotificationID: notificationID,
+		UserID:         userID,
+		TargetID:       targetID,
+		Message:        message,
+		Data:           &notificationData,
+		Timestamp:      now,
+	}
+	return s.deliverOnce(ctx, dedupeKey, deliveryChannelStream, func() error {
+		return s.PublishEvent(ctx, userID, NotificationStream, notificationType, event)
+	})
+}
+
+// Channels recorded in notification_deliveries
+const (
+	deliveryChannelPush   = "push"
+	deliveryChannelStream = "stream"
+)
+
+// deliverOnce runs deliver unless the notification with dedupeKey already reached the
+// channel, and records that it has once deliver succeeds. Notifications without a key
+// are always delivered.
+func (s *NotificationService) deliverOnce(ctx context.Context, dedupeKey string, channel string, deliver func() error) error {
+	if dedupeKey == "" {
+		return deliver()
+	}
+
+	var delivered bool
+	err := s.GetDB().QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM notification_deliveries WHERE dedupe_key = $1 AND channel = $2)
+	`, dedupeKey, channel).Scan(&delivered)
+	if err != nil {
+		return err
+	}
+	if delivered {
+		return nil
+	}
+
+	if err := deliver(); err != nil {
+		return fmt.Errorf("deliver notification by %s: %w", channel, err)
+	}
+
+	_, err = s.GetDB().ExecContext(ctx, `
+		INSERT INTO notification_deliveries (dedupe_key, channel, delivered_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT DO NOTHING
+	`, dedupeKey, channel)
+	return err
+}
+
+// Notifications returned per inbox page
+const (
+	defaultNotificationPageSize = 20
+	maxNotificationPageSize     = 100
+)
+
+// Errors returned by the notification inbox
+var (
+	ErrNotificationNotFound      = errors.New("notification not found or not owned by user")
+	ErrInvalidNotificationCursor = errors.New("invalid cursor")
+)
+
+// notificationDataFrom picks the deep-link targets out of the data passed to SendNotification
+func notificationDataFrom(data map[string]interface{}) models.NotificationData {
+	var notificationData models.NotificationData
+	notificationData.MatchID, _ = data["match_id"].(int64)
+	notificationData.ProfileID, _ = data["profile_id"].(string)
+	notificationData.MessageID, _ = data["message_id"].(int64)
+	notificationData.SenderID, _ = data["sender_id"].(string)
+	notificationData.Preview, _ = data["preview"].(*models.LikePreview)
+	return notificationData
 }
 
 // GetUnreadNotificationCount gets the count of unread notifications for a user
@@ -219,51 +466,73 @@ func (s *NotificationService) GetUnreadNotificationCount(ctx context.Context, us
 	return count, nil
 }
 
-// GetNotifications gets notifications for a user
-func (s *NotificationService) GetNotifications(ctx context.Context, userID string, limit, offset int) ([]map[string]interface{}, error) {
-	rows, err := s.GetDB().QueryContext(
-		ctx,
-		`SELECT id, type, target_id, message, is_read, created_at
+// GetNotifications gets a page of a user's notifications, newest first. Pages are keyed
+// on notification ids so new notifications don't shift them.
+func (s *NotificationService) GetNotifications(ctx context.Context, userID string, params models.NotificationPageParams) (*models.NotificationPage, error) {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultNotificationPageSize
+	}
+	if limit > maxNotificationPageSize {
+		limit = maxNotificationPageSize
+	}
+
+	query := `
+		SELECT id, type, COALESCE(target_id, 0), COALESCE(message, ''), data, is_read, created_at
 		FROM notifications
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3`,
-		userID, limit, offset,
-	)
+		WHERE user_id = $1`
+	args := []interface{}{userID, limit + 1}
+	if params.Cursor != "" {
+		beforeID, err := strconv.ParseInt(params.Cursor, 10, 64)
+		if err != nil || beforeID <= 0 {
+			return nil, ErrInvalidNotificationCursor
+		}
+		query += ` AND id < $3`
+		args = append(args, beforeID)
+	}
+	query += `
+		ORDER BY id DESC
+		LIMIT $2`
+
+	rows, err := s.GetDB().QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var notifications []map[string]interface{}
+	page := &models.NotificationPage{Notifications: []models.Notification{}}
 	for rows.Next() {
-		var id, targetID int64
-		var notificationType, message string
-		var isRead bool
-		var createdAt time.Time
-
-		err := rows.Scan(&id, &notificationType, &targetID, &message, &isRead, &createdAt)
+		var notification models.Notification
+		var dataJSON []byte
+		err := rows.Scan(
+			&notification.ID,
+			&notification.Type,
+			&notification.TargetID,
+			&notification.Message,
+			&dataJSON,
+			&notification.IsRead,
+			&notification.CreatedAt,
+		)
 		if err != nil {
 			return nil, err
 		}
-
-		notification := map[string]interface{}{
-			"id":         id,
-			"type":       notificationType,
-			"target_id":  targetID,
-			"message":    message,
-			"is_read":    isRead,
-			"created_at": createdAt,
+		if err := json.Unmarshal(dataJSON, &notification.Data); err != nil {
+			return nil, err
 		}
 
-		notifications = append(notifications, notification)
+		page.Notifications = append(page.Notifications, notification)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return notifications, nil
+	if len(page.Notifications) > limit {
+		page.Notifications = page.Notifications[:limit]
+		page.NextCursor = strconv.FormatInt(page.Notifications[limit-1].ID, 10)
+	}
+
+	return page, nil
 }
 
 // MarkNotificationAsRead marks a notification as read
@@ -283,7 +552,7 @@ func (s *NotificationService) MarkNotificationAsRead(ctx context.Context, userID
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("notification not found or not owned by user")
+		return ErrNotificationNotFound
 	}
 
 	return nil
@@ -297,4 +566,4 @@ func (s *NotificationService) MarkAllNotificationsAsRead(ctx context.Context, us
 		userID,
 	)
 	return err
-} 
\ No newline at end of file
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// Onboarding requirements
+const (
+	defaultOnboardingMinPhotos = 3
+	onboardingPrompts          = 3 // Prompt answers a profile has, no more and no fewer
+)
+
+// ErrOnboardingIncomplete is returned when finishing onboarding with steps still missing
+var ErrOnboardingIncomplete = errors.New("onboarding is not complete")
+
+// profileCompleteness scores how filled in a profile is from 0 to 100. Photos and
+// prompts count the most, the optional fields make up the rest.
+func profileCompleteness(profile *models.Profile) int {
+	score := 0
+	if profile.Name != "" && profile.DateOfBirth != "" && profile.Gender != "" {
+		score += 20
+	}
+	if profile.Bio != "" {
+		score += 10
+	}
+	if profile.Location != "" {
+		score += 5
+	}
+	if profile.Occupation != "" {
+		score += 5
+	}
+	score += 30 * min(len(profile.Photos), maxPhotosPerProfile) / maxPhotosPerProfile
+	score += 30 * min(len(profile.Prompts), onboardingPrompts) / onboardingPrompts
+	return score
+}
+
+// GetOnboarding returns the user's onboarding step and what is still missing
+func (s *ProfileService) GetOnboarding(ctx context.Context, userID string) (*models.OnboardingStatus, error) {
+	status, _, err := s.onboardingStatus(ctx, userID)
+	return status, err
+}
+
+// CompleteOnboarding finishes onboarding once every step is complete, after which the
+// profile is shown in other users' feeds. Finishing again does nothing.
+func (s *ProfileService) CompleteOnboarding(ctx context.Context, userID string) (*models.OnboardingStatus, error) {
+	status, profileID, err := s.onboardingStatus(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if status.Completed {
+		return status, nil
+	}
+	if status.Step != models.OnboardingStepDone {
+		return nil, fmt.Errorf("%w: %s", ErrOnboardingIncomplete, status.Missing[0].Message)
+	}
+
+	_, err = s.GetDB().ExecContext(ctx, `
+		UPDATE profiles
+		SET onboarding_completed = true, updated_at = NOW()
+		WHERE id = $1
+	`, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Completed = true
+	return status, nil
+}
+
+// onboardingStatus works out the user's onboarding status, and returns their profile id
+// if they have a profile. Once onboarding is finished it stays done, even if the profile
+// is edited later.
+func (s *ProfileService) onboardingStatus(ctx context.Context, userID string) (*models.OnboardingStatus, string, error) {
+	db := s.GetDB()
+
+	var profile *models.Profile
+	var profileID string
+	err := db.QueryRowContext(ctx, `SELECT id FROM profiles WHERE user_id = $1`, userID).Scan(&profileID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, "", err
+	}
+	if err == nil {
+		profile, err = s.GetProfileByID(ctx, profileID)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	var hasPreferences bool
+	err = db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM preferences WHERE user_id = $1)`, userID).Scan(&hasPreferences)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return onboardingProgress(profile, hasPreferences, s.minPhotos), profileID, nil
+}
+
+// onboardingProgress works out the onboarding status of a profile, nil if the user has
+// none yet. The step is the first one with something missing.
+func onboardingProgress(profile *models.Profile, hasPreferences bool, minPhotos int) *models.OnboardingStatus {
+	status := &models.OnboardingStatus{
+		Step:    models.OnboardingStepDone,
+		Missing: []models.OnboardingRequirement{},
+	}
+	missing := func(step string, field string, message string) {
+		status.Missing = append(status.Missing, models.OnboardingRequirement{Step: step, Field: field, Message: message})
+	}
+
+	// Basics
+	if profile == nil || profile.Name == "" {
+		missing(models.OnboardingStepBasics, "name", "Add your name")
+	}
+	if profile == nil || profile.DateOfBirth == "" {
+		missing(models.OnboardingStepBasics, "date_of_birth", "Add your date of birth")
+	}
+	if profile == nil || profile.Gender == "" {
+		missing(models.OnboardingStepBasics, "gender", "Add your gender")
+	}
+
+	// Photos
+	photos := 0
+	if profile != nil {
+		photos = len(profile.Photos)
+	}
+	if photos < minPhotos {
+		missing(models.OnboardingStepPhotos, "photos", fmt.Sprintf("Add %d more %s", minPhotos-photos, plural(minPhotos-photos, "photo", "photos")))
+	}
+
+	// Prompts
+	prompts := 0
+	if profile != nil {
+		prompts = len(profile.Prompts)
+	}
+	if prompts < onboardingPrompts {
+		missing(models.OnboardingStepPrompts, "prompts", fmt.Sprintf("Answer %d more %s", onboardingPrompts-prompts, plural(onboardingPrompts-prompts, "prompt", "prompts")))
+	} else if prompts > onboardingPrompts {
+		missing(models.OnboardingStepPrompts, "prompts", fmt.Sprintf("Remove %d %s", prompts-onboardingPrompts, plural(prompts-onboardingPrompts, "prompt", "prompts")))
+	}
+
+	// Preferences
+	if !hasPreferences {
+		missing(models.OnboardingStepPreferences, "preferences", "Choose who you'd like to see")
+	}
+
+	if profile != nil {
+		status.Completed = profile.OnboardingCompleted
+		status.Completeness = profile.Completeness
+	}
+	if len(status.Missing) > 0 && !status.Completed {
+		status.Step = status.Missing[0].Step
+	}
+
+	return status
+}
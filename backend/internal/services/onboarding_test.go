@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// testProfile builds a profile with the basics filled in and the given number of photos
+// and prompt answers
+func testProfile(photos int, prompts int) *models.Profile {
+	profile := &models.Profile{Name: "Sam", DateOfBirth: "1995-06-01", Gender: "woman"}
+	profile.Photos = make([]models.Photo, photos)
+	profile.Prompts = make([]models.ProfilePrompt, prompts)
+	return profile
+}
+
+func TestProfileCompleteness(t *testing.T) {
+	full := testProfile(maxPhotosPerProfile, onboardingPrompts)
+	full.Bio, full.Location, full.Occupation = "Bio", "London", "Engineer"
+
+	tests := []struct {
+		name    string
+		profile *models.Profile
+		want    int
+	}{
+		{"empty", &models.Profile{}, 0},
+		{"basics", testProfile(0, 0), 20},
+		{"basics missing gender", &models.Profile{Name: "Sam", DateOfBirth: "1995-06-01"}, 0},
+		{"onboarding minimum", testProfile(3, 3), 20 + 15 + 30},
+		{"one photo", testProfile(1, 0), 20 + 5},
+		{"photos past the limit", testProfile(maxPhotosPerProfile+2, 0), 20 + 30},
+		{"prompts past the limit", testProfile(0, onboardingPrompts+1), 20 + 30},
+		{"everything", full, 100},
+	}
+	for _, tt := range tests {
+		if got := profileCompleteness(tt.profile); got != tt.want {
+			t.Errorf("%s: profileCompleteness() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOnboardingProgress(t *testing.T) {
+	completed := testProfile(0, 0)
+	completed.OnboardingCompleted = true
+
+	tests := []struct {
+		name           string
+		profile        *models.Profile
+		hasPreferences bool
+		wantStep       string
+		wantMissing    []string // Fields, in order
+		wantMessage    string   // Of the first missing requirement
+	}{
+		{"no profile", nil, false, models.OnboardingStepBasics, []string{"name", "date_of_birth", "gender", "photos", "prompts", "preferences"}, "Add your name"},
+		{"missing gender", &models.Profile{Name: "Sam", DateOfBirth: "1995-06-01"}, true, models.OnboardingStepBasics, []string{"gender", "photos", "prompts"}, "Add your gender"},
+		{"basics done", testProfile(0, 0), false, models.OnboardingStepPhotos, []string{"photos", "prompts", "preferences"}, "Add 3 more photos"},
+		{"one photo short", testProfile(2, 0), false, models.OnboardingStepPhotos, []string{"photos", "prompts", "preferences"}, "Add 1 more photo"},
+		{"photos done", testProfile(3, 1), false, models.OnboardingStepPrompts, []string{"prompts", "preferences"}, "Answer 2 more prompts"},
+		{"too many prompts", testProfile(3, 4), false, models.OnboardingStepPrompts, []string{"prompts", "preferences"}, "Remove 1 prompt"},
+		{"prompts done", testProfile(3, 3), false, models.OnboardingStepPreferences, []string{"preferences"}, "Choose who you'd like to see"},
+		{"done", testProfile(6, 3), true, models.OnboardingStepDone, nil, ""},
+		{"finished then edited", completed, true, models.OnboardingStepDone, []string{"photos", "prompts"}, "Add 3 more photos"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := onboardingProgress(tt.profile, tt.hasPreferences, defaultOnboardingMinPhotos)
+			if status.Step != tt.wantStep {
+				t.Errorf("step = %q, want %q", status.Step, tt.wantStep)
+			}
+
+			var fields []string
+			for _, requirement := range status.Missing {
+				fields = append(fields, requirement.Field)
+			}
+			if len(fields) != len(tt.wantMissing) {
+				t.Fatalf("missing %v, want %v", fields, tt.wantMissing)
+			}
+			for i := range fields {
+				if fields[i] != tt.wantMissing[i] {
+					t.Fatalf("missing %v, want %v", fields, tt.wantMissing)
+				}
+			}
+			if len(status.Missing) > 0 && status.Missing[0].Message != tt.wantMessage {
+				t.Errorf("first message = %q, want %q", status.Missing[0].Message, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/utils"
+)
+
+// Outbox event statuses
+const (
+	outboxStatusPending = "pending"
+	outboxStatusDone    = "done"
+	outboxStatusDead    = "dead" // Gave up after too many attempts, kept for inspection
+)
+
+// Outbox topics
+const (
+	outboxTopicMatchCreated      = "match.created"
+	outboxTopicMatchTurnReminder = "match.turn_reminder"
+	outboxTopicMatchExpired      = "match.expired"
+	outboxTopicSwipeLiked        = "swipe.liked"
+	outboxTopicMessageSent       = "message.sent"
+	outboxTopicModerationReport  = "moderation.report"
+)
+
+// Outbox dispatch settings
+const (
+	defaultOutboxMaxAttempts = 8
+	defaultOutboxRetention   = 7 * 24 * time.Hour
+	outboxLease              = time.Minute // How long a claimed event is hidden from other dispatchers
+	outboxMaxBackoff         = 10 * time.Minute
+	outboxPruneInterval      = time.Hour
+)
+
+// OutboxEvent is a side effect recorded in the same transaction as the change that
+// caused it, delivered to its topic's consumers after commit
+type OutboxEvent struct {
+	ID        int64
+	Topic     string
+	Payload   json.RawMessage
+	Attempts  int // Including the current one
+	CreatedAt time.Time
+}
+
+// OutboxHandler handles events on a topic for one consumer. Delivery is at least once:
+// an event whose dispatcher dies mid-delivery is delivered again, so handlers must
+// tolerate repeats. Returning an error retries the event with backoff.
+type OutboxHandler func(ctx context.Context, event OutboxEvent) error
+
+// outboxConsumer is a handler registered for a topic
+type outboxConsumer struct {
+	name   string
+	handle OutboxHandler
+}
+
+// OutboxDispatcher delivers outbox events to the consumers registered for their topic.
+// Consumers that have handled an event are recorded, so a retry after another consumer
+// failed doesn't repeat their side effects. Events that keep failing are dead-lettered.
+type OutboxDispatcher struct {
+	BaseService
+	mu          sync.RWMutex
+	consumers   map[string][]outboxConsumer // Keyed by topic
+	maxAttempts int
+	retention   time.Duration
+	wake        chan struct{}
+}
+
+// NewOutboxDispatcher creates a new outbox dispatcher
+func NewOutboxDispatcher(db *sql.DB) *OutboxDispatcher {
+	config := utils.NewConfig()
+	return &OutboxDispatcher{
+		BaseService: NewBaseService(db),
+		consumers:   map[string][]outboxConsumer{},
+		maxAttempts: config.GetEnvInt("OUTBOX_MAX_ATTEMPTS", defaultOutboxMaxAttempts),
+		retention:   config.GetEnvDuration("OUTBOX_RETENTION", defaultOutboxRetention),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Register adds a consumer for a topic. Consumer names are recorded with each event they
+// handle, so keep them stable.
+func (d *OutboxDispatcher) Register(topic string, consumer string, handler OutboxHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.consumers[topic] = append(d.consumers[topic], outboxConsumer{name: consumer, handle: handler})
+}
+
+// Wake asks the dispatcher to deliver pending events now instead of at its next poll.
+// Call it after committing a transaction that enqueued events.
+func (d *OutboxDispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueueOutbox records an event in the outbox as part of tx, so it is delivered if and
+// only if tx commits
+func enqueueOutbox(ctx context.Context, tx *sql.Tx, topic string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox_events (topic, payload, created_at, available_at)
+		VALUES ($1, $2, NOW(), NOW())
+	`, topic, string(payloadJSON))
+	return err
+}
+
+// Dispatch delivers the pending events that are due, oldest first, until none are left.
+// Each event is leased to this dispatcher while it is delivered, so several instances
+// can dispatch at once. Returns the number of events delivered.
+func (d *OutboxDispatcher) Dispatch(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		event, err := d.claim(ctx)
+		if err != nil {
+			return delivered, err
+		}
+		if event == nil {
+			return delivered, nil
+		}
+
+		if err := d.deliver(ctx, *event); err != nil {
+			d.fail(ctx, *event, err)
+			continue
+		}
+		if _, err := d.GetDB().ExecContext(ctx, `
+			UPDATE outbox_events SET status = $2, processed_at = NOW(), last_error = NULL WHERE id = $1
+		`, event.ID, outboxStatusDone); err != nil {
+			log.Printf("Failed to mark outbox event %d done: %v", event.ID, err)
+			continue
+		}
+		delivered++
+	}
+}
+
+// claim leases the oldest pending event that is due, counting the attempt. Events are
+// claimed one at a time so the lease only has to cover a single delivery. Returns nil
+// when no event is due.
+func (d *OutboxDispatcher) claim(ctx context.Context) (*OutboxEvent, error) {
+	now := time.Now()
+	var event OutboxEvent
+	var payload []byte
+	err := d.GetDB().QueryRowContext(ctx, `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, available_at = $2
+		WHERE id = (
+			SELECT id FROM outbox_events
+			WHERE status = $1 AND available_at <= $3
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, payload, attempts, created_at
+	`, outboxStatusPending, now.Add(outboxLease), now).Scan(&event.ID, &event.Topic, &payload, &event.Attempts, &event.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	event.Payload = payload
+	return &event, nil
+}
+
+// deliver hands an event to each consumer of its topic that hasn't handled it yet
+func (d *OutboxDispatcher) deliver(ctx context.Context, event OutboxEvent) error {
+	d.mu.RLock()
+	consumers := d.consumers[event.Topic]
+	d.mu.RUnlock()
+
+	if len(consumers) == 0 {
+		log.Printf("No consumers for outbox topic %q, dropping event %d", event.Topic, event.ID)
+		return nil
+	}
+
+	// Leave time to record the outcome before the lease runs out
+	ctx, cancel := context.WithTimeout(ctx, outboxLease-10*time.Second)
+	defer cancel()
+
+	db := d.GetDB()
+	for _, consumer := range consumers {
+		var consumed bool
+		err := db.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM outbox_consumed WHERE event_id = $1 AND consumer = $2)
+		`, event.ID, consumer.name).Scan(&consumed)
+		if err != nil {
+			return err
+		}
+		if consumed {
+			continue
+		}
+
+		if err := consumer.handle(ctx, event); err != nil {
+			return &outboxConsumerError{consumer: consumer.name, err: err}
+		}
+
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO outbox_consumed (event_id, consumer, consumed_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT DO NOTHING
+		`, event.ID, consumer.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fail schedules a failed event for another attempt with exponential backoff, or
+// dead-letters it once it has used up its attempts
+func (d *OutboxDispatcher) fail(ctx context.Context, event OutboxEvent, cause error) {
+	if event.Attempts >= d.maxAttempts {
+		log.Printf("Outbox event %d (%s) failed %d times, dead-lettering it: %v", event.ID, event.Topic, event.Attempts, cause)
+		_, err := d.GetDB().ExecContext(ctx, `
+			UPDATE outbox_events SET status = $2, last_error = $3, processed_at = NOW() WHERE id = $1
+		`, event.ID, outboxStatusDead, cause.Error())
+		if err != nil {
+			log.Printf("Failed to dead-letter outbox event %d: %v", event.ID, err)
+		}
+		return
+	}
+
+	backoff := outboxBackoff(event.Attempts)
+	log.Printf("Outbox event %d (%s) failed, retrying in %s: %v", event.ID, event.Topic, backoff, cause)
+
+	_, err := d.GetDB().ExecContext(ctx, `
+		UPDATE outbox_events SET available_at = $2, last_error = $3 WHERE id = $1
+	`, event.ID, time.Now().Add(backoff), cause.Error())
+	if err != nil {
+		log.Printf("Failed to reschedule outbox event %d: %v", event.ID, err)
+	}
+}
+
+// outboxBackoff is how long to wait before retrying an event that has failed attempts
+// times, doubling from 2s up to outboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	backoff := time.Second << uint(attempts)
+	if backoff > outboxMaxBackoff || backoff <= 0 {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
+
+// PruneOutbox deletes delivered events older than the retention period, along with the
+// notification deliveries recorded for them. Dead-lettered events are kept until someone
+// looks at them.
+func (d *OutboxDispatcher) PruneOutbox(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-d.retention)
+
+	_, err := d.GetDB().ExecContext(ctx, `
+		DELETE FROM notification_deliveries WHERE delivered_at < $1
+	`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := d.GetDB().ExecContext(ctx, `
+		DELETE FROM outbox_events WHERE status = $1 AND processed_at < $2
+	`, outboxStatusDone, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Run dispatches pending events every interval, and straight away when woken, until ctx
+// is cancelled
+func (d *OutboxDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	pruneTicker := time.NewTicker(outboxPruneInterval)
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pruneTicker.C:
+			pruned, err := d.PruneOutbox(ctx)
+			if err != nil {
+				log.Printf("Failed to prune outbox: %v", err)
+			}
+			if pruned > 0 {
+				log.Printf("Pruned %d delivered outbox events", pruned)
+			}
+			continue
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		if _, err := d.Dispatch(ctx); err != nil {
+			log.Printf("Failed to dispatch outbox events: %v", err)
+		}
+	}
+}
+
+// outboxConsumerError is a consumer's failure to handle an event
+type outboxConsumerError struct {
+	consumer string
+	err      error
+}
+
+func (e *outboxConsumerError) Error() string {
+	return e.consumer + ": " + e.err.Error()
+}
+
+func (e *outboxConsumerError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{5, 32 * time.Second},
+		{9, 512 * time.Second},
+		{10, outboxMaxBackoff},
+		{70, outboxMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// outboxRow is the dispatch state of an outbox event
+type outboxRow struct {
+	status      string
+	attempts    int
+	availableAt time.Time
+	lastError   sql.NullString
+}
+
+// enqueueTestEvent records an event on topic and returns its id
+func enqueueTestEvent(t *testing.T, db *sql.DB, topic string) int64 {
+	t.Helper()
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := enqueueOutbox(ctx, tx, topic, map[string]string{"test": t.Name()}); err != nil {
+		t.Fatal(err)
+	}
+	var eventID int64
+	if err := tx.QueryRowContext(ctx, `SELECT currval('outbox_events_id_seq')`).Scan(&eventID); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM outbox_events WHERE id = $1`, eventID) })
+	return eventID
+}
+
+func getOutboxRow(t *testing.T, db *sql.DB, eventID int64) outboxRow {
+	t.Helper()
+	var row outboxRow
+	err := db.QueryRow(`
+		SELECT status, attempts, available_at, last_error FROM outbox_events WHERE id = $1
+	`, eventID).Scan(&row.status, &row.attempts, &row.availableAt, &row.lastError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return row
+}
+
+// makeDue makes a rescheduled event available again straight away
+func makeDue(t *testing.T, db *sql.DB, eventID int64) {
+	t.Helper()
+	if _, err := db.Exec(`UPDATE outbox_events SET available_at = NOW() WHERE id = $1`, eventID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutboxDispatch(t *testing.T) {
+	db, _ := openTestDB(t)
+	ctx := context.Background()
+	errConsumer := errors.New("consumer failed")
+
+	t.Run("retry with backoff", func(t *testing.T) {
+		d := NewOutboxDispatcher(db)
+		d.Register("test.retry", "failing", func(ctx context.Context, event OutboxEvent) error {
+			return errConsumer
+		})
+		eventID := enqueueTestEvent(t, db, "test.retry")
+
+		before := time.Now()
+		if _, err := d.Dispatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+		after := time.Now()
+
+		row := getOutboxRow(t, db, eventID)
+		if row.status != outboxStatusPending || row.attempts != 1 {
+			t.Errorf("event is %s after %d attempts, want pending after 1", row.status, row.attempts)
+		}
+		backoff := outboxBackoff(1)
+		if row.availableAt.Before(before.Add(backoff)) || row.availableAt.After(after.Add(backoff)) {
+			t.Errorf("event available at %s, want %s after the attempt", row.availableAt, backoff)
+		}
+		if row.lastError.String != "failing: consumer failed" {
+			t.Errorf("last_error = %q, want the consumer's error", row.lastError.String)
+		}
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		d := NewOutboxDispatcher(db)
+		d.maxAttempts = 3
+		d.Register("test.dead", "failing", func(ctx context.Context, event OutboxEvent) error {
+			return errConsumer
+		})
+		eventID := enqueueTestEvent(t, db, "test.dead")
+
+		for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+			if _, err := d.Dispatch(ctx); err != nil {
+				t.Fatal(err)
+			}
+			row := getOutboxRow(t, db, eventID)
+			wantStatus := outboxStatusPending
+			if attempt == d.maxAttempts {
+				wantStatus = outboxStatusDead
+			}
+			if row.status != wantStatus || row.attempts != attempt {
+				t.Fatalf("event is %s after %d attempts, want %s after %d", row.status, row.attempts, wantStatus, attempt)
+			}
+			makeDue(t, db, eventID)
+		}
+
+		// Dead events aren't claimed again
+		if _, err := d.Dispatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if row := getOutboxRow(t, db, eventID); row.attempts != d.maxAttempts {
+			t.Errorf("dead event was attempted %d times, want %d", row.attempts, d.maxAttempts)
+		}
+	})
+
+	t.Run("skip consumed", func(t *testing.T) {
+		var firstCalls, secondCalls int
+		d := NewOutboxDispatcher(db)
+		d.Register("test.consumed", "first", func(ctx context.Context, event OutboxEvent) error {
+			firstCalls++
+			return nil
+		})
+		d.Register("test.consumed", "second", func(ctx context.Context, event OutboxEvent) error {
+			secondCalls++
+			if secondCalls == 1 {
+				return errConsumer
+			}
+			return nil
+		})
+		eventID := enqueueTestEvent(t, db, "test.consumed")
+
+		if _, err := d.Dispatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+		makeDue(t, db, eventID)
+		if _, err := d.Dispatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if firstCalls != 1 || secondCalls != 2 {
+			t.Errorf("consumers called %d and %d times, want 1 and 2", firstCalls, secondCalls)
+		}
+		if row := getOutboxRow(t, db, eventID); row.status != outboxStatusDone || row.attempts != 2 {
+			t.Errorf("event is %s after %d attempts, want done after 2", row.status, row.attempts)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/imaging"
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// isBannedPhoto reports whether an image with this hash has been banned by a moderator
+func (s *PhotoService) isBannedPhoto(ctx context.Context, hash uint64) (bool, error) {
+	var banned bool
+	err := s.GetDB().QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM banned_photo_hashes WHERE dhash = $1)
+	`, int64(hash)).Scan(&banned)
+	return banned, err
+}
+
+// reportBannedUpload tells moderation someone tried to upload a banned image. Reporting
+// is best effort, failures are only logged.
+func (s *PhotoService) reportBannedUpload(ctx context.Context, userID string, hash uint64) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	_, err := s.moderationService.Report(ctx, models.ModerationReport{
+		SubjectType:   models.ModerationSubjectPhoto,
+		SubjectUserID: userID,
+		Content:       fmt.Sprintf("Upload of banned image %016x blocked", hash),
+		Source:        photoHashSource,
+		Action:        models.SafetyBlock,
+		Score:         1,
+		Labels:        []string{"banned_photo"},
+	})
+	if err != nil {
+		log.Printf("Failed to report banned photo upload by user %s to moderation: %v", userID, err)
+	}
+}
+
+// queueDuplicateReports finds photos on other profiles whose hash is within the
+// duplicate distance of a new photo and queues a moderation report for each profile,
+// closest first. Reports whether any were queued.
+func (s *PhotoService) queueDuplicateReports(ctx context.Context, tx *sql.Tx, userID string, hash uint64, photo *models.Photo) (bool, error) {
+	if s.outbox == nil || s.duplicateDistance < 0 {
+		return false, nil
+	}
+
+	// Candidates share at least one 8-bit band with the hash, see the photo hash indexes
+	h := int64(hash)
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, profile_id, dhash
+		FROM photos
+		WHERE dhash IS NOT NULL
+		  AND profile_id <> $1
+		  AND (((dhash >> 56) & 255) = (($2::BIGINT >> 56) & 255)
+		    OR ((dhash >> 48) & 255) = (($2::BIGINT >> 48) & 255)
+		    OR ((dhash >> 40) & 255) = (($2::BIGINT >> 40) & 255)
+		    OR ((dhash >> 32) & 255) = (($2::BIGINT >> 32) & 255)
+		    OR ((dhash >> 24) & 255) = (($2::BIGINT >> 24) & 255)
+		    OR ((dhash >> 16) & 255) = (($2::BIGINT >> 16) & 255)
+		    OR ((dhash >> 8) & 255) = (($2::BIGINT >> 8) & 255)
+		    OR (dhash & 255) = ($2::BIGINT & 255))
+	`, photo.ProfileID, h)
+	if err != nil {
+		return false, err
+	}
+
+	type duplicate struct {
+		photoID   int64
+		profileID string
+		distance  int
+	}
+	closest := map[string]duplicate{} // Keyed by profile
+	for rows.Next() {
+		var d duplicate
+		var other int64
+		if err := rows.Scan(&d.photoID, &d.profileID, &other); err != nil {
+			rows.Close()
+			return false, err
+		}
+		d.distance = imaging.HashDistance(hash, uint64(other))
+		if d.distance > s.duplicateDistance {
+			continue
+		}
+		if current, ok := closest[d.profileID]; !ok || d.distance < current.distance {
+			closest[d.profileID] = d
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	queued := 0
+	for distance := 0; distance <= s.duplicateDistance && queued < maxPhotoDuplicateReports; distance++ {
+		for _, d := range closest {
+			if d.distance != distance || queued >= maxPhotoDuplicateReports {
+				continue
+			}
+			photoID := photo.ID
+			err := enqueueOutbox(ctx, tx, outboxTopicModerationReport, models.ModerationReport{
+				SubjectType:   models.ModerationSubjectPhoto,
+				SubjectUserID: userID,
+				PhotoID:       &photoID,
+				Content:       fmt.Sprintf("Photo %d (%s) looks like photo %d on profile %s, %d bits apart", photo.ID, photo.URL, d.photoID, d.profileID, d.distance),
+				Source:        photoHashSource,
+				Action:        models.SafetyAllow,
+				Score:         1 - float64(d.distance)/64,
+				Labels:        []string{"duplicate_photo"},
+			})
+			if err != nil {
+				return false, err
+			}
+			queued++
+		}
+	}
+
+	return queued > 0, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math/bits"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// hashBand is band i of a photo hash, as indexed by the photo hash migration
+func hashBand(hash uint64, i int) uint8 {
+	return uint8(hash >> (56 - 8*i))
+}
+
+// flipBits flips n bits of a hash, spread over as many bands as possible
+func flipBits(hash uint64, n int, rng *rand.Rand) uint64 {
+	for i, band := range rng.Perm(8)[:min(n, 8)] {
+		bit := 8*band + rng.Intn(8)
+		hash ^= 1 << bit
+		// More than 8 flips double up within bands
+		if n > 8 && i < n-8 {
+			hash ^= 1 << (8*band + (bit+1)%8)
+		}
+	}
+	return hash
+}
+
+func TestHashBandsFindNearDuplicates(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	shareBand := func(a uint64, b uint64) bool {
+		for i := 0; i < 8; i++ {
+			if hashBand(a, i) == hashBand(b, i) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Hashes up to maxPhotoDuplicateDistance apart always share a band, even with the
+	// differences spread out as much as possible
+	for distance := 0; distance <= maxPhotoDuplicateDistance; distance++ {
+		for i := 0; i < 1000; i++ {
+			a := rng.Uint64()
+			b := flipBits(a, distance, rng)
+			if got := bits.OnesCount64(a ^ b); got != distance {
+				t.Fatalf("flipped %d bits, want %d", got, distance)
+			}
+			if !shareBand(a, b) {
+				t.Fatalf("%016x and %016x are %d bits apart but share no band", a, b, distance)
+			}
+		}
+	}
+
+	// One more and a bit in every band can differ, which is why the distance is capped
+	a := rng.Uint64()
+	if b := a ^ 0x0101010101010101; shareBand(a, b) {
+		t.Fatalf("%016x and %016x differ in every band but share one", a, b)
+	}
+}
+
+// createTestProfile creates a profile for a user
+func createTestProfile(t *testing.T, db *sql.DB, userID string) string {
+	t.Helper()
+	var profileID string
+	err := db.QueryRow(`
+		INSERT INTO profiles (id, user_id, name, date_of_birth, gender)
+		VALUES (gen_random_uuid(), $1, 'Test', '1995-06-01', 'woman')
+		RETURNING id
+	`, userID).Scan(&profileID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profileID
+}
+
+func TestQueueDuplicateReports(t *testing.T) {
+	db, _ := openTestDB(t)
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(2))
+
+	svc := NewPhotoService(db, nil)
+	svc.SetOutbox(NewOutboxDispatcher(db))
+	svc.duplicateDistance = maxPhotoDuplicateDistance
+
+	uploaderID := createTestUser(t, db)
+	photo := &models.Photo{ID: 1, ProfileID: createTestProfile(t, db, uploaderID), URL: "https://photos.example/new.jpg"}
+	hash := rng.Uint64()
+
+	// One profile per distance, with the differing bits spread over the bands
+	distances := map[string]int{}
+	for distance := 0; distance <= maxPhotoDuplicateDistance+2; distance++ {
+		profileID := createTestProfile(t, db, createTestUser(t, db))
+		_, err := db.Exec(`
+			INSERT INTO photos (profile_id, url, is_primary, position, dhash)
+			VALUES ($1, 'https://photos.example/other.jpg', true, 0, $2)
+		`, profileID, int64(flipBits(hash, distance, rng)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		distances[profileID] = distance
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := svc.queueDuplicateReports(ctx, tx, uploaderID, hash, photo); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT payload FROM outbox_events
+		WHERE topic = $1 AND status = 'pending' AND payload->>'subject_user_id' = $2
+	`, outboxTopicModerationReport, uploaderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	reported := map[string]bool{}
+	for rows.Next() {
+		var payload []byte
+		var report models.ModerationReport
+		if err := rows.Scan(&payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(payload, &report); err != nil {
+			t.Fatal(err)
+		}
+		for profileID := range distances {
+			if strings.Contains(report.Content, profileID) {
+				reported[profileID] = true
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for profileID, distance := range distances {
+		if want := distance <= maxPhotoDuplicateDistance; reported[profileID] != want {
+			t.Errorf("photo %d bits away reported = %v, want %v", distance, reported[profileID], want)
+		}
+	}
+}
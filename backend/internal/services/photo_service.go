@@ -0,0 +1,528 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/vibe-code-hinge/backend/internal/imaging"
+	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/storage"
+	"github.com/vibe-code-hinge/backend/internal/utils"
+)
+
+// MaxPhotoSize is the largest photo upload accepted
+const MaxPhotoSize = 15 << 20
+
+// Photo limits
+const (
+	maxPhotosPerProfile = 6
+	minPhotoSide        = 320
+	maxPhotoSide        = 10000
+	maxPhotoPixels      = 40000000 // Decoding needs 4 bytes a pixel
+	photoJPEGQuality    = 85
+)
+
+// Duplicate photo detection. Hashes are indexed in eight 8-bit bands, so only distances
+// up to 7 are guaranteed to be found.
+const (
+	defaultPhotoDuplicateDistance = 6
+	maxPhotoDuplicateDistance     = 7
+	maxPhotoDuplicateReports      = 10 // Profiles reported per upload
+	photoHashSource               = "photo_hash"
+)
+
+// photoVariants are the resized copies stored for each upload. Photo.URL is the last one.
+var photoVariants = []struct {
+	name    string
+	maxSide int
+}{
+	{"thumb", 200},
+	{"medium", 640},
+	{"large", 1440},
+}
+
+// photoColumns is the column list scanned by scanPhoto
+const photoColumns = `id, profile_id, url, is_primary, position, COALESCE(width, 0), COALESCE(height, 0), variants, created_at`
+
+// photoOrder is the order photos are listed in, the primary photo first
+const photoOrder = `is_primary DESC, position ASC, id ASC`
+
+// Errors returned by the photo service
+var (
+	ErrPhotoNotFound        = errors.New("photo not found")
+	ErrUnsupportedPhoto     = errors.New("photo must be a JPEG, PNG or GIF image")
+	ErrPhotoTooLarge        = errors.New("photo is too large")
+	ErrInvalidPhotoSize     = fmt.Errorf("photo must be between %d and %d pixels on each side", minPhotoSide, maxPhotoSide)
+	ErrTooManyPhotos        = fmt.Errorf("a profile can have at most %d photos", maxPhotosPerProfile)
+	ErrInvalidPhotoOrder    = errors.New("photo_ids must list every photo on the profile exactly once")
+	ErrPhotoProfileRequired = errors.New("create a profile before adding photos")
+	ErrPhotoBanned          = errors.New("this photo can't be used")
+)
+
+// scanPhoto scans a row selected with photoColumns
+func scanPhoto(row rowScanner, photo *models.Photo) error {
+	var variants []byte
+	err := row.Scan(
+		&photo.ID,
+		&photo.ProfileID,
+		&photo.URL,
+		&photo.IsPrimary,
+		&photo.Position,
+		&photo.Width,
+		&photo.Height,
+		&variants,
+		&photo.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	photo.Variants = nil
+	if len(variants) > 0 {
+		if err := json.Unmarshal(variants, &photo.Variants); err != nil {
+			return err
+		}
+		if len(photo.Variants) == 0 {
+			photo.Variants = nil
+		}
+	}
+	return nil
+}
+
+// PhotoService handles profile photo uploads. Each upload is checked, turned upright,
+// stripped of its metadata and stored as JPEG variants in a BlobStore.
+type PhotoService struct {
+	BaseService
+	store             storage.BlobStore
+	moderationService *ModerationService
+	outbox            *OutboxDispatcher
+	urlBase           string
+	duplicateDistance int // Hashes at most this many bits apart are near duplicates
+}
+
+// NewPhotoService creates a new PhotoService storing photos in store
+func NewPhotoService(db *sql.DB, store storage.BlobStore) *PhotoService {
+	config := utils.NewConfig()
+	distance := config.GetEnvInt("PHOTO_DUPLICATE_DISTANCE", defaultPhotoDuplicateDistance)
+	if distance > maxPhotoDuplicateDistance {
+		log.Printf("PHOTO_DUPLICATE_DISTANCE can be at most %d, using that", maxPhotoDuplicateDistance)
+		distance = maxPhotoDuplicateDistance
+	}
+
+	return &PhotoService{
+		BaseService:       NewBaseService(db),
+		store:             store,
+		moderationService: NewModerationService(db),
+		urlBase:           strings.TrimSuffix(config.GetEnv("PHOTO_URL_BASE", "/api/v1"), "/"),
+		duplicateDistance: distance,
+	}
+}
+
+// SetOutbox sets the outbox that duplicate photo reports are queued in. Without one,
+// near duplicates aren't reported.
+func (s *PhotoService) SetOutbox(outbox *OutboxDispatcher) {
+	s.outbox = outbox
+}
+
+// GetPhotos lists the photos on the user's profile, the primary photo first
+func (s *PhotoService) GetPhotos(ctx context.Context, userID string) ([]models.Photo, error) {
+	rows, err := s.GetDB().QueryContext(ctx, `
+		SELECT `+photoColumns+`
+		FROM photos
+		WHERE profile_id = (SELECT id FROM profiles WHERE user_id = $1)
+		ORDER BY `+photoOrder, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	photos := []models.Photo{}
+	for rows.Next() {
+		var photo models.Photo
+		if err := scanPhoto(rows, &photo); err != nil {
+			return nil, err
+		}
+		photos = append(photos, photo)
+	}
+	return photos, rows.Err()
+}
+
+// UploadPhoto adds a photo to the user's profile. The image must be a JPEG, PNG or GIF
+// within the size limits. It is turned upright according to its EXIF orientation and
+// re-encoded, which drops EXIF and GPS metadata, in every variant size. The first photo
+// on a profile becomes its primary photo. Images a moderator has banned are rejected, and
+// near duplicates of photos on other profiles are reported to moderation.
+func (s *PhotoService) UploadPhoto(ctx context.Context, userID string, body io.Reader, size int64) (*models.Photo, error) {
+	if size > MaxPhotoSize {
+		return nil, ErrPhotoTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(body, MaxPhotoSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxPhotoSize {
+		return nil, ErrPhotoTooLarge
+	}
+
+	// Check the header before decoding, so huge images aren't decoded at all
+	_, width, height, err := imaging.Inspect(data)
+	if err != nil {
+		return nil, ErrUnsupportedPhoto
+	}
+	if !validPhotoSize(width, height) {
+		return nil, ErrInvalidPhotoSize
+	}
+
+	db := s.GetDB()
+	var profileID string
+	err = db.QueryRowContext(ctx, `SELECT id FROM profiles WHERE user_id = $1`, userID).Scan(&profileID)
+	if err == sql.ErrNoRows {
+		return nil, ErrPhotoProfileRequired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM photos WHERE profile_id = $1`, profileID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= maxPhotosPerProfile {
+		return nil, ErrTooManyPhotos
+	}
+
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, ErrUnsupportedPhoto
+	}
+
+	hash := imaging.DHash(img)
+	banned, err := s.isBannedPhoto(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if banned {
+		s.reportBannedUpload(ctx, userID, hash)
+		return nil, ErrPhotoBanned
+	}
+
+	random, err := utils.GenerateRandomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	key := hex.EncodeToString(random)
+	prefix := photoKeyPrefix(profileID, key)
+
+	photo := &models.Photo{
+		ProfileID: profileID,
+		Width:     img.Bounds().Dx(),
+		Height:    img.Bounds().Dy(),
+		Variants:  make(map[string]string, len(photoVariants)),
+	}
+	var stored []string
+	for _, variant := range photoVariants {
+		encoded, err := imaging.EncodeJPEG(imaging.Fit(img, variant.maxSide), photoJPEGQuality)
+		if err != nil {
+			s.deleteBlobs(ctx, stored)
+			return nil, err
+		}
+		variantKey := prefix + "/" + variant.name + ".jpg"
+		if err := s.store.Put(ctx, variantKey, bytes.NewReader(encoded), int64(len(encoded)), "image/jpeg"); err != nil {
+			s.deleteBlobs(ctx, stored)
+			return nil, err
+		}
+		stored = append(stored, variantKey)
+		photo.Variants[variant.name] = s.photoURL(profileID, key, variant.name)
+		photo.URL = photo.Variants[variant.name]
+	}
+
+	reported, err := s.insertPhoto(ctx, userID, prefix, hash, photo)
+	if err != nil {
+		s.deleteBlobs(ctx, stored)
+		return nil, err
+	}
+	if reported {
+		s.outbox.Wake()
+	}
+	return photo, nil
+}
+
+// validPhotoSize reports whether a photo's dimensions are within the upload limits
+func validPhotoSize(width int, height int) bool {
+	return width >= minPhotoSide && height >= minPhotoSide &&
+		width <= maxPhotoSide && height <= maxPhotoSide &&
+		width*height <= maxPhotoPixels
+}
+
+// insertPhoto records an uploaded photo at the end of the profile's photos, as its
+// primary photo if it is the first, and queues reports for near duplicates on other
+// profiles. Reports whether any were queued.
+func (s *PhotoService) insertPhoto(ctx context.Context, userID string, prefix string, hash uint64, photo *models.Photo) (bool, error) {
+	tx, err := s.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	profileID, err := lockPhotoProfile(ctx, tx, userID)
+	if err != nil {
+		return false, err
+	}
+	if profileID != photo.ProfileID {
+		// The profile was replaced while the photo was being processed
+		return false, ErrPhotoProfileRequired
+	}
+
+	// Checked again under the lock, uploads can race
+	var count, nextPosition int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(MAX(position) + 1, 0) FROM photos WHERE profile_id = $1
+	`, profileID).Scan(&count, &nextPosition)
+	if err != nil {
+		return false, err
+	}
+	if count >= maxPhotosPerProfile {
+		return false, ErrTooManyPhotos
+	}
+
+	variants, err := json.Marshal(photo.Variants)
+	if err != nil {
+		return false, err
+	}
+	photo.IsPrimary = count == 0
+	photo.Position = nextPosition
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO photos (profile_id, url, is_primary, position, width, height, storage_key, variants, dhash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING id, created_at
+	`, profileID, photo.URL, photo.IsPrimary, photo.Position, photo.Width, photo.Height, prefix, string(variants), int64(hash)).Scan(&photo.ID, &photo.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	reported, err := s.queueDuplicateReports(ctx, tx, userID, hash, photo)
+	if err != nil {
+		return false, err
+	}
+
+	return reported, tx.Commit()
+}
+
+// DeletePhoto removes a photo from the user's profile along with its stored variants.
+// Deleting the primary photo makes the next photo in order the primary one.
+func (s *PhotoService) DeletePhoto(ctx context.Context, userID string, photoID int64) error {
+	tx, err := s.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	profileID, err := lockPhotoProfile(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	var wasPrimary bool
+	var prefix string
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM photos
+		WHERE id = $1 AND profile_id = $2
+		RETURNING is_primary, COALESCE(storage_key, '')
+	`, photoID, profileID).Scan(&wasPrimary, &prefix)
+	if err == sql.ErrNoRows {
+		return ErrPhotoNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if wasPrimary {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE photos SET is_primary = true
+			WHERE id = (SELECT id FROM photos WHERE profile_id = $1 ORDER BY position ASC, id ASC LIMIT 1)
+		`, profileID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Uploaded photos have variants to delete, ones added by URL don't
+	if prefix != "" {
+		keys := make([]string, len(photoVariants))
+		for i, variant := range photoVariants {
+			keys[i] = prefix + "/" + variant.name + ".jpg"
+		}
+		s.deleteBlobs(ctx, keys)
+	}
+	return nil
+}
+
+// ReorderPhotos puts the user's photos in the given order, which must list each of them
+// exactly once. The primary photo stays first when photos are listed.
+func (s *PhotoService) ReorderPhotos(ctx context.Context, userID string, photoIDs []int64) ([]models.Photo, error) {
+	tx, err := s.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	profileID, err := lockPhotoProfile(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var current []int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(array_agg(id), '{}') FROM photos WHERE profile_id = $1
+	`, profileID).Scan(pq.Array(&current))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(photoIDs) != len(current) {
+		return nil, ErrInvalidPhotoOrder
+	}
+	owned := make(map[int64]bool, len(current))
+	for _, id := range current {
+		owned[id] = true
+	}
+	for _, id := range photoIDs {
+		if !owned[id] {
+			return nil, ErrInvalidPhotoOrder
+		}
+		delete(owned, id)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE photos SET position = array_position($2::BIGINT[], id) - 1
+		WHERE profile_id = $1
+	`, profileID, pq.Array(photoIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetPhotos(ctx, userID)
+}
+
+// SetPrimaryPhoto makes a photo the primary photo on the user's profile. A profile has
+// at most one primary photo, the previous one stops being primary.
+func (s *PhotoService) SetPrimaryPhoto(ctx context.Context, userID string, photoID int64) ([]models.Photo, error) {
+	tx, err := s.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	profileID, err := lockPhotoProfile(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM photos WHERE id = $1 AND profile_id = $2)
+	`, photoID, profileID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrPhotoNotFound
+	}
+
+	// Clear the old primary first, the unique index is checked row by row
+	_, err = tx.ExecContext(ctx, `
+		UPDATE photos SET is_primary = false WHERE profile_id = $1 AND is_primary = true AND id <> $2
+	`, profileID, photoID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE photos SET is_primary = true WHERE id = $1`, photoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetPhotos(ctx, userID)
+}
+
+// OpenPhoto opens a stored photo variant for serving. Photo URLs are unguessable and
+// photos are shown to other users anyway, so no signature is needed.
+func (s *PhotoService) OpenPhoto(ctx context.Context, profileID string, key string, variant string) (io.ReadCloser, error) {
+	known := false
+	for _, v := range photoVariants {
+		if v.name == variant {
+			known = true
+			break
+		}
+	}
+	if !known || !isPhotoKeyPart(profileID) || !isPhotoKeyPart(key) {
+		return nil, ErrPhotoNotFound
+	}
+
+	body, err := s.store.Get(ctx, photoKeyPrefix(profileID, key)+"/"+variant+".jpg")
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, ErrPhotoNotFound
+	}
+	return body, err
+}
+
+// photoURL returns the URL a photo variant is served from
+func (s *PhotoService) photoURL(profileID string, key string, variant string) string {
+	return s.urlBase + "/photos/" + profileID + "/" + key + "/" + variant
+}
+
+// deleteBlobs removes stored variants, logging failures since the rows are already gone
+func (s *PhotoService) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete photo blob %s: %v", key, err)
+		}
+	}
+}
+
+// lockPhotoProfile locks the user's profile row so photo changes on it are serialized,
+// returning its id
+func lockPhotoProfile(ctx context.Context, tx *sql.Tx, userID string) (string, error) {
+	var profileID string
+	err := tx.QueryRowContext(ctx, `SELECT id FROM profiles WHERE user_id = $1 FOR UPDATE`, userID).Scan(&profileID)
+	if err == sql.ErrNoRows {
+		return "", ErrPhotoProfileRequired
+	}
+	return profileID, err
+}
+
+// photoKeyPrefix returns the storage key prefix of an uploaded photo's variants
+func photoKeyPrefix(profileID string, key string) string {
+	return "photos/" + profileID + "/" + key
+}
+
+// isPhotoKeyPart reports whether a URL path segment can be part of a photo storage key
+func isPhotoKeyPart(part string) bool {
+	if part == "" {
+		return false
+	}
+	for _, c := range part {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
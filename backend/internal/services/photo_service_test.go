@@ -0,0 +1,26 @@
+package services
+
+import "testing"
+
+func TestValidPhotoSize(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          bool
+	}{
+		{minPhotoSide, minPhotoSide, true},
+		{1080, 1920, true},
+		{minPhotoSide - 1, 1000, false},
+		{1000, minPhotoSide - 1, false},
+		{maxPhotoSide, 4000, true},
+		{maxPhotoSide + 1, 1000, false},
+		{1000, maxPhotoSide + 1, false},
+		{maxPhotoSide, maxPhotoSide, false}, // Too many pixels to decode
+		{8000, 5000, true},
+		{8000, 5001, false},
+	}
+	for _, tt := range tests {
+		if got := validPhotoSize(tt.width, tt.height); got != tt.want {
+			t.Errorf("validPhotoSize(%d, %d) = %v, want %v", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
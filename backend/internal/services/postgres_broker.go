@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// eventsChannel is the Postgres NOTIFY channel shared by all API instances
+const eventsChannel = "vibe_events"
+
+// maxNotifyPayload keeps NOTIFY payloads under Postgres' 8000 byte limit. Larger events
+// are sent by reference and loaded from the event log by the receiving instances.
+const maxNotifyPayload = 7000
+
+// listenerPingInterval is how often an idle listener connection is checked
+const listenerPingInterval = 90 * time.Second
+
+// brokerMessage is the NOTIFY payload for one event
+type brokerMessage struct {
+	UserID string          `json:"user_id"`
+	ID     int64           `json:"id,omitempty"`
+	Stream EventStream     `json:"stream"`
+	Name   string          `json:"name"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// PostgresBroker is a Broker that fans events out to every instance using Postgres
+// LISTEN/NOTIFY
+type PostgresBroker struct {
+	db       *sql.DB
+	listener *pq.Listener
+	mu       sync.RWMutex
+	handlers []BrokerHandler
+	done     chan struct{}
+	close    sync.Once
+}
+
+// NewPostgresBroker creates a new PostgresBroker. db is used to send notifications and
+// dsn opens the dedicated connection that listens for them.
+func NewPostgresBroker(db *sql.DB, dsn string) (*PostgresBroker, error) {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Event broker listener error: %v", err)
+		}
+	})
+	if err := listener.Listen(eventsChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	b := &PostgresBroker{
+		db:       db,
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+	go b.run()
+
+	return b, nil
+}
+
+// Publish notifies every instance of the event
+func (b *PostgresBroker) Publish(ctx context.Context, userID string, event Event) error {
+	msg := brokerMessage{
+		UserID: userID,
+		ID:     event.ID,
+		Stream: event.Stream,
+		Name:   event.Name,
+		Data:   event.Data,
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxNotifyPayload {
+		if event.ID == 0 {
+			return errors.New("event too large to publish")
+		}
+		// Send by reference, receivers load the data from the event log
+		msg.Data = nil
+		if payload, err = json.Marshal(msg); err != nil {
+			return err
+		}
+	}
+
+	_, err = b.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", eventsChannel, string(payload))
+	return err
+}
+
+// Subscribe registers a handler for events published by any instance
+func (b *PostgresBroker) Subscribe(handler BrokerHandler) {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+}
+
+// Close stops listening for events. It is safe to call more than once.
+func (b *PostgresBroker) Close() error {
+	var err error
+	b.close.Do(func() {
+		close(b.done)
+		err = b.listener.Close()
+	})
+	return err
+}
+
+// run receives notifications until the broker is closed
+func (b *PostgresBroker) run() {
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case n, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				// The listener reconnected and events sent meanwhile were lost, so tell
+				// every client to catch up from the event log
+				log.Println("Event broker listener reconnected")
+				b.dispatch("", Event{Name: "reset", Data: []byte("{}")})
+				continue
+			}
+			b.handle(n.Extra)
+		case <-ticker.C:
+			go b.listener.Ping()
+		}
+	}
+}
+
+// handle decodes one notification and passes it to the subscribed handlers
+func (b *PostgresBroker) handle(payload string) {
+	var msg brokerMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.Printf("Failed to decode event broker message: %v", err)
+		return
+	}
+
+	event := Event{
+		ID:     msg.ID,
+		Stream: msg.Stream,
+		Name:   msg.Name,
+		Data:   msg.Data,
+	}
+
+	// Sent by reference, load the data from the event log
+	if event.Data == nil && event.ID != 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := b.db.QueryRowContext(ctx, "SELECT data FROM user_events WHERE id = $1", event.ID).Scan(&event.Data)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to load event %d from event log: %v", event.ID, err)
+			return
+		}
+	}
+
+	b.dispatch(msg.UserID, event)
+}
+
+// dispatch passes an event to the subscribed handlers
+func (b *PostgresBroker) dispatch(userID string, event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(userID, event)
+	}
+}
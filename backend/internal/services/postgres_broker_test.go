@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestBroker opens a PostgresBroker on its own connection pool, as a separate
+// instance would
+func newTestBroker(t *testing.T) *PostgresBroker {
+	t.Helper()
+	db, dsn := openTestDB(t)
+
+	broker, err := NewPostgresBroker(db, dsn)
+	if err != nil {
+		t.Fatalf("NewPostgresBroker: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+	return broker
+}
+
+func TestPostgresBrokerDeliversAcrossInstances(t *testing.T) {
+	sender := newTestBroker(t)
+	receiver := newTestBroker(t)
+
+	type delivery struct {
+		userID string
+		event  Event
+	}
+	received := make(chan delivery, 1)
+	receiver.Subscribe(func(userID string, event Event) {
+		received <- delivery{userID, event}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sent := Event{Stream: MessageStream, Name: "typing", Data: []byte(`{"match_id":1}`)}
+	if err := sender.Publish(ctx, "user-1", sent); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got.userID != "user-1" || got.event.Stream != sent.Stream || got.event.Name != sent.Name || string(got.event.Data) != string(sent.Data) {
+			t.Fatalf("received %+v for %s, want %+v for user-1", got.event, got.userID, sent)
+		}
+	case <-ctx.Done():
+		t.Fatal("event published by one broker never reached the other")
+	}
+}
+
+func TestPostgresBrokerResetsAfterReconnect(t *testing.T) {
+	broker := newTestBroker(t)
+
+	received := make(chan Event, 1)
+	broker.Subscribe(func(userID string, event Event) {
+		if userID == "" {
+			received <- event
+		}
+	})
+
+	// Drop the listener's connection, pq reconnects after minReconnectInterval
+	_, err := broker.db.Exec(`
+		SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		WHERE query = 'LISTEN "' || $1 || '"'
+	`, eventsChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-received:
+		if event.Name != "reset" {
+			t.Fatalf("received %q after reconnecting, want reset", event.Name)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("no reset event after the listener reconnected")
+	}
+}
+
+func TestPostgresBrokerCloseTwice(t *testing.T) {
+	broker := newTestBroker(t)
+	if err := broker.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := broker.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
@@ -29,15 +29,15 @@ func (s *PreferenceService) GetPreferences(ctx context.Context, userID string) (
 	db := s.GetDB()
 
 	var preference models.Preference
-	var preferencesJSON []byte
+	var preferencesJSON, attributeFiltersJSON []byte
 
 	err := db.QueryRowContext(ctx, `
-		SELECT id, user_id, preferred_gender, min_age, max_age, max_distance, preferences, created_at, updated_at
+		SELECT id, user_id, preferred_gender, min_age, max_age, max_distance, preferences, attribute_filters, created_at, updated_at
 		FROM preferences 
 		WHERE user_id = $1
 	`, userID).Scan(
 		&preference.ID, &preference.UserID, &preference.PreferredGender, &preference.MinAge, 
-		&preference.MaxAge, &preference.MaxDistance, &preferencesJSON, &preference.CreatedAt, &preference.UpdatedAt,
+		&preference.MaxAge, &preference.MaxDistance, &preferencesJSON, &attributeFiltersJSON, &preference.CreatedAt, &preference.UpdatedAt,
 	)
 
 	if err != nil {
@@ -50,6 +50,7 @@ func (s *PreferenceService) GetPreferences(ctx context.Context, userID string) (
 				MaxAge:          100,
 				MaxDistance:     50,
 				Preferences:     make(map[string]interface{}),
+				AttributeFilters: make(map[string]models.AttributeFilter),
 				CreatedAt:       time.Now(),
 				UpdatedAt:       time.Now(),
 			}, nil
@@ -66,6 +67,14 @@ func (s *PreferenceService) GetPreferences(ctx context.Context, userID string) (
 		preference.Preferences = make(map[string]interface{})
 	}
 
+	// Parse attribute filters JSON
+	preference.AttributeFilters = make(map[string]models.AttributeFilter)
+	if attributeFiltersJSON != nil {
+		if err := json.Unmarshal(attributeFiltersJSON, &preference.AttributeFilters); err != nil {
+			return nil, err
+		}
+	}
+
 	return &preference, nil
 }
 
@@ -86,6 +95,9 @@ func (s *PreferenceService) CreateOrUpdatePreference(ctx context.Context, userID
 	if preference.MaxDistance < 1 {
 		return errors.New("maximum distance must be at least 1")
 	}
+	if err := validateAttributeFilters(preference.AttributeFilters); err != nil {
+		return err
+	}
 
 	// Prepare preferences JSON
 	preferencesJSON := []byte("{}")
@@ -97,6 +109,16 @@ func (s *PreferenceService) CreateOrUpdatePreference(ctx context.Context, userID
 		preferencesJSON = preferencesBytes
 	}
 
+	// Prepare attribute filters JSON
+	attributeFiltersJSON := []byte("{}")
+	if preference.AttributeFilters != nil {
+		attributeFiltersBytes, err := json.Marshal(preference.AttributeFilters)
+		if err != nil {
+			return err
+		}
+		attributeFiltersJSON = attributeFiltersBytes
+	}
+
 	// Check if preferences already exist
 	var exists bool
 	err := db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM preferences WHERE user_id = $1)", userID).Scan(&exists)
@@ -109,25 +131,19 @@ func (s *PreferenceService) CreateOrUpdatePreference(ctx context.Context, userID
 		_, err = db.ExecContext(ctx, `
 			UPDATE preferences 
 			SET preferred_gender = $1, min_age = $2, max_age = $3, max_distance = $4, 
-			    preferences = $5, updated_at = NOW()
-			WHERE user_id = $6
+			    preferences = $5, attribute_filters = $6, updated_at = NOW()
+			WHERE user_id = $7
 		`, preference.PreferredGender, preference.MinAge, preference.MaxAge, 
-		   preference.MaxDistance, preferencesJSON, userID)
+		   preference.MaxDistance, preferencesJSON, attributeFiltersJSON, userID)
 	} else {
 		// Create new preferences
 		_, err = db.ExecContext(ctx, `
 			INSERT INTO preferences 
-			(user_id, preferred_gender, min_age, max_age, max_distance, preferences)
-			VALUES ($1, $2, $3, $4, $5, $6)
+			(user_id, preferred_gender, min_age, max_age, max_distance, preferences, attribute_filters)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
 		`, userID, preference.PreferredGender, preference.MinAge, preference.MaxAge, 
-		   preference.MaxDistance, preferencesJSON)
+		   preference.MaxDistance, preferencesJSON, attributeFiltersJSON)
 	}
 
 	return err
 }
-
-// UpdatePreferences updates user preferences
-func (s *PreferenceService) UpdatePreferences(ctx context.Context, userID string, preferences map[string]interface{}) error {
-	// TODO: Implement updating user preferences
-	return nil
-} 
\ No newline at end of file
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// Profile attribute errors
+var (
+	ErrUnknownAttribute         = errors.New("unknown profile attribute")
+	ErrInvalidAttribute         = errors.New("invalid profile attribute")
+	ErrAttributeProfileRequired = errors.New("create a profile before adding attributes")
+)
+
+// GetAttributes returns every attribute the user has answered, hidden ones included,
+// keyed by attribute
+func (s *ProfileService) GetAttributes(ctx context.Context, userID string) (map[string]models.ProfileAttribute, error) {
+	rows, err := s.GetDB().QueryContext(ctx, `
+		SELECT a.key, a.value, a.visible, a.updated_at
+		FROM profile_attributes a
+		JOIN profiles p ON p.id = a.profile_id
+		WHERE p.user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attributes := map[string]models.ProfileAttribute{}
+	for rows.Next() {
+		var key string
+		var value []byte
+		var attribute models.ProfileAttribute
+		if err := rows.Scan(&key, &value, &attribute.Visible, &attribute.UpdatedAt); err != nil {
+			return nil, err
+		}
+		attribute.Value = value
+		attributes[key] = attribute
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attributes, nil
+}
+
+// UpdateAttributes sets, clears or shows and hides the user's attributes. Every change
+// is checked against the catalog before any is saved.
+func (s *ProfileService) UpdateAttributes(ctx context.Context, userID string, input map[string]models.ProfileAttributeInput) (map[string]models.ProfileAttribute, error) {
+	values := map[string]json.RawMessage{}
+	for key, change := range input {
+		definition, ok := models.LookupAttribute(key)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAttribute, key)
+		}
+		if len(change.Value) == 0 || isJSONNull(change.Value) {
+			continue
+		}
+		value, err := normalizeAttributeValue(definition, change.Value)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+
+	tx, err := s.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var profileID string
+	err = tx.QueryRowContext(ctx, `SELECT id FROM profiles WHERE user_id = $1`, userID).Scan(&profileID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAttributeProfileRequired
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	for key, change := range input {
+		switch {
+		case len(change.Value) == 0:
+			// Only the visibility changes
+			if change.Visible == nil {
+				continue
+			}
+			_, err = tx.ExecContext(ctx, `
+				UPDATE profile_attributes SET visible = $1, updated_at = $2
+				WHERE profile_id = $3 AND key = $4
+			`, *change.Visible, now, profileID, key)
+		case isJSONNull(change.Value):
+			_, err = tx.ExecContext(ctx, `
+				DELETE FROM profile_attributes WHERE profile_id = $1 AND key = $2
+			`, profileID, key)
+		default:
+			// New attributes are visible unless told otherwise, existing ones keep
+			// their visibility
+			var visible sql.NullBool
+			if change.Visible != nil {
+				visible = sql.NullBool{Bool: *change.Visible, Valid: true}
+			}
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO profile_attributes (profile_id, key, value, visible, updated_at)
+				VALUES ($1, $2, $3, COALESCE($4, true), $5)
+				ON CONFLICT (profile_id, key) DO UPDATE
+				SET value = EXCLUDED.value,
+				    visible = COALESCE($4, profile_attributes.visible),
+				    updated_at = EXCLUDED.updated_at
+			`, profileID, key, []byte(values[key]), visible, now)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.GetAttributes(ctx, userID)
+}
+
+// loadVisibleAttributes loads the attributes shown on each of the profiles, keyed by
+// profile id then attribute
+func (s *ProfileService) loadVisibleAttributes(ctx context.Context, profileIDs []string) (map[string]map[string]json.RawMessage, error) {
+	rows, err := s.GetDB().QueryContext(ctx, `
+		SELECT profile_id, key, value
+		FROM profile_attributes
+		WHERE profile_id = ANY($1) AND visible
+	`, pq.Array(profileIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attributes := map[string]map[string]json.RawMessage{}
+	for rows.Next() {
+		var profileID, key string
+		var value []byte
+		if err := rows.Scan(&profileID, &key, &value); err != nil {
+			return nil, err
+		}
+		if attributes[profileID] == nil {
+			attributes[profileID] = map[string]json.RawMessage{}
+		}
+		attributes[profileID][key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attributes, nil
+}
+
+// normalizeAttributeValue checks a value against its attribute's definition and returns
+// it re-encoded, with text trimmed and repeated choices dropped
+func normalizeAttributeValue(definition models.AttributeDefinition, raw json.RawMessage) (json.RawMessage, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s %s", ErrInvalidAttribute, definition.Key, reason)
+	}
+
+	var value interface{}
+	switch definition.Type {
+	case models.AttributeTypeChoice:
+		var choice string
+		if err := json.Unmarshal(raw, &choice); err != nil || !slices.Contains(definition.Options, choice) {
+			return nil, invalid("must be one of " + strings.Join(definition.Options, ", "))
+		}
+		value = choice
+	case models.AttributeTypeMultiChoice:
+		var choices []string
+		if err := json.Unmarshal(raw, &choices); err != nil || len(choices) == 0 {
+			return nil, invalid("must be a list of " + strings.Join(definition.Options, ", "))
+		}
+		var unique []string
+		for _, choice := range choices {
+			if !slices.Contains(definition.Options, choice) {
+				return nil, invalid("must be a list of " + strings.Join(definition.Options, ", "))
+			}
+			if !slices.Contains(unique, choice) {
+				unique = append(unique, choice)
+			}
+		}
+		value = unique
+	case models.AttributeTypeText:
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, invalid("must be text")
+		}
+		text = strings.TrimSpace(text)
+		if text == "" || utf8.RuneCountInString(text) > definition.Max {
+			return nil, invalid(fmt.Sprintf("must be 1 to %d characters", definition.Max))
+		}
+		value = text
+	case models.AttributeTypeNumber:
+		var number int
+		if err := json.Unmarshal(raw, &number); err != nil || number < definition.Min || number > definition.Max {
+			return nil, invalid(fmt.Sprintf("must be a whole number from %d to %d", definition.Min, definition.Max))
+		}
+		value = number
+	default:
+		return nil, invalid("has an unknown type")
+	}
+
+	return json.Marshal(value)
+}
+
+// validateAttributeFilters checks feed attribute filters against the catalog
+func validateAttributeFilters(filters map[string]models.AttributeFilter) error {
+	for key, filter := range filters {
+		definition, ok := models.LookupAttribute(key)
+		if !ok || !definition.Filterable {
+			return fmt.Errorf("%w: %s can't be filtered on", ErrUnknownAttribute, key)
+		}
+
+		if definition.Type == models.AttributeTypeNumber {
+			if len(filter.Values) > 0 || (filter.Min == nil && filter.Max == nil) {
+				return fmt.Errorf("%w: %s filters need a min or max", ErrInvalidAttribute, key)
+			}
+			low, high := attributeFilterRange(definition, filter)
+			if low < definition.Min || high > definition.Max || low > high {
+				return fmt.Errorf("%w: %s filters must be from %d to %d", ErrInvalidAttribute, key, definition.Min, definition.Max)
+			}
+			continue
+		}
+
+		if len(filter.Values) == 0 || filter.Min != nil || filter.Max != nil {
+			return fmt.Errorf("%w: %s filters need values", ErrInvalidAttribute, key)
+		}
+		for _, value := range filter.Values {
+			if !slices.Contains(definition.Options, value) {
+				return fmt.Errorf("%w: %s filters must be of %s", ErrInvalidAttribute, key, strings.Join(definition.Options, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// attributeFilterRange returns the range a number filter allows, an open end running
+// to the end of the attribute's range
+func attributeFilterRange(definition models.AttributeDefinition, filter models.AttributeFilter) (int, int) {
+	low, high := definition.Min, definition.Max
+	if filter.Min != nil {
+		low = *filter.Min
+	}
+	if filter.Max != nil {
+		high = *filter.Max
+	}
+	return low, high
+}
+
+// appendAttributeFilters adds attribute filters to a feed query over profiles p. A
+// profile is dropped when it shows the attribute with a value the filter doesn't allow;
+// profiles that don't show it are kept. Filters are added in catalog order so the query
+// is the same for the same filters.
+func appendAttributeFilters(query string, args []interface{}, filters map[string]models.AttributeFilter) (string, []interface{}) {
+	for _, definition := range models.ProfileAttributes {
+		filter, ok := filters[definition.Key]
+		if !ok {
+			continue
+		}
+
+		args = append(args, definition.Key)
+		keyArg := "$" + strconv.Itoa(len(args))
+
+		var allowed string
+		switch definition.Type {
+		case models.AttributeTypeNumber:
+			low, high := attributeFilterRange(definition, filter)
+			args = append(args, low, high)
+			allowed = `(a.value #>> '{}')::INT BETWEEN $` + strconv.Itoa(len(args)-1) + ` AND $` + strconv.Itoa(len(args))
+		case models.AttributeTypeMultiChoice:
+			args = append(args, pq.Array(filter.Values))
+			allowed = `a.value ?| $` + strconv.Itoa(len(args))
+		default:
+			args = append(args, pq.Array(filter.Values))
+			allowed = `a.value #>> '{}' = ANY($` + strconv.Itoa(len(args)) + `)`
+		}
+
+		query += ` AND NOT EXISTS (
+			SELECT 1 FROM profile_attributes a
+			WHERE a.profile_id = p.id AND a.key = ` + keyArg + ` AND a.visible AND NOT (` + allowed + `))`
+	}
+	return query, args
+}
+
+// isJSONNull reports whether a raw JSON value is null
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+func TestNormalizeAttributeValue(t *testing.T) {
+	tests := []struct {
+		key  string
+		raw  string
+		want string // Empty if the value is invalid
+	}{
+		// Choice
+		{"religion", `"buddhist"`, `"buddhist"`},
+		{"religion", `"Buddhist"`, ""},
+		{"religion", `["buddhist"]`, ""},
+		{"drinking", `"sometimes"`, `"sometimes"`},
+		// Multi choice
+		{"ethnicity", `["white","south_asian"]`, `["white","south_asian"]`},
+		{"ethnicity", `["white","white","other"]`, `["white","other"]`},
+		{"ethnicity", `[]`, ""},
+		{"ethnicity", `"white"`, ""},
+		{"ethnicity", `["white","martian"]`, ""},
+		{"pronouns", `["they/them"]`, `["they/them"]`},
+		// Text
+		{"school", `"  Leeds  "`, `"Leeds"`},
+		{"school", `"   "`, ""},
+		{"school", `"` + strings.Repeat("é", 100) + `"`, `"` + strings.Repeat("é", 100) + `"`},
+		{"school", `"` + strings.Repeat("é", 101) + `"`, ""},
+		{"job_title", `42`, ""},
+		// Number
+		{"height", `180`, `180`},
+		{"height", `90`, `90`},
+		{"height", `250`, `250`},
+		{"height", `89`, ""},
+		{"height", `251`, ""},
+		{"height", `180.5`, ""},
+		{"height", `"180"`, ""},
+	}
+	for _, tt := range tests {
+		definition, ok := models.LookupAttribute(tt.key)
+		if !ok {
+			t.Fatalf("no %s attribute in the catalog", tt.key)
+		}
+		got, err := normalizeAttributeValue(definition, json.RawMessage(tt.raw))
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidAttribute) {
+				t.Errorf("%s %s: error = %v, want ErrInvalidAttribute", tt.key, tt.raw, err)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s %s = %s, %v, want %s", tt.key, tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidateAttributeFilters(t *testing.T) {
+	number := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		filters map[string]models.AttributeFilter
+		wantErr error
+	}{
+		{"none", nil, nil},
+		{"choice", map[string]models.AttributeFilter{"religion": {Values: []string{"jewish", "atheist"}}}, nil},
+		{"multi choice", map[string]models.AttributeFilter{"ethnicity": {Values: []string{"black"}}}, nil},
+		{"height range", map[string]models.AttributeFilter{"height": {Min: number(160), Max: number(190)}}, nil},
+		{"height open ended", map[string]models.AttributeFilter{"height": {Min: number(170)}}, nil},
+		{"several", map[string]models.AttributeFilter{"smoking": {Values: []string{"no"}}, "height": {Max: number(200)}}, nil},
+		{"unknown", map[string]models.AttributeFilter{"star_sign": {Values: []string{"leo"}}}, ErrUnknownAttribute},
+		{"not filterable", map[string]models.AttributeFilter{"pronouns": {Values: []string{"she/her"}}}, ErrUnknownAttribute},
+		{"text not filterable", map[string]models.AttributeFilter{"school": {Values: []string{"Leeds"}}}, ErrUnknownAttribute},
+		{"unknown option", map[string]models.AttributeFilter{"religion": {Values: []string{"pastafarian"}}}, ErrInvalidAttribute},
+		{"no values", map[string]models.AttributeFilter{"religion": {}}, ErrInvalidAttribute},
+		{"range on a choice", map[string]models.AttributeFilter{"religion": {Values: []string{"hindu"}, Min: number(1)}}, ErrInvalidAttribute},
+		{"values on a number", map[string]models.AttributeFilter{"height": {Values: []string{"180"}}}, ErrInvalidAttribute},
+		{"empty range", map[string]models.AttributeFilter{"height": {}}, ErrInvalidAttribute},
+		{"below range", map[string]models.AttributeFilter{"height": {Min: number(50)}}, ErrInvalidAttribute},
+		{"above range", map[string]models.AttributeFilter{"height": {Max: number(300)}}, ErrInvalidAttribute},
+		{"inverted range", map[string]models.AttributeFilter{"height": {Min: number(190), Max: number(160)}}, ErrInvalidAttribute},
+	}
+	for _, tt := range tests {
+		err := validateAttributeFilters(tt.filters)
+		if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: validateAttributeFilters() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// GetProfilesByIDs loads full profiles, with photos, prompts and visible attributes, for
+// many profile ids in four queries. Profiles that don't exist are missing from the map.
+func (s *ProfileService) GetProfilesByIDs(ctx context.Context, ids []string) (map[string]*models.Profile, error) {
+	return s.loadProfiles(ctx, "id", ids)
+}
+
+// GetProfilesByUserIDs loads full profiles for many users, keyed by user id. Users
+// without a profile are missing from the map.
+func (s *ProfileService) GetProfilesByUserIDs(ctx context.Context, userIDs []string) (map[string]*models.Profile, error) {
+	byID, err := s.loadProfiles(ctx, "user_id", userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byUserID := make(map[string]*models.Profile, len(byID))
+	for _, profile := range byID {
+		byUserID[profile.UserID] = profile
+	}
+	return byUserID, nil
+}
+
+// loadProfiles loads the profiles whose column is one of values, keyed by profile id.
+// column is always a constant from this file, never user input.
+func (s *ProfileService) loadProfiles(ctx context.Context, column string, values []string) (map[string]*models.Profile, error) {
+	profiles := map[string]*models.Profile{}
+	if len(values) == 0 {
+		return profiles, nil
+	}
+
+	db := s.GetDB()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT p.id, p.user_id, p.name, p.bio, p.date_of_birth,
+		       p.gender, p.location, p.occupation, p.vices, p.preferences,
+		       p.created_at, p.updated_at, p.onboarding_completed
+		FROM profiles p
+		WHERE p.`+column+` = ANY($1)
+	`, pq.Array(values))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var profile models.Profile
+		var vicesJSON, preferencesJSON []byte
+		var dateOfBirth time.Time
+		if err := rows.Scan(
+			&profile.ID, &profile.UserID, &profile.Name, &profile.Bio, &dateOfBirth,
+			&profile.Gender, &profile.Location, &profile.Occupation, &vicesJSON, &preferencesJSON,
+			&profile.CreatedAt, &profile.UpdatedAt, &profile.OnboardingCompleted,
+		); err != nil {
+			return nil, err
+		}
+		profile.DateOfBirth = dateOfBirth.Format("2006-01-02")
+
+		profile.Vices = make(map[string]bool)
+		if vicesJSON != nil {
+			if err := json.Unmarshal(vicesJSON, &profile.Vices); err != nil {
+				return nil, err
+			}
+		}
+		profile.Preferences = make(map[string]interface{})
+		if preferencesJSON != nil {
+			if err := json.Unmarshal(preferencesJSON, &profile.Preferences); err != nil {
+				return nil, err
+			}
+		}
+
+		profiles[profile.ID] = &profile
+		ids = append(ids, profile.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return profiles, nil
+	}
+
+	// Photos, primary first as in getProfilePhotos
+	photoRows, err := db.QueryContext(ctx, `
+		SELECT `+photoColumns+`
+		FROM photos
+		WHERE profile_id = ANY($1)
+		ORDER BY profile_id, `+photoOrder, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer photoRows.Close()
+
+	for photoRows.Next() {
+		var photo models.Photo
+		if err := scanPhoto(photoRows, &photo); err != nil {
+			return nil, err
+		}
+		if profile, ok := profiles[photo.ProfileID]; ok {
+			profile.Photos = append(profile.Photos, photo)
+		}
+	}
+	if err := photoRows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Prompts, in the order they were answered as in getProfilePrompts
+	promptRows, err := db.QueryContext(ctx, `
+		SELECT pp.id, pp.profile_id, pp.prompt_id, p.text, pp.answer
+		FROM profile_prompts pp
+		JOIN prompts p ON pp.prompt_id = p.id
+		WHERE pp.profile_id = ANY($1)
+		ORDER BY pp.profile_id, pp.id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer promptRows.Close()
+
+	for promptRows.Next() {
+		var prompt models.ProfilePrompt
+		if err := promptRows.Scan(&prompt.ID, &prompt.ProfileID, &prompt.PromptID, &prompt.Text, &prompt.Answer); err != nil {
+			return nil, err
+		}
+		if profile, ok := profiles[prompt.ProfileID]; ok {
+			profile.Prompts = append(profile.Prompts, prompt)
+		}
+	}
+	if err := promptRows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Attributes shown on the profiles
+	attributes, err := s.loadVisibleAttributes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		profile.Completeness = profileCompleteness(profile)
+		profile.Attributes = attributes[profile.ID]
+	}
+
+	return profiles, nil
+}
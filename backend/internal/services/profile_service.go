@@ -8,17 +8,22 @@ import (
 	"time"
 
 	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/utils"
 )
 
 // ProfileService handles profile-related business logic
 type ProfileService struct {
 	BaseService
+	minPhotos int // Photos needed to finish onboarding
 }
 
 // NewProfileService creates a new profile service
 func NewProfileService(db *sql.DB) *ProfileService {
+	minPhotos := utils.NewConfig().GetEnvInt("ONBOARDING_MIN_PHOTOS", defaultOnboardingMinPhotos)
+
 	return &ProfileService{
 		BaseService: NewBaseService(db),
+		minPhotos:   max(1, min(minPhotos, maxPhotosPerProfile)),
 	}
 }
 
@@ -34,13 +39,13 @@ func (s *ProfileService) GetProfileByID(ctx context.Context, id string) (*models
 	err := db.QueryRowContext(ctx, `
 		SELECT p.id, p.user_id, p.name, p.bio, p.date_of_birth, 
 		       p.gender, p.location, p.occupation, p.vices, p.preferences,
-		       p.created_at, p.updated_at
+		       p.created_at, p.updated_at, p.onboarding_completed
 		FROM profiles p
 		WHERE p.id = $1
 	`, id).Scan(
 		&profile.ID, &userID, &profile.Name, &profile.Bio, &dateOfBirth,
 		&profile.Gender, &profile.Location, &profile.Occupation, &vicesJSON, &preferencesJSON,
-		&profile.CreatedAt, &profile.UpdatedAt,
+		&profile.CreatedAt, &profile.UpdatedAt, &profile.OnboardingCompleted,
 	)
 
 	if err != nil {
@@ -84,6 +89,14 @@ func (s *ProfileService) GetProfileByID(ctx context.Context, id string) (*models
 		return nil, err
 	}
 	profile.Prompts = prompts
+	profile.Completeness = profileCompleteness(&profile)
+
+	// Get the attributes shown on the profile
+	attributes, err := s.loadVisibleAttributes(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	profile.Attributes = attributes[id]
 
 	return &profile, nil
 }
@@ -237,8 +250,8 @@ func (s *ProfileService) getProfilePrompts(ctx context.Context, profileID string
 func (s *ProfileService) getProfilePhotos(ctx context.Context, profileID string) ([]models.Photo, error) {
 	rows, err := s.GetDB().QueryContext(
 		ctx,
-		`SELECT id, profile_id, url, is_primary, created_at 
-		FROM photos WHERE profile_id = $1 ORDER BY is_primary DESC, created_at ASC`,
+		`SELECT `+photoColumns+`
+		FROM photos WHERE profile_id = $1 ORDER BY `+photoOrder,
 		profileID,
 	)
 	if err != nil {
@@ -249,7 +262,7 @@ func (s *ProfileService) getProfilePhotos(ctx context.Context, profileID string)
 	var photos []models.Photo
 	for rows.Next() {
 		var photo models.Photo
-		if err := rows.Scan(&photo.ID, &photo.ProfileID, &photo.URL, &photo.IsPrimary, &photo.CreatedAt); err != nil {
+		if err := scanPhoto(rows, &photo); err != nil {
 			return nil, err
 		}
 		photos = append(photos, photo)
@@ -364,33 +377,6 @@ func (s *ProfileService) DeleteProfilePrompt(ctx context.Context, profileID stri
 	return nil
 }
 
-// CompleteOnboarding marks the profile as having completed onboarding
-func (s *ProfileService) CompleteOnboarding(ctx context.Context, profileID string) error {
-	db := s.GetDB()
-
-	// Check if profile exists
-	var exists bool
-	err := db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM profiles WHERE id = $1)", profileID).Scan(&exists)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return errors.New("profile not found")
-	}
-
-	// Mark profile as having completed onboarding
-	_, err = db.ExecContext(ctx, `
-		UPDATE profiles
-		SET onboarding_completed = true, updated_at = NOW()
-		WHERE id = $1
-	`, profileID)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // GetProfileByUserID retrieves a profile by user ID
 func (s *ProfileService) GetProfileByUserID(ctx context.Context, userID string) (*models.Profile, error) {
 	var profileID string
@@ -4,10 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/vibe-code-hinge/backend/internal/models"
 )
 
+// ErrTooManyPrompts is returned when answering another prompt on a profile that already
+// has all its prompts
+var ErrTooManyPrompts = fmt.Errorf("a profile can only answer %d prompts, remove one first", onboardingPrompts)
+
 // PromptService handles prompt operations
 type PromptService struct {
 	BaseService
@@ -120,15 +125,52 @@ func (s *PromptService) UpdateUserPrompt(ctx context.Context, userID string, pro
 		return err
 	}
 
-	// Update or insert the prompt response
-	_, err = s.GetDB().ExecContext(
+	// Update or insert the prompt response. A new answer is only added while the
+	// profile has fewer than onboardingPrompts others.
+	result, err := s.GetDB().ExecContext(
 		ctx,
 		`INSERT INTO profile_prompts (profile_id, prompt_id, answer)
-		VALUES ($1, $2, $3)
+		SELECT $1::UUID, $2::BIGINT, $3::TEXT
+		WHERE EXISTS (SELECT 1 FROM profile_prompts WHERE profile_id = $1 AND prompt_id = $2)
+		   OR (SELECT COUNT(*) FROM profile_prompts WHERE profile_id = $1 AND prompt_id <> $2) < $4
 		ON CONFLICT (profile_id, prompt_id) 
 		DO UPDATE SET answer = $3, updated_at = NOW()`,
-		profileID, promptID, response,
+		profileID, promptID, response, onboardingPrompts,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTooManyPrompts
+	}
+
+	return nil
+}
+
+// DeleteUserPrompt removes a user's answer to a prompt
+func (s *PromptService) DeleteUserPrompt(ctx context.Context, userID string, promptID int64) error {
+	result, err := s.GetDB().ExecContext(
+		ctx,
+		`DELETE FROM profile_prompts
+		WHERE profile_id = (SELECT id FROM profiles WHERE user_id = $1) AND prompt_id = $2`,
+		userID, promptID,
 	)
-	
-	return err
-} 
\ No newline at end of file
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("prompt response not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/push"
+	"github.com/vibe-code-hinge/backend/internal/utils"
+)
+
+// Retries for push sends that fail with a temporary error
+const (
+	defaultPushMaxAttempts = 4
+	pushInitialBackoff     = 500 * time.Millisecond
+	pushMaxBackoff         = 30 * time.Second
+)
+
+// maxDeviceTokenLength caps registered tokens, real APNs and FCM tokens are far shorter
+const maxDeviceTokenLength = 4096
+
+// Errors returned by device registration
+var (
+	ErrInvalidDevicePlatform = errors.New("platform must be ios, android or web")
+	ErrInvalidDeviceToken    = errors.New("device token is required")
+	ErrDeviceNotFound        = errors.New("device not found or not owned by user")
+)
+
+// PushService registers users' devices and sends them push notifications
+type PushService struct {
+	BaseService
+	providers   map[string]push.PushProvider // Keyed by device platform
+	maxAttempts int
+}
+
+// NewPushService creates a new PushService sending through the given providers, keyed by
+// the platform they deliver to. Devices on a platform without a provider are skipped.
+func NewPushService(db *sql.DB, providers map[string]push.PushProvider) *PushService {
+	config := utils.NewConfig()
+	return &PushService{
+		BaseService: NewBaseService(db),
+		providers:   providers,
+		maxAttempts: config.GetEnvInt("PUSH_MAX_ATTEMPTS", defaultPushMaxAttempts),
+	}
+}
+
+// RegisterDevice registers a device token for the user. Registering a token again
+// refreshes it, and a token registered by another user moves to this one, as happens
+// when someone logs into a different account on the same phone.
+func (s *PushService) RegisterDevice(ctx context.Context, userID string, input models.DeviceInput) (*models.Device, error) {
+	input.Token = strings.TrimSpace(input.Token)
+	if input.Token == "" || len(input.Token) > maxDeviceTokenLength {
+		return nil, ErrInvalidDeviceToken
+	}
+	switch input.Platform {
+	case push.PlatformIOS, push.PlatformAndroid, push.PlatformWeb:
+	default:
+		return nil, ErrInvalidDevicePlatform
+	}
+
+	device := models.Device{Platform: input.Platform, Token: input.Token}
+	err := s.GetDB().QueryRowContext(ctx, `
+		INSERT INTO devices (user_id, platform, token, created_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (token) DO UPDATE
+		SET user_id = $1, platform = $2, last_seen_at = $4
+		RETURNING id, created_at, last_seen_at
+	`, userID, input.Platform, input.Token, time.Now()).Scan(&device.ID, &device.CreatedAt, &device.LastSeenAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+// UnregisterDevice stops pushes to one of the user's devices, for example on logout
+func (s *PushService) UnregisterDevice(ctx context.Context, userID string, token string) error {
+	result, err := s.GetDB().ExecContext(ctx, `
+		DELETE FROM devices WHERE user_id = $1 AND token = $2
+	`, userID, token)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrDeviceNotFound
+	}
+
+	return nil
+}
+
+// SendToUser pushes a message to every device the user has registered. Temporary
+// provider errors are retried with exponential backoff and tokens the provider rejects
+// are deleted. Returns the number of devices the message was delivered to, and an error
+// if it reached none because sending failed.
+func (s *PushService) SendToUser(ctx context.Context, userID string, msg push.Message) (int, error) {
+	if len(s.providers) == 0 {
+		return 0, nil
+	}
+
+	rows, err := s.GetDB().QueryContext(ctx, `
+		SELECT id, platform, token FROM devices WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	var devices []models.Device
+	for rows.Next() {
+		var device models.Device
+		if err := rows.Scan(&device.ID, &device.Platform, &device.Token); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		devices = append(devices, device)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	var sendErr error
+	for _, device := range devices {
+		provider, ok := s.providers[device.Platform]
+		if !ok {
+			continue
+		}
+
+		err := s.sendWithRetry(ctx, provider, device.Token, msg)
+		switch {
+		case err == nil:
+			delivered++
+		case errors.Is(err, push.ErrInvalidToken):
+			if _, err := s.GetDB().ExecContext(ctx, `DELETE FROM devices WHERE id = $1`, device.ID); err != nil {
+				log.Printf("Failed to prune invalid device token %d: %v", device.ID, err)
+			}
+		default:
+			log.Printf("Failed to push to device %d of user %s: %v", device.ID, userID, err)
+			sendErr = err
+		}
+	}
+
+	if delivered == 0 && sendErr != nil {
+		return 0, sendErr
+	}
+	return delivered, nil
+}
+
+// sendWithRetry sends a push, retrying temporary errors with exponential backoff. A
+// Retry-After from the provider is used in place of the backoff when it is longer.
+func (s *PushService) sendWithRetry(ctx context.Context, provider push.PushProvider, token string, msg push.Message) error {
+	backoff := pushInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := provider.Send(ctx, token, msg)
+		if err == nil || !push.IsTemporary(err) || attempt >= s.maxAttempts {
+			return err
+		}
+
+		wait := backoff
+		if retryAfter := push.RetryAfter(err); retryAfter > wait {
+			wait = retryAfter
+		}
+		if wait > pushMaxBackoff {
+			wait = pushMaxBackoff
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+// pushTitles are the push titles for each notification type
+var pushTitles = map[string]string{
+	"message":                "New message",
+	"match":                  "It's a match!",
+	NotificationYourTurn:     "Your turn",
+	NotificationMatchExpired: "Match expired",
+	NotificationNewLike:      "New like",
+	NotificationNewRose:      "New rose",
+}
+
+// pushNotification pushes a notification to the user's devices, folds it into a later
+// summary push or holds it back until their quiet hours end
+func (s *NotificationService) pushNotification(ctx context.Context, userID string, notificationType string, notificationID int64, message string, notificationData models.NotificationData, data map[string]interface{}, quietHours models.QuietHours) error {
+	title, ok := pushTitles[notificationType]
+	if !ok {
+		title = "Vibe"
+	}
+	if notificationType == "message" && message == "" {
+		if attachments, _ := data["attachments"].([]models.Attachment); len(attachments) > 0 {
+			message = "Sent an attachment"
+		}
+	}
+
+	// Push data is string-valued on every platform
+	pushData := map[string]string{"type": notificationType}
+	if notificationID != 0 {
+		pushData["notification_id"] = strconv.FormatInt(notificationID, 10)
+	}
+	if notificationData.MatchID != 0 {
+		pushData["match_id"] = strconv.FormatInt(notificationData.MatchID, 10)
+	}
+	if notificationData.ProfileID != "" {
+		pushData["profile_id"] = notificationData.ProfileID
+	}
+	if notificationData.MessageID != 0 {
+		pushData["message_id"] = strconv.FormatInt(notificationData.MessageID, 10)
+	}
+	if notificationData.SenderID != "" {
+		pushData["sender_id"] = notificationData.SenderID
+	}
+
+	// Similar pushes within the type's aggregation window are summed up in one push later
+	aggregated, err := s.aggregatePush(ctx, userID, notificationType, notificationData.MatchID)
+	if err != nil {
+		log.Printf("Failed to aggregate %s push to user %s, sending it anyway: %v", notificationType, userID, err)
+	}
+	if aggregated {
+		return nil
+	}
+
+	return s.deliverPush(ctx, userID, notificationType, push.Message{Title: title, Body: message, Data: pushData}, quietHours)
+}
+
+// deliverPush sends a push now, or holds it back until the user's quiet hours end
+func (s *NotificationService) deliverPush(ctx context.Context, userID string, notificationType string, msg push.Message, quietHours models.QuietHours) error {
+	if deliverAt, quiet := quietHoursEnd(quietHours, time.Now()); quiet {
+		return s.deferPush(ctx, userID, notificationType, msg, deliverAt)
+	}
+
+	_, err := s.pushService.SendToUser(ctx, userID, msg)
+	return err
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+	_ "time/tzdata" // Quiet hours need timezone data even on hosts without it
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+	"github.com/vibe-code-hinge/backend/internal/push"
+)
+
+// Sending deferred pushes
+const (
+	deferredPushBatchSize   = 100         // How many deferred pushes are claimed at once
+	deferredPushLease       = time.Minute // How long a claimed push is hidden from other instances
+	deferredPushMaxAttempts = 5
+)
+
+// quietHoursEnd reports whether now falls within the quiet hours and, if so, when they
+// end. Quiet hours that can't be read are treated as disabled.
+func quietHoursEnd(quietHours models.QuietHours, now time.Time) (time.Time, bool) {
+	if !quietHours.Enabled {
+		return time.Time{}, false
+	}
+
+	start, err := time.Parse(quietHoursLayout, quietHours.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse(quietHoursLayout, quietHours.End)
+	if err != nil {
+		return time.Time{}, false
+	}
+	location, err := time.LoadLocation(quietHours.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	// Quiet hours either sit within a day (13:00-14:00) or run past midnight (22:00-07:00)
+	var quiet, endsTomorrow bool
+	if startMinute < endMinute {
+		quiet = minute >= startMinute && minute < endMinute
+	} else {
+		quiet = minute >= startMinute || minute < endMinute
+		endsTomorrow = minute >= startMinute
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	day := local.Day()
+	if endsTomorrow {
+		day++
+	}
+	return time.Date(local.Year(), local.Month(), day, end.Hour(), end.Minute(), 0, 0, location), true
+}
+
+// deferPush holds a push back until deliverAt
+func (s *NotificationService) deferPush(ctx context.Context, userID string, notificationType string, msg push.Message, deliverAt time.Time) error {
+	dataJSON, err := json.Marshal(msg.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.GetDB().ExecContext(ctx, `
+		INSERT INTO deferred_pushes (user_id, type, title, body, data, deliver_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, userID, notificationType, msg.Title, msg.Body, string(dataJSON), deliverAt, time.Now())
+	return err
+}
+
+// SendDeferredPushes sends the pushes held back by quiet hours that have since ended.
+// Each push is leased before it is sent, so instances don't send it at the same time, and
+// only deleted once it has been sent. A push that fails is retried with backoff up to
+// deferredPushMaxAttempts times, and one whose lease ran out mid-send is picked up again.
+// Returns the number of pushes sent.
+func (s *NotificationService) SendDeferredPushes(ctx context.Context) (int, error) {
+	if s.pushService == nil {
+		return 0, nil
+	}
+
+	db := s.GetDB()
+	sent := 0
+	for {
+		now := time.Now()
+		rows, err := db.QueryContext(ctx, `
+			UPDATE deferred_pushes
+			SET attempts = attempts + 1, deliver_at = $1
+			WHERE id IN (
+				SELECT id FROM deferred_pushes
+				WHERE deliver_at <= $2
+				ORDER BY deliver_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, user_id, type, title, body, data, attempts
+		`, now.Add(deferredPushLease), now, deferredPushBatchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		type deferred struct {
+			id               int64
+			userID           string
+			notificationType string
+			msg              push.Message
+			attempts         int
+		}
+		var pushes []deferred
+		for rows.Next() {
+			var d deferred
+			var dataJSON []byte
+			if err := rows.Scan(&d.id, &d.userID, &d.notificationType, &d.msg.Title, &d.msg.Body, &dataJSON, &d.attempts); err != nil {
+				rows.Close()
+				return sent, err
+			}
+			if err := json.Unmarshal(dataJSON, &d.msg.Data); err != nil {
+				log.Printf("Failed to read deferred push data for user %s: %v", d.userID, err)
+			}
+			pushes = append(pushes, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return sent, err
+		}
+
+		for _, d := range pushes {
+			_, sendErr := s.pushService.SendToUser(ctx, d.userID, d.msg)
+			if sendErr != nil && d.attempts < deferredPushMaxAttempts {
+				log.Printf("Failed to send deferred %s push to user %s, retrying: %v", d.notificationType, d.userID, sendErr)
+				backoff := time.Minute << uint(d.attempts-1)
+				if _, err := db.ExecContext(ctx, `
+					UPDATE deferred_pushes SET deliver_at = $2 WHERE id = $1
+				`, d.id, time.Now().Add(backoff)); err != nil {
+					log.Printf("Failed to reschedule deferred push %d: %v", d.id, err)
+				}
+				continue
+			}
+			if sendErr != nil {
+				log.Printf("Failed to send deferred %s push to user %s %d times, dropping it: %v", d.notificationType, d.userID, d.attempts, sendErr)
+			}
+
+			if _, err := db.ExecContext(ctx, `DELETE FROM deferred_pushes WHERE id = $1`, d.id); err != nil {
+				log.Printf("Failed to delete deferred push %d: %v", d.id, err)
+			}
+			if sendErr == nil {
+				sent++
+			}
+		}
+
+		if len(pushes) < deferredPushBatchSize {
+			return sent, nil
+		}
+	}
+}
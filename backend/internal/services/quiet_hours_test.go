@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+func TestQuietHoursEnd(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(value string) time.Time {
+		t.Helper()
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return at
+	}
+	overnight := models.QuietHours{Enabled: true, Start: "22:00", End: "07:00", Timezone: "UTC"}
+	lunch := models.QuietHours{Enabled: true, Start: "13:00", End: "14:30", Timezone: "UTC"}
+	newYorkNights := models.QuietHours{Enabled: true, Start: "22:00", End: "07:00", Timezone: "America/New_York"}
+
+	tests := []struct {
+		name       string
+		quietHours models.QuietHours
+		now        time.Time
+		wantQuiet  bool
+		wantEnd    time.Time
+	}{
+		{"same day before", lunch, utc("2024-03-05T12:59:00Z"), false, time.Time{}},
+		{"same day start", lunch, utc("2024-03-05T13:00:00Z"), true, utc("2024-03-05T14:30:00Z")},
+		{"same day during", lunch, utc("2024-03-05T14:29:00Z"), true, utc("2024-03-05T14:30:00Z")},
+		{"same day end", lunch, utc("2024-03-05T14:30:00Z"), false, time.Time{}},
+		{"overnight before midnight", overnight, utc("2024-03-05T23:15:00Z"), true, utc("2024-03-06T07:00:00Z")},
+		{"overnight after midnight", overnight, utc("2024-03-06T03:00:00Z"), true, utc("2024-03-06T07:00:00Z")},
+		{"overnight daytime", overnight, utc("2024-03-05T12:00:00Z"), false, time.Time{}},
+		{"overnight end", overnight, utc("2024-03-06T07:00:00Z"), false, time.Time{}},
+		{"overnight month end", overnight, utc("2024-03-31T22:30:00Z"), true, utc("2024-04-01T07:00:00Z")},
+		// 03:00 UTC is 22:00 the evening before in New York
+		{"timezone quiet", newYorkNights, utc("2024-03-05T03:00:00Z"), true, time.Date(2024, 3, 5, 7, 0, 0, 0, newYork)},
+		{"timezone awake", newYorkNights, utc("2024-03-05T23:00:00Z"), false, time.Time{}},
+		// Clocks go forward overnight, so the night is an hour shorter in UTC
+		{"timezone dst", newYorkNights, utc("2024-03-10T04:00:00Z"), true, utc("2024-03-10T11:00:00Z")},
+		{"disabled", models.QuietHours{Start: "00:00", End: "23:59", Timezone: "UTC"}, utc("2024-03-05T12:00:00Z"), false, time.Time{}},
+		{"unreadable", models.QuietHours{Enabled: true, Start: "10pm", End: "07:00", Timezone: "UTC"}, utc("2024-03-05T23:00:00Z"), false, time.Time{}},
+		{"unknown timezone", models.QuietHours{Enabled: true, Start: "22:00", End: "07:00", Timezone: "Mars/Olympus"}, utc("2024-03-05T23:00:00Z"), true, utc("2024-03-06T07:00:00Z")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, quiet := quietHoursEnd(tt.quietHours, tt.now)
+			if quiet != tt.wantQuiet || !end.Equal(tt.wantEnd) {
+				t.Errorf("quietHoursEnd() = %s, %v, want %s, %v", end, quiet, tt.wantEnd, tt.wantQuiet)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// SettingsService handles per-user app settings
+type SettingsService struct {
+	BaseService
+}
+
+// NewSettingsService creates a new settings service
+func NewSettingsService(db *sql.DB) *SettingsService {
+	return &SettingsService{
+		BaseService: NewBaseService(db),
+	}
+}
+
+// GetSettings retrieves a user's settings, falling back to defaults if none are saved
+func (s *SettingsService) GetSettings(ctx context.Context, userID string) (*models.UserSettings, error) {
+	settings := models.UserSettings{UserID: userID}
+
+	err := s.GetDB().QueryRowContext(ctx, `
+		SELECT read_receipts_enabled, updated_at
+		FROM user_settings
+		WHERE user_id = $1
+	`, userID).Scan(&settings.ReadReceiptsEnabled, &settings.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Return default settings
+			return &models.UserSettings{
+				UserID:              userID,
+				ReadReceiptsEnabled: true,
+				UpdatedAt:           time.Now(),
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// UpdateSettings updates the settings present in the input
+func (s *SettingsService) UpdateSettings(ctx context.Context, userID string, input models.UserSettingsInput) (*models.UserSettings, error) {
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.ReadReceiptsEnabled != nil {
+		settings.ReadReceiptsEnabled = *input.ReadReceiptsEnabled
+	}
+
+	err = s.GetDB().QueryRowContext(ctx, `
+		INSERT INTO user_settings (user_id, read_receipts_enabled)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET read_receipts_enabled = $2, updated_at = NOW()
+		RETURNING updated_at
+	`, userID, settings.ReadReceiptsEnabled).Scan(&settings.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// ReadReceiptsEnabled reports whether the user shares read receipts with their matches
+func (s *SettingsService) ReadReceiptsEnabled(ctx context.Context, userID string) (bool, error) {
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return settings.ReadReceiptsEnabled, nil
+}
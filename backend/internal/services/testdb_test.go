@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// openTestDB opens its own connection pool to the test database and returns it with its
+// URL. The test is skipped unless TEST_DATABASE_URL points at a migrated Postgres
+// database, e.g. postgres://postgres@localhost:5432/postgres?sslmode=disable.
+func openTestDB(tb testing.TB) (*sql.DB, string) {
+	tb.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		tb.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	return db, dsn
+}
+
+// createTestUser creates a user who is deleted, with everything that belongs to them,
+// when the test ends
+func createTestUser(tb testing.TB, db *sql.DB) string {
+	tb.Helper()
+	var userID string
+	err := db.QueryRowContext(context.Background(), `
+		INSERT INTO users (id, email, password_hash)
+		VALUES (gen_random_uuid(), gen_random_uuid() || '@test.example', 'x')
+		RETURNING id
+	`).Scan(&userID)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Exec(`DELETE FROM users WHERE id = $1`, userID) })
+	return userID
+}
+
+// createTestMatch matches two users
+func createTestMatch(tb testing.TB, db *sql.DB, user1ID string, user2ID string) int64 {
+	tb.Helper()
+	var matchID int64
+	err := db.QueryRowContext(context.Background(), `
+		INSERT INTO matches (user1_id, user2_id) VALUES ($1, $2) RETURNING id
+	`, user1ID, user2ID).Scan(&matchID)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return matchID
+}
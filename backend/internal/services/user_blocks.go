@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+// Block errors
+var (
+	ErrCannotBlockSelf = errors.New("you can't block yourself")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrUserBlocked     = errors.New("this conversation is no longer available")
+)
+
+// blockedSQL returns an SQL condition that is true when either user has blocked the
+// other. A block hides the pair from each other's feeds, likes, matches and message
+// search, and closes their conversation: messages, edits, reactions, attachments,
+// icebreakers and exports.
+func blockedSQL(userA string, userB string) string {
+	return `EXISTS (
+		SELECT 1 FROM user_blocks b
+		WHERE (b.blocker_id = ` + userA + ` AND b.blocked_id = ` + userB + `)
+		   OR (b.blocker_id = ` + userB + ` AND b.blocked_id = ` + userA + `)
+	)`
+}
+
+// BlockUser blocks another user. Blocking someone already blocked does nothing.
+func (s *MatchingService) BlockUser(ctx context.Context, userID string, blockedID string) (*models.Block, error) {
+	if userID == blockedID {
+		return nil, ErrCannotBlockSelf
+	}
+
+	db := s.GetDB()
+
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, blockedID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	block := models.Block{UserID: blockedID}
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (blocker_id, blocked_id) DO UPDATE SET blocker_id = EXCLUDED.blocker_id
+		RETURNING created_at
+	`, userID, blockedID, time.Now()).Scan(&block.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+// UnblockUser removes the user's block on another user. Unblocking someone who isn't
+// blocked does nothing.
+func (s *MatchingService) UnblockUser(ctx context.Context, userID string, blockedID string) error {
+	_, err := s.GetDB().ExecContext(ctx, `
+		DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2
+	`, userID, blockedID)
+	return err
+}
+
+// GetBlocks lists the users the user has blocked, most recent first
+func (s *MatchingService) GetBlocks(ctx context.Context, userID string) ([]models.Block, error) {
+	rows, err := s.GetDB().QueryContext(ctx, `
+		SELECT blocked_id, created_at
+		FROM user_blocks
+		WHERE blocker_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := []models.Block{}
+	for rows.Next() {
+		var block models.Block
+		if err := rows.Scan(&block.UserID, &block.CreatedAt); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vibe-code-hinge/backend/internal/models"
+)
+
+func TestBlockClosesConversation(t *testing.T) {
+	db, _ := openTestDB(t)
+	ctx := context.Background()
+
+	userID := createTestUser(t, db)
+	partnerID := createTestUser(t, db)
+	matchID := createTestMatch(t, db, userID, partnerID)
+
+	messages := NewMessageService(db)
+	sent, err := messages.SendMessage(ctx, partnerID, models.MessageInput{MatchID: matchID, Message: "hey"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The partner blocks the user, which closes the conversation for both of them
+	if _, err := NewMatchingService(db).BlockUser(ctx, partnerID, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		call    func(userID string) error
+		wantErr error
+	}{
+		{"send", func(userID string) error {
+			_, err := messages.SendMessage(ctx, userID, models.MessageInput{MatchID: matchID, Message: "hello?"})
+			return err
+		}, ErrUserBlocked},
+		{"read", func(userID string) error {
+			_, err := messages.GetMessages(ctx, userID, matchID, models.MessagePageParams{})
+			return err
+		}, ErrUserBlocked},
+		{"export", func(userID string) error {
+			_, err := messages.ExportConversation(ctx, userID, matchID, models.ExportFormatJSON)
+			return err
+		}, ErrUserBlocked},
+		{"icebreakers", func(userID string) error {
+			_, err := NewIcebreakerService(db).GetIcebreakers(ctx, userID, matchID, 0)
+			return err
+		}, ErrUserBlocked},
+		{"upload", func(userID string) error {
+			_, err := NewAttachmentService(db, nil).UploadAttachment(ctx, userID, matchID, strings.NewReader("GIF89a"), 6, "image/gif")
+			return err
+		}, ErrUserBlocked},
+		{"edit", func(userID string) error {
+			_, err := messages.EditMessage(ctx, userID, sent.ID, models.EditMessageInput{Message: "edited"})
+			return err
+		}, ErrMessageNotFound},
+		{"react", func(userID string) error {
+			_, err := messages.SetReaction(ctx, userID, sent.ID, "👍")
+			return err
+		}, ErrMessageNotFound},
+	}
+	for _, tt := range tests {
+		for _, id := range []string{userID, partnerID} {
+			if err := tt.call(id); !errors.Is(err, tt.wantErr) {
+				who := "blocked user"
+				if id == partnerID {
+					who = "blocker"
+				}
+				t.Errorf("%s by the %s: error = %v, want %v", tt.name, who, err, tt.wantErr)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/vibe-code-hinge/backend/internal/utils"
+)
+
+// ErrBlobNotFound is returned when a key does not exist in the store
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores binary objects such as chat attachments under opaque keys
+type BlobStore interface {
+	// Put stores size bytes read from body under key, replacing any existing object
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+
+	// Get opens the object stored under key. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object stored under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// NewBlobStoreFromConfig creates the BlobStore selected by BLOB_STORE, either "local"
+// (the default) or "s3" for S3 and S3-compatible stores such as MinIO
+func NewBlobStoreFromConfig(config *utils.Config) (BlobStore, error) {
+	switch backend := config.GetEnv("BLOB_STORE", "local"); backend {
+	case "local":
+		return NewLocalBlobStore(config.GetEnv("BLOB_LOCAL_DIR", "./uploads"))
+	case "s3":
+		return NewS3BlobStore(S3Config{
+			Endpoint:        config.GetEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:          config.GetEnv("S3_REGION", "us-east-1"),
+			Bucket:          config.GetEnv("S3_BUCKET", ""),
+			AccessKeyID:     config.GetEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: config.GetEnv("S3_SECRET_ACCESS_KEY", ""),
+			UsePathStyle:    config.GetEnvBool("S3_USE_PATH_STYLE", false),
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", backend)
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBlobStore is a BlobStore that keeps objects as files under a directory. It is
+// meant for development and single-instance deployments.
+type LocalBlobStore struct {
+	root string
+}
+
+// NewLocalBlobStore creates a new LocalBlobStore rooted at dir, creating it if needed
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &LocalBlobStore{root: root}, nil
+}
+
+// Put writes the object to a temporary file and moves it into place, so readers never
+// see a partial object
+func (s *LocalBlobStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return errors.New("blob size does not match")
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens the file stored under key
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+// Delete removes the file stored under key
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path maps a key to a file under the root, rejecting keys that would escape it
+func (s *LocalBlobStore) path(key string) (string, error) {
+	path := filepath.Join(s.root, filepath.FromSlash(key))
+	if key == "" || !strings.HasPrefix(path, s.root+string(filepath.Separator)) {
+		return "", errors.New("invalid blob key")
+	}
+	return path, nil
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unsignedPayload skips hashing request bodies, which S3 allows when the request is
+// otherwise signed
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Config holds the settings for an S3-compatible bucket
+type S3Config struct {
+	Endpoint        string // e.g. https://s3.amazonaws.com or http://localhost:9000 for MinIO
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	UsePathStyle    bool // Address the bucket as endpoint/bucket instead of bucket.endpoint, needed for MinIO
+}
+
+// S3BlobStore is a BlobStore backed by an S3-compatible bucket. Requests are signed with
+// AWS Signature Version 4.
+type S3BlobStore struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3BlobStore creates a new S3BlobStore
+func NewS3BlobStore(config S3Config) (*S3BlobStore, error) {
+	if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("S3 bucket and credentials are required")
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+	}
+
+	return &S3BlobStore{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 2 * time.Minute},
+	}, nil
+}
+
+// Put uploads the object
+func (s *S3BlobStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	// S3 needs the length up front, it does not accept chunked uploads
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Get downloads the object
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Delete removes the object
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// newRequest builds a request for an object in the bucket
+func (s *S3BlobStore) newRequest(ctx context.Context, method string, key string, body io.Reader) (*http.Request, error) {
+	if key == "" {
+		return nil, errors.New("invalid blob key")
+	}
+
+	u := *s.endpoint
+	if s.config.UsePathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.config.Bucket + "/" + key
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawPath = awsURIEncode(u.Path, false)
+
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do signs and sends a request, turning error responses into errors
+func (s *S3BlobStore) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrBlobNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("S3 %s %s failed with status %d: %s", req.Method, req.URL.Path, resp.StatusCode, msg)
+	}
+
+	return resp, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header covering the host and
+// every header already set on the request
+func (s *S3BlobStore) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	// Canonical headers, sorted by lowercase name
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsURIEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// canonicalQuery encodes query parameters sorted by name as SigV4 requires
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode percent-encodes everything except unreserved characters, and slashes
+// unless encodeSlash is set
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -180,6 +180,17 @@ func generateSignature(signingString, secret string) string {
 	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
 }
 
+// SignString signs a string with an HMAC-SHA256 keyed by secret, for example to sign URLs
+func SignString(signingString, secret string) string {
+	return generateSignature(signingString, secret)
+}
+
+// VerifySignature checks a signature produced by SignString in constant time
+func VerifySignature(signingString, signature, secret string) bool {
+	expected := generateSignature(signingString, secret)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
 // GenerateRandomBytes generates n random bytes
 func GenerateRandomBytes(n int) ([]byte, error) {
 	b := make([]byte, n)
@@ -0,0 +1,2 @@
+-- Drop user_events table
+DROP TABLE IF EXISTS user_events;
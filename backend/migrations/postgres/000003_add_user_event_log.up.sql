@@ -0,0 +1,18 @@
+-- Create user_events table for SSE resumption
+-- Every real-time event gets a monotonically increasing id that clients echo back
+-- in the Last-Event-ID header when they reconnect
+CREATE TABLE IF NOT EXISTS user_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    stream VARCHAR(20) NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}'::JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT user_events_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Replay reads a user's events on one stream after a given id
+CREATE INDEX IF NOT EXISTS idx_user_events_user_stream_id ON user_events (user_id, stream, id);
+
+-- Retention cleanup deletes by age
+CREATE INDEX IF NOT EXISTS idx_user_events_created_at ON user_events (created_at);
@@ -0,0 +1,7 @@
+-- Drop user_settings table
+DROP TABLE IF EXISTS user_settings;
+
+-- Remove delivery state columns from messages
+ALTER TABLE messages DROP COLUMN IF EXISTS read_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS delivered_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS status;
@@ -0,0 +1,16 @@
+-- Track delivery state per message: sent -> delivered -> read
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'sent';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS read_at TIMESTAMP WITH TIME ZONE;
+
+-- Backfill messages that were already read
+UPDATE messages SET status = 'read', delivered_at = created_at, read_at = created_at WHERE is_read = true;
+
+-- Create user_settings table for per-user app settings
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id UUID PRIMARY KEY,
+    read_receipts_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT user_settings_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
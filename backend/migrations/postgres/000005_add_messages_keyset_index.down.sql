@@ -0,0 +1,2 @@
+-- Drop message history index
+DROP INDEX IF EXISTS idx_messages_match_id_id;
@@ -0,0 +1,2 @@
+-- Message history is paged by id within a match
+CREATE INDEX IF NOT EXISTS idx_messages_match_id_id ON messages (match_id, id);
@@ -0,0 +1,6 @@
+-- Drop message reactions and edit history
+DROP TABLE IF EXISTS message_reactions;
+DROP TABLE IF EXISTS message_edits;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
@@ -0,0 +1,25 @@
+-- Edited and unsent messages; unsent messages stay behind as tombstones
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Create message_edits table for the previous versions of edited messages
+CREATE TABLE IF NOT EXISTS message_edits (
+    id BIGSERIAL PRIMARY KEY,
+    message_id BIGINT NOT NULL,
+    previous_message TEXT NOT NULL,
+    edited_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT message_edits_message_id_fkey FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_edits_message_id ON message_edits (message_id, edited_at);
+
+-- Create message_reactions table, one reaction per user per message
+CREATE TABLE IF NOT EXISTS message_reactions (
+    message_id BIGINT NOT NULL,
+    user_id UUID NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (message_id, user_id),
+    CONSTRAINT message_reactions_message_id_fkey FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    CONSTRAINT message_reactions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
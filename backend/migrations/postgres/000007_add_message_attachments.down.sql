@@ -0,0 +1,2 @@
+-- Drop message attachments
+DROP TABLE IF EXISTS message_attachments;
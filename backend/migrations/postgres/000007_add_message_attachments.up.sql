@@ -0,0 +1,19 @@
+-- Create message_attachments table for photos and voice notes sent in chat. Attachments
+-- are uploaded first and linked to a message when it is sent.
+CREATE TABLE IF NOT EXISTS message_attachments (
+    id BIGSERIAL PRIMARY KEY,
+    match_id BIGINT NOT NULL,
+    message_id BIGINT,
+    uploader_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT message_attachments_match_id_fkey FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE,
+    CONSTRAINT message_attachments_message_id_fkey FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    CONSTRAINT message_attachments_uploader_id_fkey FOREIGN KEY (uploader_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_attachments_message_id ON message_attachments (message_id);
+CREATE INDEX IF NOT EXISTS idx_message_attachments_unlinked ON message_attachments (created_at) WHERE message_id IS NULL;
@@ -0,0 +1,5 @@
+-- Drop user blocks and message search
+DROP TABLE IF EXISTS user_blocks;
+
+DROP INDEX IF EXISTS idx_messages_search_vector;
+ALTER TABLE messages DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,6 @@
+-- Drop moderation reports and message safety columns
+DROP TABLE IF EXISTS moderation_reports;
+
+DROP INDEX IF EXISTS idx_messages_sender_content_hash;
+ALTER TABLE messages DROP COLUMN IF EXISTS content_hash;
+ALTER TABLE messages DROP COLUMN IF EXISTS safety_warning;
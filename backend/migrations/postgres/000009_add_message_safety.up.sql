@@ -0,0 +1,26 @@
+-- Safety pipeline results on messages. content_hash is the hash of the normalized text,
+-- used to spot the same message pasted into many conversations.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS safety_warning BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_messages_sender_content_hash ON messages (sender_id, content_hash, created_at);
+
+-- Create moderation_reports table for content flagged for review
+CREATE TABLE IF NOT EXISTS moderation_reports (
+    id BIGSERIAL PRIMARY KEY,
+    subject_type VARCHAR(20) NOT NULL,
+    subject_user_id UUID NOT NULL,
+    match_id BIGINT,
+    content TEXT NOT NULL DEFAULT '',
+    source VARCHAR(50) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT moderation_reports_subject_user_id_fkey FOREIGN KEY (subject_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT moderation_reports_match_id_fkey FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_reports_open ON moderation_reports (created_at) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_moderation_reports_subject_user_id ON moderation_reports (subject_user_id);
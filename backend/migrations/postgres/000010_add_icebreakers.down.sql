@@ -0,0 +1,2 @@
+-- Drop icebreaker suggestion tracking
+DROP TABLE IF EXISTS icebreaker_suggestions;
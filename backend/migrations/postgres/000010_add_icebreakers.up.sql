@@ -0,0 +1,25 @@
+-- Create icebreaker_suggestions table to track which openers are shown, sent and replied to.
+-- A suggestion shown again to the same user in the same match updates the existing row.
+CREATE TABLE IF NOT EXISTS icebreaker_suggestions (
+    id BIGSERIAL PRIMARY KEY,
+    match_id BIGINT NOT NULL,
+    user_id UUID NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    template_key VARCHAR(50) NOT NULL,
+    prompt_id BIGINT,
+    text TEXT NOT NULL,
+    times_shown INT NOT NULL DEFAULT 1,
+    message_id BIGINT,
+    used_at TIMESTAMP WITH TIME ZONE,
+    replied_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_shown_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(match_id, user_id, text),
+    CONSTRAINT icebreaker_suggestions_match_id_fkey FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE,
+    CONSTRAINT icebreaker_suggestions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT icebreaker_suggestions_message_id_fkey FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE SET NULL
+);
+
+-- Sent icebreakers still waiting for a reply
+CREATE INDEX IF NOT EXISTS idx_icebreaker_suggestions_awaiting_reply ON icebreaker_suggestions (match_id) WHERE used_at IS NOT NULL AND replied_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_icebreaker_suggestions_template_key ON icebreaker_suggestions (template_key);
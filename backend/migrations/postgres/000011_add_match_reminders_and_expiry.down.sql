@@ -0,0 +1,4 @@
+-- Drop match reminder and expiry columns
+DROP INDEX IF EXISTS idx_matches_active_last_message_at;
+ALTER TABLE matches DROP COLUMN IF EXISTS expired_at;
+ALTER TABLE matches DROP COLUMN IF EXISTS turn_reminder_sent_at;
@@ -0,0 +1,6 @@
+-- "Your turn" reminders and match expiry. turn_reminder_sent_at is compared with the last
+-- message so each unanswered message is nudged about at most once.
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS turn_reminder_sent_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS expired_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_matches_active_last_message_at ON matches (last_message_at) WHERE expired_at IS NULL;
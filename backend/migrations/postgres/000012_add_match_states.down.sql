@@ -0,0 +1,2 @@
+-- Drop per-user match states
+DROP TABLE IF EXISTS match_states;
@@ -0,0 +1,13 @@
+-- Create match_states table for each user's archived, muted and pinned flags on a match.
+-- Matches without a row have every flag off.
+CREATE TABLE IF NOT EXISTS match_states (
+    match_id BIGINT NOT NULL,
+    user_id UUID NOT NULL,
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (match_id, user_id),
+    CONSTRAINT match_states_match_id_fkey FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE,
+    CONSTRAINT match_states_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
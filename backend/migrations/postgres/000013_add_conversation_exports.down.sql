@@ -0,0 +1,2 @@
+-- Drop the conversation export log
+DROP TABLE IF EXISTS conversation_exports;
@@ -0,0 +1,13 @@
+-- Create conversation_exports table, an audit log of every conversation export
+CREATE TABLE IF NOT EXISTS conversation_exports (
+    id BIGSERIAL PRIMARY KEY,
+    match_id BIGINT NOT NULL,
+    user_id UUID NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    message_count INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT conversation_exports_match_id_fkey FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE,
+    CONSTRAINT conversation_exports_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_exports_user_id ON conversation_exports (user_id, created_at);
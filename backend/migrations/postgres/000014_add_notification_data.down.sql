@@ -0,0 +1,4 @@
+-- Drop notification data and inbox indexes
+DROP INDEX IF EXISTS idx_notifications_unread;
+DROP INDEX IF EXISTS idx_notifications_user_id_id;
+ALTER TABLE notifications DROP COLUMN IF EXISTS data;
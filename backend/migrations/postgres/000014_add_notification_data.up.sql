@@ -0,0 +1,6 @@
+-- Deep-link targets for notifications (match, profile, message ids) and indexes for the
+-- notification inbox
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS data JSONB NOT NULL DEFAULT '{}'::JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id_id ON notifications (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE is_read = false;
@@ -0,0 +1,2 @@
+-- Drop registered push devices
+DROP TABLE IF EXISTS devices;
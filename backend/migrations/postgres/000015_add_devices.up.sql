@@ -0,0 +1,14 @@
+-- Create devices table, the push notification tokens registered by each user's devices
+CREATE TABLE IF NOT EXISTS devices (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    platform VARCHAR(10) NOT NULL,
+    token TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT devices_token_key UNIQUE (token),
+    CONSTRAINT devices_platform_check CHECK (platform IN ('ios', 'android', 'web')),
+    CONSTRAINT devices_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_devices_user_id ON devices (user_id);
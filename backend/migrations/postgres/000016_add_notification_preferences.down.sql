@@ -0,0 +1,7 @@
+-- Drop notification preferences, quiet hours and deferred pushes
+DROP TABLE IF EXISTS deferred_pushes;
+ALTER TABLE user_settings DROP COLUMN IF EXISTS timezone;
+ALTER TABLE user_settings DROP COLUMN IF EXISTS quiet_hours_end;
+ALTER TABLE user_settings DROP COLUMN IF EXISTS quiet_hours_start;
+ALTER TABLE user_settings DROP COLUMN IF EXISTS quiet_hours_enabled;
+DROP TABLE IF EXISTS notification_preferences;
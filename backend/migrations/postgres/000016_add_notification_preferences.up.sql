@@ -0,0 +1,32 @@
+-- Per-type notification channel preferences. Types without a row use the defaults.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    in_app BOOLEAN NOT NULL,
+    push BOOLEAN NOT NULL,
+    email BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, type),
+    CONSTRAINT notification_preferences_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Quiet hours, in the user's timezone
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS quiet_hours_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS quiet_hours_start TIME NOT NULL DEFAULT '22:00';
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS quiet_hours_end TIME NOT NULL DEFAULT '07:00';
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- Pushes held back during quiet hours, sent once they end
+CREATE TABLE IF NOT EXISTS deferred_pushes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}'::JSONB,
+    deliver_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT deferred_pushes_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_deferred_pushes_deliver_at ON deferred_pushes (deliver_at);
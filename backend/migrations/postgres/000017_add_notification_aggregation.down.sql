@@ -0,0 +1,3 @@
+-- Drop push aggregation windows and digest tracking
+ALTER TABLE user_settings DROP COLUMN IF EXISTS last_digest_at;
+DROP TABLE IF EXISTS push_aggregates;
@@ -0,0 +1,17 @@
+-- Aggregation windows for pushes. The first push of a type in a window is sent and later
+-- ones are counted, then summed up in one push when the window ends. group_key separates
+-- windows within a type, e.g. one per match for messages.
+CREATE TABLE IF NOT EXISTS push_aggregates (
+    user_id UUID NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    group_key VARCHAR(40) NOT NULL DEFAULT '',
+    window_ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    pending_count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, type, group_key),
+    CONSTRAINT push_aggregates_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_aggregates_window_ends_at ON push_aggregates (window_ends_at);
+
+-- When each user was last sent the daily email digest
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS last_digest_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,5 @@
+-- Drop the transactional outbox
+DROP INDEX IF EXISTS idx_notifications_dedupe_key;
+ALTER TABLE notifications DROP COLUMN IF EXISTS dedupe_key;
+DROP TABLE IF EXISTS outbox_consumed;
+DROP TABLE IF EXISTS outbox_events;
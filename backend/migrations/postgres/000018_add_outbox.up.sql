@@ -0,0 +1,31 @@
+-- Transactional outbox: side effects recorded in the same transaction as the change that
+-- caused them and delivered by a dispatcher after commit
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    available_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    processed_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT outbox_events_status_check CHECK (status IN ('pending', 'done', 'dead'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events (available_at, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_events_processed_at ON outbox_events (processed_at) WHERE status = 'done';
+
+-- The consumers that have handled each event, so retries skip them
+CREATE TABLE IF NOT EXISTS outbox_consumed (
+    event_id BIGINT NOT NULL,
+    consumer VARCHAR(50) NOT NULL,
+    consumed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (event_id, consumer),
+    CONSTRAINT outbox_consumed_event_id_fkey FOREIGN KEY (event_id) REFERENCES outbox_events(id) ON DELETE CASCADE
+);
+
+-- Notifications sent by outbox consumers carry a key so a redelivered event isn't
+-- notified twice
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS dedupe_key VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_dedupe_key ON notifications (dedupe_key);
@@ -0,0 +1,8 @@
+-- Drop like notification tracking and the premium flag
+UPDATE push_aggregates SET type = 'rose' WHERE type = 'new_rose';
+UPDATE push_aggregates SET type = 'like' WHERE type = 'new_like';
+UPDATE notification_preferences SET type = 'rose' WHERE type = 'new_rose';
+UPDATE notification_preferences SET type = 'like' WHERE type = 'new_like';
+DROP INDEX IF EXISTS idx_swipes_profile_id_likes;
+DROP TABLE IF EXISTS like_notifications;
+ALTER TABLE users DROP COLUMN IF EXISTS is_premium;
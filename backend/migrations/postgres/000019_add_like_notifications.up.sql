@@ -0,0 +1,23 @@
+-- Premium members see who liked them, everyone else gets a redacted preview
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_premium BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- When each liker last notified each user of a like or rose, so toggling a like on and off
+-- doesn't notify again within the cooldown
+CREATE TABLE IF NOT EXISTS like_notifications (
+    liker_id UUID NOT NULL,
+    likee_id UUID NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    notified_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (liker_id, likee_id, type),
+    CONSTRAINT like_notifications_liker_id_fkey FOREIGN KEY (liker_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT like_notifications_likee_id_fkey FOREIGN KEY (likee_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- The likes inbox lists the likes on a profile, roses first
+CREATE INDEX IF NOT EXISTS idx_swipes_profile_id_likes ON swipes (profile_id, is_rose DESC, created_at DESC) WHERE is_like = true;
+
+-- Like and rose notifications are now new_like and new_rose
+UPDATE notification_preferences SET type = 'new_like' WHERE type = 'like';
+UPDATE notification_preferences SET type = 'new_rose' WHERE type = 'rose';
+UPDATE push_aggregates SET type = 'new_like' WHERE type = 'like';
+UPDATE push_aggregates SET type = 'new_rose' WHERE type = 'rose';
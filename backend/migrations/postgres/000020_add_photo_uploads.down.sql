@@ -0,0 +1,8 @@
+-- Drop photo upload columns and the one primary photo constraint
+DROP INDEX IF EXISTS idx_photos_profile_id_position;
+DROP INDEX IF EXISTS idx_photos_one_primary;
+ALTER TABLE photos DROP COLUMN IF EXISTS variants;
+ALTER TABLE photos DROP COLUMN IF EXISTS storage_key;
+ALTER TABLE photos DROP COLUMN IF EXISTS height;
+ALTER TABLE photos DROP COLUMN IF EXISTS width;
+ALTER TABLE photos DROP COLUMN IF EXISTS position;
@@ -0,0 +1,28 @@
+-- Uploaded photos are stored as resized variants under a common storage key prefix.
+-- Photos added by URL before uploads existed have no prefix or variants.
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS width INT;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS height INT;
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS storage_key VARCHAR(255);
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '{}'::JSONB;
+
+-- Number existing photos in the order they were shown
+UPDATE photos p
+SET position = ordered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY profile_id ORDER BY is_primary DESC, created_at ASC, id ASC) - 1 AS position
+    FROM photos
+) ordered
+WHERE p.id = ordered.id;
+
+-- At most one primary photo per profile, keeping the oldest where there are several
+UPDATE photos p
+SET is_primary = false
+WHERE is_primary = true
+  AND EXISTS (
+      SELECT 1 FROM photos other
+      WHERE other.profile_id = p.profile_id AND other.is_primary = true AND other.id < p.id
+  );
+CREATE UNIQUE INDEX IF NOT EXISTS idx_photos_one_primary ON photos (profile_id) WHERE is_primary = true;
+
+CREATE INDEX IF NOT EXISTS idx_photos_profile_id_position ON photos (profile_id, position);
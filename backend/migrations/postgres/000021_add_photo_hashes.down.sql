@@ -0,0 +1,13 @@
+-- Drop photo hashes, banned hashes and photo report references
+ALTER TABLE moderation_reports DROP CONSTRAINT IF EXISTS moderation_reports_photo_id_fkey;
+ALTER TABLE moderation_reports DROP COLUMN IF EXISTS photo_id;
+DROP TABLE IF EXISTS banned_photo_hashes;
+DROP INDEX IF EXISTS idx_photos_dhash_band7;
+DROP INDEX IF EXISTS idx_photos_dhash_band6;
+DROP INDEX IF EXISTS idx_photos_dhash_band5;
+DROP INDEX IF EXISTS idx_photos_dhash_band4;
+DROP INDEX IF EXISTS idx_photos_dhash_band3;
+DROP INDEX IF EXISTS idx_photos_dhash_band2;
+DROP INDEX IF EXISTS idx_photos_dhash_band1;
+DROP INDEX IF EXISTS idx_photos_dhash_band0;
+ALTER TABLE photos DROP COLUMN IF EXISTS dhash;
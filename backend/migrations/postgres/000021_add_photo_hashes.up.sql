@@ -0,0 +1,24 @@
+-- Difference hash of each uploaded photo, to spot the same photo on several profiles.
+-- The hash is split into eight 8-bit bands with an index each: hashes at most 7 bits
+-- apart share at least one band, so near duplicates are found with index lookups.
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS dhash BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_photos_dhash_band0 ON photos (((dhash >> 56) & 255)) WHERE dhash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_photos_dhash_band1 ON photos (((dhash >> 48) & 255)) WHERE dhash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_photos_dhash_band2 ON photos (((dhash >> 40) & 255)) WHERE dhash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_photos_dhash_band3 ON photos (((dhash >> 32) & 255)) WHERE dhash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_photos_dhash_band4 ON photos (((dhash >> 24) & 255)) WHERE dhash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_photos_dhash_band5 ON photos (((dhash >> 16) & 255)) WHERE dhash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_photos_dhash_band6 ON photos (((dhash >> 8) & 255)) WHERE dhash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_photos_dhash_band7 ON photos ((dhash & 255)) WHERE dhash IS NOT NULL;
+
+-- Hashes of images banned by moderators, uploads with the same hash are blocked
+CREATE TABLE IF NOT EXISTS banned_photo_hashes (
+    dhash BIGINT PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    banned_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Photo reports point at the photo they are about
+ALTER TABLE moderation_reports ADD COLUMN IF NOT EXISTS photo_id BIGINT;
+ALTER TABLE moderation_reports ADD CONSTRAINT moderation_reports_photo_id_fkey FOREIGN KEY (photo_id) REFERENCES photos(id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+-- Allow null onboarding_completed again, the backfilled profiles can't be told apart
+ALTER TABLE profiles ALTER COLUMN onboarding_completed DROP NOT NULL;
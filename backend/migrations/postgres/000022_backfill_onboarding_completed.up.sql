@@ -0,0 +1,17 @@
+-- Profiles are only shown in feeds once onboarding is complete. Profiles created before
+-- onboarding was enforced keep being shown if they would pass it: basics filled in, at
+-- least 3 photos (the ONBOARDING_MIN_PHOTOS default), exactly 3 prompt answers and
+-- preferences set. The rest are taken through onboarding.
+UPDATE profiles p
+SET onboarding_completed = true
+WHERE p.onboarding_completed IS NOT TRUE
+  AND p.name <> ''
+  AND p.date_of_birth IS NOT NULL
+  AND p.gender <> ''
+  AND (SELECT COUNT(*) FROM photos WHERE profile_id = p.id) >= 3
+  AND (SELECT COUNT(*) FROM profile_prompts WHERE profile_id = p.id) = 3
+  AND EXISTS (SELECT 1 FROM preferences WHERE user_id = p.user_id);
+
+UPDATE profiles SET onboarding_completed = false WHERE onboarding_completed IS NULL;
+
+ALTER TABLE profiles ALTER COLUMN onboarding_completed SET NOT NULL;
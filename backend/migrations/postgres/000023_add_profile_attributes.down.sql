@@ -0,0 +1,3 @@
+-- Drop structured profile attributes and attribute filters
+ALTER TABLE preferences DROP COLUMN IF EXISTS attribute_filters;
+DROP TABLE IF EXISTS profile_attributes;
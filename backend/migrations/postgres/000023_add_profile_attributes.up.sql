@@ -0,0 +1,13 @@
+-- Structured profile attributes from the catalog in models.ProfileAttributes, one row
+-- per answered attribute. Values are JSON: a string, a list of strings or a number.
+CREATE TABLE IF NOT EXISTS profile_attributes (
+    profile_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    key VARCHAR(50) NOT NULL,
+    value JSONB NOT NULL,
+    visible BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (profile_id, key)
+);
+
+-- Feed filters on attributes, keyed by attribute
+ALTER TABLE preferences ADD COLUMN IF NOT EXISTS attribute_filters JSONB NOT NULL DEFAULT '{}';
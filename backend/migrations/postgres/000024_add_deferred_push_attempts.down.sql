@@ -0,0 +1,2 @@
+-- Drop deferred push attempts
+ALTER TABLE deferred_pushes DROP COLUMN IF EXISTS attempts;
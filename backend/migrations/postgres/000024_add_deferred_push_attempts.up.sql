@@ -0,0 +1,3 @@
+-- Deferred pushes stay in the table until they are sent, retried a few times if sending
+-- fails
+ALTER TABLE deferred_pushes ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
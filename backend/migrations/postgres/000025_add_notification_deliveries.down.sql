@@ -0,0 +1,2 @@
+-- Drop notification deliveries
+DROP TABLE IF EXISTS notification_deliveries;
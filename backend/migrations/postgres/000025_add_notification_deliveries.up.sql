@@ -0,0 +1,10 @@
+-- Pushes and live events already sent for a notification's dedupe key, so a redelivered
+-- outbox event doesn't send them twice whether or not the notification went to the inbox
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    dedupe_key VARCHAR(100) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (dedupe_key, channel)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_delivered_at ON notification_deliveries (delivered_at);